@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/wtask/sitemap/internal/sitemap"
+	"github.com/wtask/sitemap/internal/sitemap/audit"
+	"github.com/wtask/sitemap/internal/sitemap/load"
+)
+
+// auditCommand - compares published site map against crawl results.
+// Returns exit code: 0 if no problems are found, 1 if problems are found or audit failed, 2 on usage error.
+func auditCommand(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("audit", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, `Compare published site map (local file or URL, map or index, plain or gzipped) against crawl results.
+Crawl starts from given URI, or from the root of site map host if URI is omitted:
+
+	smgen audit [options] SITEMAP [URI]
+
+Options:
+
+`)
+		flags.PrintDefaults()
+		fmt.Fprint(stderr, "\n")
+	}
+	depth := flags.Uint("depth", 1, "Maximum depth of link-junctions from start URL to crawl.")
+	workers := flags.Uint("num-workers", 1, "Number of allowed concurrent workers to crawl site and to check published URLs not reached by crawl.")
+	asJSON := flags.Bool("json", false, "Print audit result in JSON format.")
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+	if flags.NArg() < 1 || flags.NArg() > 2 {
+		fmt.Fprint(stderr, "Error: SITEMAP is required.\n\n")
+		flags.Usage()
+		return 2
+	}
+	if *workers == 0 {
+		fmt.Fprint(stderr, "Error: unable to start with 0 workers.\n\n")
+		flags.Usage()
+		return 2
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(interrupt)
+	go func() {
+		select {
+		case <-interrupt:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	published, err := load.Sitemap(ctx, flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(stderr, "ERR", err)
+		return 1
+	}
+	var root *sitemap.URI
+	if flags.NArg() == 2 {
+		root, err = sitemap.NewURI(flags.Arg(1))
+	} else if len(published) > 0 {
+		root, err = sitemap.NewURI(published[0].Scheme + "://" + published[0].Host + "/")
+	} else {
+		err = fmt.Errorf("site map is empty, URI is required")
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "ERR", err)
+		return 2
+	}
+
+	result, err := audit.Run(ctx, published, root, *depth, *workers)
+	if err != nil {
+		fmt.Fprintln(stderr, "ERR", err)
+		return 1
+	}
+	if *asJSON {
+		err = result.WriteJSON(stdout)
+	} else {
+		err = result.WriteText(stdout)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "ERR", err)
+		return 1
+	}
+	if !result.OK() {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_run_audit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html><body><a href="/a.html">a</a><a href="/new.html">new</a></body></html>`))
+		case "/a.html", "/new.html", "/orphan.html":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html><body>page</body></html>`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	dir, err := ioutil.TempDir("", "smgen-audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	write := func(name string, paths ...string) string {
+		content := `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`
+		for _, p := range paths {
+			content += "<url><loc>" + server.URL + p + "</loc></url>"
+		}
+		content += "</urlset>"
+		filename := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(filename, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return filename
+	}
+	complete := write("complete.xml", "/", "/a.html", "/new.html")
+	outdated := write("outdated.xml", "/", "/a.html", "/orphan.html", "/old.html")
+
+	cases := []struct {
+		name     string
+		args     []string
+		expected int
+		output   []string
+	}{
+		{"help", []string{"audit", "-h"}, 0, nil},
+		{"no site map", []string{"audit"}, 2, nil},
+		{"no workers", []string{"audit", "-num-workers", "0", complete}, 2, nil},
+		{"missing site map", []string{"audit", filepath.Join(dir, "missing.xml")}, 1, nil},
+		{"complete", []string{"audit", complete}, 0, []string{"Published URLs: 3, reachable pages: 3\n"}},
+		{
+			"outdated",
+			[]string{"audit", "-num-workers", "2", outdated, server.URL + "/"},
+			1,
+			[]string{
+				"Published URLs: 4, reachable pages: 3\n",
+				"SITEMAP broken " + server.URL + "/old.html (404)",
+				"SITEMAP unreachable " + server.URL + "/orphan.html\n",
+				"MISSING " + server.URL + "/new.html\n",
+			},
+		},
+		{"json", []string{"audit", "-json", outdated}, 1, []string{`"sitemap_issues"`, `"missing_from_sitemap"`}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+			if code := run(c.args, stdout, stderr); code != c.expected {
+				t.Errorf("Unexpected exit code %d, expected %d, stdout:\n%s\nstderr:\n%s", code, c.expected, stdout, stderr)
+			}
+			for _, line := range c.output {
+				if !strings.Contains(stdout.String(), line) {
+					t.Errorf("Expected output contains %q, got:\n%s", line, stdout)
+				}
+			}
+			if c.name == "json" && !json.Valid(stdout.Bytes()) {
+				t.Errorf("Expected JSON output, got:\n%s", stdout)
+			}
+		})
+	}
+}
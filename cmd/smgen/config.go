@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// sitesKey - config key with list of sites, all other top-level keys are common for every site.
+const sitesKey = "sites"
+
+// siteSettings - settings of single site from config file, keys are names of crawl command flags,
+// additionally "url" key defines start URI. Scalar value is kept as list of single item.
+type siteSettings map[string][]string
+
+// value - returns setting as single string, list items are joined with comma.
+func (s siteSettings) value(name string) string {
+	return strings.Join(s[name], ",")
+}
+
+// loadConfig - reads config file in YAML, JSON or TOML format (detected by file extension)
+// and returns settings common for all sites and settings of every described site, if config has no sites list,
+// common settings describe the only site and list of sites is empty.
+// Environment variables like $NAME or ${NAME} are substituted in string values after parsing, use $$ to keep dollar sign.
+func loadConfig(filename string) (siteSettings, []siteSettings, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to read config: %s", err)
+	}
+	raw := map[string]interface{}{}
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		err = dec.Decode(&raw)
+	case ".toml":
+		err = toml.Unmarshal(data, &raw)
+	default:
+		return nil, nil, fmt.Errorf("config %s: unsupported format, expected .yaml, .yml, .json or .toml", filename)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("config %s: %s", filename, err)
+	}
+	common, sites, err := parseConfig(raw)
+	if err != nil {
+		return nil, nil, fmt.Errorf("config %s: %s", filename, err)
+	}
+	return common, sites, nil
+}
+
+// parseConfig - converts decoded config into common settings and settings of sites.
+func parseConfig(raw map[string]interface{}) (siteSettings, []siteSettings, error) {
+	common, err := settings(raw, sitesKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	list, ok := raw[sitesKey]
+	if !ok {
+		return common, []siteSettings{}, nil
+	}
+	var items []map[string]interface{}
+	switch list := list.(type) {
+	case []map[string]interface{}:
+		items = list
+	case []interface{}:
+		for i, item := range list {
+			m, ok := item.(map[string]interface{})
+			if !ok {
+				return nil, nil, fmt.Errorf("site #%d is not a map", i+1)
+			}
+			items = append(items, m)
+		}
+	default:
+		return nil, nil, fmt.Errorf("%q must be a list of sites", sitesKey)
+	}
+	if len(items) == 0 {
+		return nil, nil, fmt.Errorf("%q list is empty", sitesKey)
+	}
+	sites := []siteSettings{}
+	for i, item := range items {
+		site, err := settings(item)
+		if err != nil {
+			return nil, nil, fmt.Errorf("site #%d: %s", i+1, err)
+		}
+		sites = append(sites, site)
+	}
+	return common, sites, nil
+}
+
+// settings - converts values of decoded map into lists of strings accepted by flags, `skip` keys are ignored.
+func settings(raw map[string]interface{}, skip ...string) (siteSettings, error) {
+	s := siteSettings{}
+	keys := make([]string, 0, len(raw))
+	for k := range raw {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+next:
+	for _, k := range keys {
+		for _, sk := range skip {
+			if k == sk {
+				continue next
+			}
+		}
+		if section, ok := raw[k].(map[string]interface{}); ok {
+			if err := sectionSettings(s, k, section); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		v, err := settingValue(raw[k])
+		if err != nil {
+			return nil, fmt.Errorf("%q: %s", k, err)
+		}
+		s[k] = v
+	}
+	return s, nil
+}
+
+// sectionSettings - puts settings of config section into `s`, names of settings are prefixed with section name,
+// like "login-url" for "url" key of "login" section.
+// Map inside section is converted into list of "name=value" items, like "login-fields".
+func sectionSettings(s siteSettings, section string, raw map[string]interface{}) error {
+	for k, v := range raw {
+		name := section + "-" + k
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			values, err := settingValue(v)
+			if err != nil {
+				return fmt.Errorf("%q: %s", name, err)
+			}
+			s[name] = values
+			continue
+		}
+		items := []string{}
+		for field, value := range m {
+			str, err := scalarValue(value)
+			if err != nil {
+				return fmt.Errorf("%q: %q: %s", name, field, err)
+			}
+			items = append(items, field+"="+str)
+		}
+		sort.Strings(items)
+		s[name] = items
+	}
+	return nil
+}
+
+// settingValue - converts scalar value or list of scalars into list of strings.
+func settingValue(v interface{}) ([]string, error) {
+	items, ok := v.([]interface{})
+	if !ok {
+		s, err := scalarValue(v)
+		if err != nil {
+			return nil, err
+		}
+		return []string{s}, nil
+	}
+	values := make([]string, 0, len(items))
+	for _, item := range items {
+		s, err := scalarValue(item)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, s)
+	}
+	return values, nil
+}
+
+// scalarValue - converts scalar value into string, environment variables are substituted in string value.
+func scalarValue(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return expandEnv(v)
+	case bool, int, int64, uint64, json.Number:
+		return fmt.Sprint(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	default:
+		return "", fmt.Errorf("unsupported value %v", v)
+	}
+}
+
+// expandEnv - substitutes environment variables, undefined variable is an error.
+func expandEnv(s string) (string, error) {
+	undefined := []string{}
+	expanded := os.Expand(s, func(name string) string {
+		if name == "$" {
+			return "$"
+		}
+		v, ok := os.LookupEnv(name)
+		if !ok {
+			undefined = append(undefined, name)
+		}
+		return v
+	})
+	if len(undefined) > 0 {
+		return "", fmt.Errorf("undefined environment variable(s): %s", strings.Join(undefined, ", "))
+	}
+	return expanded, nil
+}
+
+// settingNames - returns sorted names of settings.
+func settingNames(s siteSettings) []string {
+	names := make([]string, 0, len(s))
+	for name := range s {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_loadConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "smgen-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.Setenv("SMGEN_TEST_HOST", "example.com")
+	defer os.Unsetenv("SMGEN_TEST_HOST")
+	os.Setenv("SMGEN_TEST_HEADER", "X-Quoted: \"a\", 'b'\n\tc")
+	defer os.Unsetenv("SMGEN_TEST_HEADER")
+
+	common := siteSettings{"depth": {"3"}, "graph": {"dot", "json"}, "progress": {"true"}}
+	sites := []siteSettings{
+		{"url": {"https://example.com/"}, "output-dir": {"/tmp/a"}},
+		{"url": {"https://blog.example.com/$"}, "output-dir": {"/tmp/b"}, "depth": {"5"}},
+	}
+	cases := []struct {
+		filename string
+		content  string
+		common   siteSettings
+		sites    []siteSettings
+		isErr    bool
+	}{
+		{
+			"sites.yaml",
+			`
+depth: 3
+graph: [dot, json]
+progress: true
+sites:
+  - url: https://${SMGEN_TEST_HOST}/
+    output-dir: /tmp/a
+  - url: https://blog.$SMGEN_TEST_HOST/$$
+    output-dir: /tmp/b
+    depth: 5
+`,
+			common,
+			sites,
+			false,
+		},
+		{
+			"sites.json",
+			`{
+	"depth": 3,
+	"graph": ["dot", "json"],
+	"progress": true,
+	"sites": [
+		{"url": "https://${SMGEN_TEST_HOST}/", "output-dir": "/tmp/a"},
+		{"url": "https://blog.$SMGEN_TEST_HOST/$$", "output-dir": "/tmp/b", "depth": 5}
+	]
+}`,
+			common,
+			sites,
+			false,
+		},
+		{
+			"sites.toml",
+			`
+depth = 3
+graph = ["dot", "json"]
+progress = true
+
+[[sites]]
+url = "https://${SMGEN_TEST_HOST}/"
+output-dir = "/tmp/a"
+
+[[sites]]
+url = "https://blog.$SMGEN_TEST_HOST/$$"
+output-dir = "/tmp/b"
+depth = 5
+`,
+			common,
+			sites,
+			false,
+		},
+		{
+			"single.yml",
+			"url: https://example.com/\nsize-limit: 52428800000\n",
+			siteSettings{"url": {"https://example.com/"}, "size-limit": {"52428800000"}},
+			[]siteSettings{},
+			false,
+		},
+		{"single.json", `{"size-limit": 52428800000}`, siteSettings{"size-limit": {"52428800000"}}, []siteSettings{}, false},
+		{"undefined.yaml", "url: https://${SMGEN_TEST_UNDEFINED}/\n", nil, nil, true},
+		{"undefined-list.json", `{"header": ["X-Env: ${SMGEN_TEST_UNDEFINED}"]}`, nil, nil, true},
+		{
+			"comment.yaml",
+			"# output-dir: ${SMGEN_TEST_UNDEFINED}\nurl: https://${SMGEN_TEST_HOST}/ # $SMGEN_TEST_UNDEFINED\nheader: [\"${SMGEN_TEST_HEADER}\"]\n",
+			siteSettings{"url": {"https://example.com/"}, "header": {"X-Quoted: \"a\", 'b'\n\tc"}},
+			[]siteSettings{},
+			false,
+		},
+		{
+			"comment.toml",
+			"# output-dir = \"${SMGEN_TEST_UNDEFINED}\"\nurl = \"https://${SMGEN_TEST_HOST}/\" # $SMGEN_TEST_UNDEFINED\nheader = [\"$SMGEN_TEST_HEADER\"]\n",
+			siteSettings{"url": {"https://example.com/"}, "header": {"X-Quoted: \"a\", 'b'\n\tc"}},
+			[]siteSettings{},
+			false,
+		},
+		{
+			"quoted.json",
+			`{"header": "${SMGEN_TEST_HEADER}"}`,
+			siteSettings{"header": {"X-Quoted: \"a\", 'b'\n\tc"}},
+			[]siteSettings{},
+			false,
+		},
+		{"format.ini", "url=https://example.com/\n", nil, nil, true},
+		{"invalid.json", `{"url": `, nil, nil, true},
+		{"empty-sites.yaml", "sites: []\n", nil, nil, true},
+		{"not-list.yaml", "sites: https://example.com/\n", nil, nil, true},
+		{
+			"section.yaml",
+			"login:\n  url: https://example.com/login\n  fields:\n    username: user\n    password: ${SMGEN_TEST_HOST}\n",
+			siteSettings{
+				"login-url":    {"https://example.com/login"},
+				"login-fields": {"password=example.com", "username=user"},
+			},
+			[]siteSettings{},
+			false,
+		},
+		{
+			"section.toml",
+			"[login]\nurl = \"https://example.com/login\"\n\n[login.fields]\nusername = \"user\"\n",
+			siteSettings{"login-url": {"https://example.com/login"}, "login-fields": {"username=user"}},
+			[]siteSettings{},
+			false,
+		},
+		{"nested.yaml", "login:\n  fields:\n    name:\n      first: user\n", nil, nil, true},
+	}
+	for _, c := range cases {
+		t.Run(c.filename, func(t *testing.T) {
+			filename := filepath.Join(dir, c.filename)
+			if err := ioutil.WriteFile(filename, []byte(c.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			common, sites, err := loadConfig(filename)
+			if c.isErr != (err != nil) {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(common, c.common) {
+				t.Errorf("Expected common settings %v, got %v", c.common, common)
+			}
+			if !reflect.DeepEqual(sites, c.sites) {
+				t.Errorf("Expected sites %v, got %v", c.sites, sites)
+			}
+		})
+	}
+	if _, _, err := loadConfig(filepath.Join(dir, "missing.yaml")); err == nil {
+		t.Error("Expected error for missing config")
+	}
+}
+
+func Test_parseCrawlOptions_config(t *testing.T) {
+	dir, err := ioutil.TempDir("", "smgen-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, sub := range []string{"a", "b"} {
+		if err := os.Mkdir(filepath.Join(dir, sub), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write := func(name, content string) string {
+		filename := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(filename, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return filename
+	}
+	sites := write("sites.yaml", `
+depth: 3
+num-workers: 2
+checkpoint-interval: 30s
+map-base-url: https://maps.localhost/
+sites:
+  - url: http://a.localhost/
+    output-dir: `+filepath.Join(dir, "a")+`
+  - url: http://b.localhost/
+    output-dir: `+filepath.Join(dir, "b")+`
+    map-base-url: https://maps.localhost/b
+    depth: 5
+`)
+
+	batch, err := parseCrawlOptions(
+		[]string{"-config", sites, "-num-workers", "4", "-max-workers", "6", "-combined-index", "all"},
+		ioutil.Discard,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if batch.maxWorkers != 6 || batch.combinedIndex != "all" {
+		t.Errorf("Unexpected batch settings: %+v", batch)
+	}
+	if len(batch.sites) != 2 {
+		t.Fatalf("Expected 2 sites, got %d", len(batch.sites))
+	}
+	for i, expected := range []struct {
+		uri, dir, base string
+		depth, worker  uint
+	}{
+		{"http://a.localhost/", filepath.Join(dir, "a", "a.localhost"), "https://maps.localhost/a.localhost/", 3, 4},
+		{"http://b.localhost/", filepath.Join(dir, "b", "b.localhost"), "https://maps.localhost/b/b.localhost/", 5, 4},
+	} {
+		o := batch.sites[i]
+		if o.startURL.String() != expected.uri || o.outputDir != expected.dir || o.mapBaseURL.String() != expected.base ||
+			o.depth != expected.depth || o.numWorkers != expected.worker || o.checkpointInterval != 30*time.Second {
+			t.Errorf("Unexpected options of site #%d: %+v", i+1, o)
+		}
+	}
+
+	invalid := []struct {
+		name string
+		args []string
+	}{
+		{"URI with several sites", []string{"-config", sites, "http://c.localhost/"}},
+		{"unknown option", []string{"-config", write("unknown.yaml", "url: http://a.localhost/\nunknown: 1\n")}},
+		{"invalid value", []string{"-config", write("value.yaml", "url: http://a.localhost/\ndepth: deep\n")}},
+		{"validation", []string{"-config", write("workers.yaml", "url: http://a.localhost/\nnum-workers: 0\n")}},
+		{"no URI", []string{"-config", write("no-uri.yaml", "depth: 2\n")}},
+		{
+			"same output dir",
+			[]string{"-config", write("same.yaml", "sites:\n  - url: http://a.localhost/\n  - url: http://a.localhost/\n")},
+		},
+		{
+			"batch option of site",
+			[]string{"-config", write("batch.yaml", "sites:\n  - url: http://a.localhost/\n    max-workers: 2\n")},
+		},
+		{"combined index of single site", []string{"-combined-index", "all", "http://a.localhost/"}},
+		{
+			"same metrics address",
+			[]string{"-map-base-url", "http://maps.localhost/", "-metrics-addr", ":9090", "http://a.localhost/", "http://b.localhost/"},
+		},
+		{"several sites without map base URL", []string{"http://a.localhost/", "http://b.localhost/"}},
+		{"invalid map base URL", []string{"-map-base-url", "/maps/", "http://a.localhost/"}},
+	}
+	for _, c := range invalid {
+		t.Run(c.name, func(t *testing.T) {
+			if _, err := parseCrawlOptions(c.args, ioutil.Discard); err != errUsage {
+				t.Errorf("Expected usage error, got %v", err)
+			}
+		})
+	}
+
+	single := write("single.json", `{"url": "http://a.localhost/", "depth": 2}`)
+	batch, err = parseCrawlOptions([]string{"-config", single, "-depth", "4", "http://c.localhost/"}, ioutil.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(batch.sites) != 1 || batch.sites[0].startURL.String() != "http://c.localhost/" || batch.sites[0].depth != 4 {
+		t.Errorf("Expected URI and depth are overridden by arguments, got %+v", batch.sites)
+	}
+
+	headers := write("headers.yaml", "url: http://a.localhost/\nheader: [\"X-A: 1, 2\", \"X-B: 3\"]\n")
+	batch, err = parseCrawlOptions([]string{"-config", headers}, ioutil.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h := batch.sites[0].headers; !reflect.DeepEqual([]string(h), []string{"X-A: 1, 2", "X-B: 3"}) {
+		t.Errorf("Expected headers from config, got %v", h)
+	}
+	batch, err = parseCrawlOptions([]string{"-config", headers, "-header", "X-C: 4"}, ioutil.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h := batch.sites[0].headers; !reflect.DeepEqual([]string(h), []string{"X-C: 4"}) {
+		t.Errorf("Expected headers of config are overridden by arguments, got %v", h)
+	}
+
+	batch, err = parseCrawlOptions(
+		[]string{"-output-dir", dir, "-map-base-url", "http://maps.localhost/", "http://a.localhost:8080/", "http://a.localhost/blog/"},
+		ioutil.Discard,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(batch.sites) != 2 ||
+		batch.sites[0].outputDir != filepath.Join(dir, "a.localhost_8080") ||
+		batch.sites[1].outputDir != filepath.Join(dir, "a.localhost_blog") {
+		t.Errorf("Expected every URI is crawled into own subdirectory, got %+v", batch.sites)
+	}
+}
@@ -0,0 +1,1161 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/wtask/sitemap/internal/sitemap"
+	"github.com/wtask/sitemap/internal/sitemap/report"
+)
+
+// crawlOptions - settings of crawl command.
+type crawlOptions struct {
+	// startURL - base URL from parser will start
+	startURL *sitemap.URI
+	// outputFormat - format for generating site map files;
+	// index will always be saved as XML
+	outputFormat string
+	// mapFilename - base name for site map file, used to generate final names
+	mapFilename,
+	// indexFilename - base name for site map index file, the same as for map
+	indexFilename,
+	// outputDir - absolute path to directory where all files will be generated
+	outputDir string
+	// mapBaseURL - URL of directory where map files are published, used to build index links,
+	// if it is nil, map files are expected in directory of start URL
+	mapBaseURL *url.URL
+	// numWorkers - number of concurrent work instances which fetches and parses html documents
+	numWorkers,
+	// depth - link fetching depth
+	depth uint
+	// limitFileSizeBytes - maximum size in bytes of any generated file,
+	// when file size is over this value, file is compressed into gzip
+	limitFileSizeBytes int64
+	// limitMapEntries - max number of entries per map file
+	limitMapEntries,
+	// limitIndexEntries - maximum number of entries per index file
+	limitIndexEntries int
+	// incremental - load crawl state from output directory and make conditional requests
+	incremental bool
+	// volatileSelectors - CSS selectors of document regions ignored when content hash is calculated
+	volatileSelectors string
+	// modifiedSources - precedence of sources to resolve lastmod
+	modifiedSources []sitemap.ModifiedSource
+	// linkSources - elements and attributes where links are searched
+	linkSources []sitemap.LinkSource
+	// acceptedTypes - media types of documents parsed for links
+	acceptedTypes []string
+	// resourceTypes - media types of non-HTML resources included into site map
+	resourceTypes []string
+	// headProbe - make HEAD request before GET of every document
+	headProbe bool
+	// maxBodyBytes - max size of document body, 0 means no limit
+	maxBodyBytes int64
+	// maxDocumentNodes - max num of nodes of parsed document, 0 means no limit
+	maxDocumentNodes int
+	// checkpointInterval - how often crawl progress is saved into output directory
+	checkpointInterval time.Duration
+	// resume - continue interrupted crawl from checkpoint saved in output directory
+	resume bool
+	// diskStore - keep crawl frontier and visited set in output directory instead of memory
+	diskStore bool
+	// diskStoreCapacity - expected number of pages when disk store is used
+	diskStoreCapacity uint
+	// progress - print crawl progress while parser is running
+	progress bool
+	// metricsAddr - address to serve metrics in Prometheus format
+	metricsAddr string
+	// metricsLinger - how long metrics are served after crawl is completed
+	metricsLinger time.Duration
+	// crawlReport - write crawl report into output directory
+	crawlReport bool
+	// graphFormats - formats of link graph to export into output directory
+	graphFormats []string
+	// graphClusterDepth - num of path directories to group graph nodes into clusters, 0 disables clustering
+	graphClusterDepth uint
+	// pool - budget of workers shared with other sites crawled concurrently, optional
+	pool *sitemap.WorkerPool
+	// seeds - additional start URIs sharing scope with start URL
+	seeds []*sitemap.URI
+	// userAgent - value of User-Agent header, default one is used if empty
+	userAgent string
+	// headers - additional headers of every request in "Name: Value" format
+	headers listFlag
+	// basicAuth - credentials for basic authentication in "user:password" format
+	basicAuth string
+	// bearerToken - token for bearer authentication
+	bearerToken string
+	// cookies - cookies of start URL in "name=value" format
+	cookies listFlag
+	// cookieFile - file with cookies in Netscape format
+	cookieFile string
+	// loginURL, loginPage - address where login form is posted and address of login page
+	loginURL, loginPage string
+	// loginFields - fields of login form in "name=value" format
+	loginFields listFlag
+	// request - parser options built from settings of requests above
+	request []sitemap.ParserOption
+}
+
+// errUsage - command line arguments are invalid, error is already reported.
+var errUsage = errors.New("invalid usage")
+
+// crawlBatch - sites to crawl in one run and settings shared by them.
+type crawlBatch struct {
+	sites []*crawlOptions
+	// maxWorkers - total num of workers of all sites, 0 means no limit
+	maxWorkers uint
+	// combinedIndex - base name of index of all sites maps, empty if combined index is not needed
+	combinedIndex string
+	// outputDir - directory of combined index
+	outputDir string
+	// limitFileSizeBytes, limitIndexEntries - limitations of combined index files
+	limitFileSizeBytes int64
+	limitIndexEntries  int
+}
+
+// syncWriter - serializes writes of several goroutines into underlying writer.
+type syncWriter struct {
+	mx sync.Mutex
+	w  io.Writer
+}
+
+// Write - implements io.Writer.
+func (sw *syncWriter) Write(p []byte) (int, error) {
+	sw.mx.Lock()
+	defer sw.mx.Unlock()
+	return sw.w.Write(p)
+}
+
+// crawlCommand - default command, builds site map starting from given URI.
+// Several sites, given as arguments or described in config file, are crawled concurrently.
+func crawlCommand(args []string, stdout, stderr io.Writer) int {
+	batch, err := parseCrawlOptions(args, stderr)
+	if err == flag.ErrHelp {
+		return 0
+	}
+	if err != nil {
+		return 2
+	}
+
+	out := stdout
+	if len(batch.sites) > 1 {
+		// loggers of concurrent crawls share the only output
+		out = &syncWriter{w: stdout}
+	} else if batch.sites[0].progress && isTerminal(stdout) {
+		// log lines are printed above progress line
+		out = &terminalLine{out: stdout}
+	}
+	var l logger = log.New(out, "smgen ", log.Ldate|log.Ltime)
+	// interrupted crawl can be resumed from the last checkpoint
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(interrupt)
+	go func() {
+		select {
+		case <-interrupt:
+			l.Println("Interrupting, waiting for running workers...")
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	if len(batch.sites) == 1 {
+		_, code := crawl(ctx, batch.sites[0], l, out)
+		return code
+	}
+
+	var pool *sitemap.WorkerPool
+	if batch.maxWorkers > 0 {
+		pool = sitemap.NewWorkerPool(batch.maxWorkers)
+	}
+	l.Printf("Started batch of %d sites, total workers limit: %d\n", len(batch.sites), batch.maxWorkers)
+	wg := sync.WaitGroup{}
+	codes := make([]int, len(batch.sites))
+	links := make([][]string, len(batch.sites))
+	for i, o := range batch.sites {
+		o.pool = pool
+		wg.Add(1)
+		go func(i int, o *crawlOptions) {
+			defer wg.Done()
+			siteLogger := log.New(out, "smgen "+o.startURL.Host+" ", log.Ldate|log.Ltime)
+			if err := os.MkdirAll(o.outputDir, 0755); err != nil {
+				siteLogger.Println("ERR", err)
+				codes[i] = 1
+				return
+			}
+			// progress of concurrent crawls can not share terminal line, so it is always logged
+			links[i], codes[i] = crawl(ctx, o, siteLogger, out)
+		}(i, o)
+	}
+	wg.Wait()
+
+	code := 0
+	for i, o := range batch.sites {
+		if codes[i] != 0 {
+			l.Println("FAILED", o.startURL.String())
+			code = codes[i]
+		}
+	}
+	if batch.combinedIndex == "" {
+		return code
+	}
+
+	index := []string{}
+	for _, site := range links {
+		index = append(index, site...)
+	}
+	if len(index) == 0 {
+		l.Println("Combined index is not saved, there are no map files")
+		return code
+	}
+	numErrors := 0
+	for file, err := range saveIndex(
+		index,
+		batch.limitIndexEntries,
+		batch.limitFileSizeBytes,
+		batch.combinedIndex,
+		batch.outputDir,
+	) {
+		if err != nil {
+			numErrors++
+			l.Println("INDEX", "ERR", file, err)
+		} else {
+			l.Println("INDEX", "OK", file)
+		}
+	}
+	if numErrors > 0 {
+		return 1
+	}
+	return code
+}
+
+// crawlFlags - flag set of crawl command bound to options.
+type crawlFlags struct {
+	*flag.FlagSet
+	o *crawlOptions
+	// config - name of config file
+	config string
+	// graphList, lastmodSources, linkSources, acceptedTypes, resourceTypes - raw values of list flags
+	graphList, lastmodSources, linkSources, acceptedTypes, resourceTypes string
+	// seedList, seedsFile - raw values of seeds flags
+	seedList, seedsFile string
+	// maxWorkers, combinedIndex - settings of batch, see crawlBatch
+	maxWorkers    uint
+	combinedIndex string
+	// mapBaseURL - raw value of map base URL flag
+	mapBaseURL string
+}
+
+// listFlag - flag which can be given several times, every value is appended to list.
+type listFlag []string
+
+func (l *listFlag) String() string {
+	if l == nil {
+		return ""
+	}
+	return strings.Join(*l, ", ")
+}
+
+func (l *listFlag) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// batchFlags - names of flags which are common for all sites crawled in one run.
+var batchFlags = []string{"max-workers", "combined-index"}
+
+// newCrawlFlags - defines flags of crawl command.
+func newCrawlFlags(stderr io.Writer) *crawlFlags {
+	cwd, _ := os.Getwd()
+	f := &crawlFlags{
+		FlagSet: flag.NewFlagSet("smgen", flag.ContinueOnError),
+		o:       &crawlOptions{outputFormat: "xml"},
+	}
+	o := f.o
+	f.SetOutput(stderr)
+	f.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		fmt.Fprint(stderr, "Options:\n\n")
+		f.PrintDefaults()
+		fmt.Fprint(stderr, "\n")
+	}
+
+	f.StringVar(
+		&f.config,
+		"config",
+		"",
+		"Config file (.yaml, .yml, .json or .toml) with settings of one or more sites to crawl. "+
+			"Keys are names of options, \"url\" is start URI, \"sites\" is list of sites; flags override file values.",
+	)
+	f.UintVar(&o.numWorkers, "num-workers", 1, "Number of allowed concurrent workers to build site map.")
+	f.UintVar(
+		&f.maxWorkers,
+		"max-workers",
+		0,
+		"Total number of concurrent workers of all sites crawled in one run, 0 means every site is limited by -num-workers only.",
+	)
+	f.StringVar(
+		&f.combinedIndex,
+		"combined-index",
+		"",
+		"Base name for site map INDEX of all sites crawled in one run, saved into output directory. Index is not saved if name is empty. "+
+			"Index refers maps of different hosts, so search engines accept it only from host where ownership of all sites is verified.",
+	)
+	f.StringVar(
+		&f.mapBaseURL,
+		"map-base-url",
+		"",
+		"URL of output directory where generated files are published, used to build links of site map INDEX. "+
+			"When several sites are crawled, it is required and URL of site subdirectory is built from it. "+
+			"If empty, files are expected in directory of start URL.",
+	)
+	f.UintVar(&o.depth, "depth", 1, "Maximum depth of link-junctions from start URL to render site map.")
+	f.StringVar(
+		&f.seedList,
+		"seeds",
+		"",
+		"Comma-separated additional start URIs of the same site, e.g. sections not linked from start URI. Every seed extends scope of crawl.",
+	)
+	f.StringVar(
+		&f.seedsFile,
+		"seeds-file",
+		"",
+		"File with additional start URIs, one per line, \"-\" means stdin. Empty lines and lines started with \"#\" are skipped.",
+	)
+	f.StringVar(&o.userAgent, "user-agent", "", "Value of User-Agent header of every request, Go default is used if empty.")
+	f.Var(&o.headers, "header", "Additional header of every request in \"Name: Value\" format. Option can be given several times.")
+	f.StringVar(&o.basicAuth, "basic-auth", "", "Credentials for basic authentication in \"user:password\" format.")
+	f.StringVar(&o.bearerToken, "bearer-token", "", "Token for bearer authentication, it is sent in Authorization header.")
+	f.Var(&o.cookies, "cookie", "Cookie of start URL in \"name=value\" format. Option can be given several times.")
+	f.StringVar(&o.cookieFile, "cookie-file", "", "File with cookies in Netscape format (as exported by curl or browser extensions).")
+	f.StringVar(&o.loginURL, "login-url", "", "Address where login form is posted before crawl to obtain session cookies.")
+	f.StringVar(
+		&o.loginPage,
+		"login-page",
+		"",
+		"Address of login page, crawl is aborted when page is redirected to it. Value of -login-url is used if empty.",
+	)
+	f.Var(&o.loginFields, "login-fields", "Field of login form in \"name=value\" format. Option can be given several times.")
+	f.StringVar(
+		&f.linkSources,
+		"link-sources",
+		"a@href",
+		"Comma-separated elements and attributes where links are searched in \"tag@attr\" format, "+
+			"optionally filtered by rel values, e.g. \"a@href,area@href,iframe@src,link@href[next|prev]\".",
+	)
+	f.StringVar(
+		&f.acceptedTypes,
+		"accepted-types",
+		"text/html,application/xhtml+xml",
+		"Comma-separated media types of documents parsed for links, XML-based types (XHTML) are parsed with respect to XML syntax.",
+	)
+	f.StringVar(
+		&f.resourceTypes,
+		"resource-types",
+		"",
+		"Comma-separated media types of non-HTML resources included into site map without downloading, "+
+			"e.g. \"application/pdf,application/msword\" or \"image/*\".",
+	)
+	f.BoolVar(
+		&o.headProbe,
+		"head-probe",
+		false,
+		"Make HEAD request before GET, so non-HTML responses and documents larger than -max-body-bytes are not downloaded.",
+	)
+	f.Int64Var(&o.maxBodyBytes, "max-body-bytes", 0, "Maximum size of document body in bytes, larger documents are skipped. 0 means no limit.")
+	f.IntVar(
+		&o.maxDocumentNodes,
+		"max-document-nodes",
+		0,
+		"Maximum num of nodes (elements, text, comments) of document, larger documents are skipped without parsing. 0 means no limit.",
+	)
+	f.StringVar(&o.mapFilename, "map-name", "sitemap", "Base name for site map FILE.")
+	f.StringVar(&o.indexFilename, "index-name", "sitemap_index", "Base name for site map INDEX.")
+	f.StringVar(&o.outputDir, "output-dir", cwd, "Output directory where site map and index will be generated.")
+	f.Int64Var(
+		&o.limitFileSizeBytes,
+		"size-limit",
+		50000*1024*1024,
+		"Maximum size of any generated file in bytes. If file size is greater than limitation, file is compressed into gzip.",
+	)
+	f.IntVar(&o.limitMapEntries, "map-limit", 50000, "Limit number of entries per site map file.")
+	f.IntVar(&o.limitIndexEntries, "index-limit", 50000, "Limit number of entries per index file.")
+
+	f.BoolVar(
+		&o.incremental,
+		"incremental",
+		false,
+		"Keep crawl state in output directory and re-fetch only documents changed since previous run.",
+	)
+	f.StringVar(
+		&o.volatileSelectors,
+		"volatile",
+		"",
+		"Comma-separated CSS selectors of page regions ignored when detecting content changes for lastmod (use with -incremental).",
+	)
+
+	f.DurationVar(
+		&o.checkpointInterval,
+		"checkpoint-interval",
+		0,
+		"How often crawl progress is saved into output directory to resume interrupted crawl, 0 disables checkpoints.",
+	)
+	f.BoolVar(&o.resume, "resume", false, "Resume interrupted crawl from checkpoint saved in output directory.")
+
+	f.BoolVar(
+		&o.diskStore,
+		"disk-store",
+		false,
+		"Keep crawl queue and visited pages in output directory to crawl very large sites in bounded memory.",
+	)
+	f.UintVar(
+		&o.diskStoreCapacity,
+		"disk-store-capacity",
+		1000000,
+		"Expected number of pages when -disk-store is used, affects memory used to check visited pages.",
+	)
+
+	f.BoolVar(&o.progress, "progress", false, "Print crawl progress and statistics while parser is running.")
+
+	f.StringVar(
+		&o.metricsAddr,
+		"metrics-addr",
+		"",
+		"Serve crawl metrics at /metrics in Prometheus format on given address, like \":9090\".",
+	)
+	f.DurationVar(
+		&o.metricsLinger,
+		"metrics-linger",
+		0,
+		"How long metrics are served after crawl is completed, so final values can be scraped. Interrupt stops waiting.",
+	)
+
+	f.BoolVar(
+		&o.crawlReport,
+		"report",
+		false,
+		"Write crawl report (broken links, redirects, non-HTML responses, pages beyond depth, inbound links) into output directory as JSON and HTML.",
+	)
+
+	f.StringVar(
+		&f.graphList,
+		"graph",
+		"",
+		"Comma-separated formats of link graph to export into output directory: \"dot\" (Graphviz), \"graphml\" and \"json\" (adjacency lists).",
+	)
+	f.UintVar(
+		&o.graphClusterDepth,
+		"graph-cluster-depth",
+		0,
+		"Group link graph nodes into clusters by given num of leading path directories, 0 disables clustering.",
+	)
+
+	f.StringVar(
+		&f.lastmodSources,
+		"lastmod-sources",
+		"header,document",
+		"Comma-separated precedence of lastmod sources: \"header\" (Last-Modified) and \"document\" (page metadata).",
+	)
+	return f
+}
+
+// options - validates values of flags and returns crawl options for given start URI.
+func (f *crawlFlags) options(start string) (*crawlOptions, error) {
+	o := f.o
+	if start == "" {
+		return nil, errors.New("Error: URI is required.")
+	}
+	if o.numWorkers == 0 {
+		return nil, errors.New("Error: unable to start with 0 workers.")
+	}
+	if o.mapFilename == "" {
+		return nil, errors.New("Error: can not continue when site map filename is not specified.")
+	}
+	if o.indexFilename == "" {
+		return nil, errors.New("Error: can not continue when site map index filename is not specified.")
+	}
+	if o.outputDir == "" {
+		return nil, errors.New("Error: can not continue when output directory is not specified.")
+	}
+	stat, err := os.Stat(o.outputDir)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to check output directory: %v.", err)
+	}
+	if !stat.IsDir() {
+		return nil, fmt.Errorf("Can not use output directory: %s.", o.outputDir)
+	}
+	if o.checkpointInterval < 0 {
+		return nil, fmt.Errorf("Error: invalid checkpoint interval (%v)", o.checkpointInterval)
+	}
+	if o.metricsLinger < 0 {
+		return nil, fmt.Errorf("Error: invalid metrics linger duration (%v)", o.metricsLinger)
+	}
+	if f.mapBaseURL != "" {
+		base, err := parseBaseURL(f.mapBaseURL)
+		if err != nil {
+			return nil, fmt.Errorf("Error: invalid map base URL: %v.", err)
+		}
+		o.mapBaseURL = base
+	}
+	if o.limitFileSizeBytes <= 0 {
+		return nil, fmt.Errorf("Error: invalid file size limitation (%d)", o.limitFileSizeBytes)
+	}
+	if o.limitMapEntries < 1 {
+		return nil, fmt.Errorf("Error: invalid map entries limitation (%d)", o.limitMapEntries)
+	}
+	if o.limitIndexEntries < 1 {
+		return nil, fmt.Errorf("Error: invalid index entries limitation (%d)", o.limitIndexEntries)
+	}
+	if o.maxBodyBytes < 0 {
+		return nil, fmt.Errorf("Error: invalid max body size (%d)", o.maxBodyBytes)
+	}
+	if o.maxDocumentNodes < 0 {
+		return nil, fmt.Errorf("Error: invalid max num of document nodes (%d)", o.maxDocumentNodes)
+	}
+
+	for _, source := range strings.Split(f.lastmodSources, ",") {
+		source = strings.TrimSpace(source)
+		if source != string(sitemap.ModifiedHeader) && source != string(sitemap.ModifiedDocument) {
+			return nil, fmt.Errorf("Error: unknown lastmod source %q", source)
+		}
+		o.modifiedSources = append(o.modifiedSources, sitemap.ModifiedSource(source))
+	}
+
+	for _, source := range strings.Split(f.linkSources, ",") {
+		ls, err := sitemap.ParseLinkSource(source)
+		if err != nil {
+			return nil, fmt.Errorf("Error: %v.", err)
+		}
+		o.linkSources = append(o.linkSources, ls)
+	}
+
+	for _, t := range strings.Split(f.acceptedTypes, ",") {
+		o.acceptedTypes = append(o.acceptedTypes, strings.TrimSpace(t))
+	}
+	if _, err := sitemap.NewParser(sitemap.WithAcceptedTypes(o.acceptedTypes...)); err != nil {
+		return nil, fmt.Errorf("Error: %v.", err)
+	}
+
+	if f.resourceTypes != "" {
+		for _, t := range strings.Split(f.resourceTypes, ",") {
+			o.resourceTypes = append(o.resourceTypes, strings.TrimSpace(t))
+		}
+		if _, err := sitemap.NewParser(sitemap.WithResourceTypes(o.resourceTypes...)); err != nil {
+			return nil, fmt.Errorf("Error: %v.", err)
+		}
+	}
+
+	if f.graphList != "" {
+		for _, format := range strings.Split(f.graphList, ",") {
+			format = strings.TrimSpace(format)
+			if format != "dot" && format != "graphml" && format != "json" {
+				return nil, fmt.Errorf("Error: unknown graph format %q", format)
+			}
+			o.graphFormats = append(o.graphFormats, format)
+		}
+	}
+
+	o.startURL, err = sitemap.NewURI(start)
+	if err != nil {
+		return nil, fmt.Errorf("Error: %v.", err)
+	}
+	if f.seedList != "" {
+		if o.seeds, err = parseSeeds(o.startURL, strings.Split(f.seedList, ",")); err != nil {
+			return nil, fmt.Errorf("Error: %v.", err)
+		}
+	}
+	if o.request, err = o.requestOptions(); err != nil {
+		return nil, fmt.Errorf("Error: %v.", err)
+	}
+	return o, nil
+}
+
+// requestOptions - builds parser options to customize requests: headers, authentication and cookies.
+func (o *crawlOptions) requestOptions() ([]sitemap.ParserOption, error) {
+	options := []sitemap.ParserOption{}
+	if o.userAgent != "" {
+		options = append(options, sitemap.WithUserAgent(o.userAgent))
+	}
+	for _, h := range o.headers {
+		parts := strings.SplitN(h, ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid header %q, expected \"Name: Value\"", h)
+		}
+		options = append(options, sitemap.WithHeader(strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])))
+	}
+	if o.basicAuth != "" && o.bearerToken != "" {
+		return nil, fmt.Errorf("basic auth and bearer token can not be used together")
+	}
+	if o.basicAuth != "" {
+		parts := strings.SplitN(o.basicAuth, ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid basic auth credentials, expected \"user:password\"")
+		}
+		options = append(options, sitemap.WithBasicAuth(parts[0], parts[1]))
+	}
+	if o.bearerToken != "" {
+		options = append(options, sitemap.WithBearerToken(o.bearerToken))
+	}
+	if len(o.cookies) > 0 || o.cookieFile != "" {
+		jar, _ := cookiejar.New(nil)
+		cookies := []*http.Cookie{}
+		for _, c := range o.cookies {
+			parts := strings.SplitN(c, "=", 2)
+			if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+				return nil, fmt.Errorf("invalid cookie %q, expected \"name=value\"", c)
+			}
+			cookies = append(cookies, &http.Cookie{Name: strings.TrimSpace(parts[0]), Value: parts[1], Path: "/"})
+		}
+		jar.SetCookies(o.startURL.URL, cookies)
+		if o.cookieFile != "" {
+			f, err := os.Open(o.cookieFile)
+			if err != nil {
+				return nil, fmt.Errorf("unable to read cookies: %s", err)
+			}
+			_, err = sitemap.LoadCookieFile(jar, f)
+			f.Close()
+			if err != nil {
+				return nil, err
+			}
+		}
+		options = append(options, sitemap.WithCookieJar(jar))
+	}
+	if o.loginURL != "" {
+		login, err := o.formLogin()
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, sitemap.WithFormLogin(login))
+	} else if o.loginPage != "" || len(o.loginFields) > 0 {
+		return nil, fmt.Errorf("login URL is required to submit login form")
+	}
+	// invalid values are reported by options
+	if _, err := sitemap.NewParser(options...); err != nil {
+		return nil, err
+	}
+	return options, nil
+}
+
+// formLogin - builds settings of login form.
+func (o *crawlOptions) formLogin() (sitemap.FormLogin, error) {
+	login := sitemap.FormLogin{Fields: url.Values{}}
+	var err error
+	if login.URL, err = sitemap.NewURI(o.loginURL); err != nil {
+		return login, fmt.Errorf("invalid login URL: %s", err)
+	}
+	if o.loginPage != "" {
+		if login.Page, err = sitemap.NewURI(o.loginPage); err != nil {
+			return login, fmt.Errorf("invalid login page: %s", err)
+		}
+	}
+	for _, field := range o.loginFields {
+		parts := strings.SplitN(field, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return login, fmt.Errorf("invalid login field %q, expected \"name=value\"", field)
+		}
+		login.Fields.Add(parts[0], parts[1])
+	}
+	return login, nil
+}
+
+// set - applies settings to flags, "url" setting is skipped, `denied` settings are treated as invalid.
+func (f *crawlFlags) set(s siteSettings, denied ...string) error {
+	denied = append(denied, "config", "h", "help")
+	for _, name := range settingNames(s) {
+		if name == "url" {
+			continue
+		}
+		for _, d := range denied {
+			if name == d {
+				return fmt.Errorf("option %q is not allowed here", name)
+			}
+		}
+		if f.Lookup(name) == nil {
+			return fmt.Errorf("unknown option %q", name)
+		}
+		if list, ok := f.Lookup(name).Value.(*listFlag); ok {
+			// list from settings replaces list given before
+			*list = nil
+			for _, v := range s[name] {
+				if err := list.Set(v); err != nil {
+					return fmt.Errorf("invalid value %q for option %q: %s", v, name, err)
+				}
+			}
+			continue
+		}
+		if err := f.Set(name, s.value(name)); err != nil {
+			return fmt.Errorf("invalid value %q for option %q: %s", s.value(name), name, err)
+		}
+	}
+	return nil
+}
+
+// override - applies flags which are given explicitly in command line.
+func (f *crawlFlags) override(cmd *crawlFlags) {
+	cmd.Visit(func(fl *flag.Flag) {
+		if fl.Name == "config" {
+			return
+		}
+		if list, ok := fl.Value.(*listFlag); ok {
+			*f.Lookup(fl.Name).Value.(*listFlag) = append(listFlag{}, *list...)
+			return
+		}
+		f.Set(fl.Name, fl.Value.String())
+	})
+}
+
+// siteDirName - returns name of site subdirectory built from host, port and path of start URI.
+func siteDirName(uri *sitemap.URI) string {
+	name := strings.Replace(uri.Host, ":", "_", -1)
+	for _, segment := range strings.Split(uri.Path, "/") {
+		if segment != "" {
+			name += "_" + segment
+		}
+	}
+	return name
+}
+
+// parseCrawlOptions - parses and validates arguments of crawl command and settings of config file if it is given.
+// Every argument is start URI of site to crawl, if config file describes the only site,
+// its settings are used for every URI given in command line.
+// When several sites are crawled, maps of every site are saved into subdirectory of output directory.
+// Returns flag.ErrHelp if help is requested and errUsage if arguments are invalid,
+// in both cases usage is printed into `stderr`.
+func parseCrawlOptions(args []string, stderr io.Writer) (*crawlBatch, error) {
+	flags := newCrawlFlags(stderr)
+	fail := func(format string, args ...interface{}) (*crawlBatch, error) {
+		fmt.Fprintf(stderr, format, args...)
+		flags.Usage()
+		return nil, errUsage
+	}
+
+	// -h and -help are handled by flag set, usage is already printed
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return nil, err
+		}
+		return nil, errUsage
+	}
+
+	common, sites := siteSettings{}, []siteSettings{}
+	if flags.config != "" {
+		var err error
+		if common, sites, err = loadConfig(flags.config); err != nil {
+			return fail("Error: %s.\n\n", err)
+		}
+		if len(sites) > 1 && flags.NArg() > 0 {
+			return fail("Error: URI can not be given when config describes several sites.\n\n")
+		}
+	}
+	if flags.NArg() > 0 {
+		template := siteSettings{}
+		if len(sites) == 1 {
+			template = sites[0]
+		}
+		sites = []siteSettings{}
+		for _, uri := range flags.Args() {
+			site := siteSettings{}
+			for k, v := range template {
+				site[k] = v
+			}
+			site["url"] = []string{uri}
+			sites = append(sites, site)
+		}
+	}
+	if len(sites) == 0 {
+		sites = append(sites, siteSettings{})
+	}
+
+	batch := newCrawlFlags(stderr)
+	if err := batch.set(common); err != nil {
+		return fail("Error: %s.\n\n", err)
+	}
+	batch.override(flags)
+	result := &crawlBatch{
+		maxWorkers:         batch.maxWorkers,
+		combinedIndex:      batch.combinedIndex,
+		outputDir:          batch.o.outputDir,
+		limitFileSizeBytes: batch.o.limitFileSizeBytes,
+		limitIndexEntries:  batch.o.limitIndexEntries,
+	}
+
+	multi := len(sites) > 1
+	dirs, metrics := map[string]int{}, map[string]int{}
+	seedFiles := seedsSource{}
+	for i, site := range sites {
+		siteFlags := newCrawlFlags(stderr)
+		if err := siteFlags.set(common); err != nil {
+			return fail("Error: %s.\n\n", err)
+		}
+		if err := siteFlags.set(site, batchFlags...); err != nil {
+			return fail("Error: site #%d: %s.\n\n", i+1, err)
+		}
+		// flags given explicitly override file values
+		siteFlags.override(flags)
+		start := common.value("url")
+		if _, ok := site["url"]; ok {
+			start = site.value("url")
+		}
+		o, err := siteFlags.options(start)
+		if err != nil {
+			if !multi {
+				return fail("%s\n\n", err)
+			}
+			return fail("Site #%d: %s\n\n", i+1, err)
+		}
+		if siteFlags.seedsFile != "" {
+			list, err := seedFiles.read(siteFlags.seedsFile)
+			if err != nil {
+				return fail("Error: %s.\n\n", err)
+			}
+			seeds, err := parseSeeds(o.startURL, list)
+			if err != nil {
+				return fail("Error: %s.\n\n", err)
+			}
+			o.seeds = append(o.seeds, seeds...)
+		}
+		if multi {
+			if o.mapBaseURL == nil {
+				return fail("Error: map base URL is required when several sites are crawled.\n\n")
+			}
+			o.outputDir = filepath.Join(o.outputDir, siteDirName(o.startURL))
+			o.mapBaseURL = o.mapBaseURL.ResolveReference(&url.URL{Path: siteDirName(o.startURL) + "/"})
+		}
+		dir, _ := filepath.Abs(o.outputDir)
+		if j, ok := dirs[dir]; ok {
+			return fail("Error: sites #%d and #%d use the same output directory %s.\n\n", j, i+1, o.outputDir)
+		}
+		dirs[dir] = i + 1
+		if o.metricsAddr != "" {
+			if j, ok := metrics[o.metricsAddr]; ok {
+				return fail("Error: sites #%d and #%d use the same metrics address %s.\n\n", j, i+1, o.metricsAddr)
+			}
+			metrics[o.metricsAddr] = i + 1
+		}
+		result.sites = append(result.sites, o)
+	}
+	if result.combinedIndex != "" {
+		if !multi {
+			return fail("Error: combined index requires several sites.\n\n")
+		}
+		if stat, err := os.Stat(result.outputDir); err != nil || !stat.IsDir() {
+			return fail("Can not use output directory for combined index: %s.\n\n", result.outputDir)
+		}
+	}
+	return result, nil
+}
+
+// crawl - builds site map according given options, all messages are logged with `l`,
+// progress is shown in place if `stdout` is terminal line of logger, otherwise it is logged too.
+// Returns URIs of saved map files and exit code: 0 on success, 1 if crawl was interrupted or failed.
+func crawl(ctx context.Context, o *crawlOptions, l logger, stdout io.Writer) ([]string, int) {
+	l.Printf(
+		"Started for %q, depth: %d, workers: %d, output format: %q, output dir: %s\n",
+		o.startURL.String(),
+		o.depth,
+		o.numWorkers,
+		o.outputFormat,
+		o.outputDir,
+	)
+
+	options := []sitemap.ParserOption{
+		sitemap.WithErrorHandler(func(e error) {
+			l.Println("PARSER", "WRN", e)
+		}),
+		sitemap.WithModifiedSources(o.modifiedSources...),
+		sitemap.WithLinkSources(o.linkSources...),
+		sitemap.WithAcceptedTypes(o.acceptedTypes...),
+		sitemap.WithResourceTypes(o.resourceTypes...),
+	}
+	if o.pool != nil {
+		options = append(options, sitemap.WithWorkerPool(o.pool))
+	}
+	options = append(options, o.request...)
+	if len(o.seeds) > 0 {
+		l.Println("Additional seeds:", len(o.seeds))
+		options = append(options, sitemap.WithSeeds(o.seeds...))
+	}
+	var state *sitemap.CrawlState
+	stateFile := filepath.Join(o.outputDir, crawlStateFilename)
+	if o.incremental {
+		var err error
+		state, err = loadCrawlState(stateFile)
+		if err != nil {
+			l.Println("ERR", err)
+			return nil, 1
+		}
+		l.Println("Crawl state loaded, num of known pages:", state.Len())
+		options = append(options, sitemap.WithCrawlState(state))
+	}
+	if o.volatileSelectors != "" {
+		options = append(options, sitemap.WithVolatileSelectors(o.volatileSelectors))
+	}
+	if o.headProbe {
+		options = append(options, sitemap.WithHeadProbe())
+	}
+	if o.maxBodyBytes > 0 {
+		options = append(options, sitemap.WithMaxBodyBytes(o.maxBodyBytes))
+	}
+	if o.maxDocumentNodes > 0 {
+		options = append(options, sitemap.WithMaxDocumentNodes(o.maxDocumentNodes))
+	}
+	checkpointFile := filepath.Join(o.outputDir, checkpointFilename)
+	var cp *sitemap.Checkpoint
+	if o.resume {
+		var err error
+		if cp, err = loadCheckpoint(checkpointFile); err != nil {
+			l.Println("ERR", err)
+			return nil, 1
+		}
+		if cp.Root != o.startURL.String() {
+			l.Printf("ERR checkpoint was saved for %q\n", cp.Root)
+			return nil, 1
+		}
+	}
+	// files of disk stores are kept if checkpoint refers to them, until crawl is completed
+	completed := false
+	var frontier *sitemap.DiskFrontier
+	var visited *sitemap.DiskVisitedStore
+	if o.diskStore {
+		var err error
+		frontierFile, _ := filepath.Abs(filepath.Join(o.outputDir, frontierFilename))
+		if cp != nil && cp.FrontierLog != nil {
+			frontierFile = cp.FrontierLog.File
+			frontier, err = sitemap.OpenDiskFrontier(*cp.FrontierLog)
+		} else {
+			frontier, err = sitemap.NewDiskFrontier(frontierFile)
+		}
+		if err != nil {
+			l.Println("ERR", err)
+			return nil, 1
+		}
+		defer func() {
+			frontier.Close()
+			if completed {
+				os.Remove(frontierFile)
+			}
+		}()
+		visitedFile, _ := filepath.Abs(filepath.Join(o.outputDir, visitedFilename))
+		if cp != nil && cp.VisitedLog != nil {
+			visitedFile = cp.VisitedLog.File
+			visited, err = sitemap.OpenDiskVisitedStore(*cp.VisitedLog, o.diskStoreCapacity, visitedFalsePositiveRate)
+		} else {
+			visited, err = sitemap.NewDiskVisitedStore(visitedFile, o.diskStoreCapacity, visitedFalsePositiveRate)
+		}
+		if err != nil {
+			l.Println("ERR", err)
+			return nil, 1
+		}
+		defer func() {
+			visited.Close()
+			if completed {
+				os.Remove(visitedFile)
+			}
+		}()
+		options = append(options, sitemap.WithFrontier(frontier), sitemap.WithVisitedStore(visited))
+	}
+	if o.checkpointInterval > 0 {
+		options = append(options, sitemap.WithCheckpoint(o.checkpointInterval, func(cp *sitemap.Checkpoint) {
+			if err := saveCheckpoint(checkpointFile, cp); err != nil {
+				l.Println("CHECKPOINT", "ERR", err)
+			}
+		}))
+	}
+	var crawlMetrics *metrics
+	if o.metricsAddr != "" {
+		crawlMetrics = newMetrics()
+		options = append(options, sitemap.WithHooks(crawlMetrics.hooks()))
+	}
+	var collector *report.Collector
+	if o.crawlReport {
+		collector = report.NewCollector()
+		options = append(options, sitemap.WithHooks(collector.Hooks()))
+	}
+	var graph *sitemap.LinkGraph
+	if len(o.graphFormats) > 0 {
+		graph = sitemap.NewLinkGraph()
+		options = append(options, sitemap.WithLinkGraph(graph))
+	}
+	parser, err := sitemap.NewParser(options...)
+	if err != nil {
+		l.Println("Parser can not be started:", err)
+		return nil, 1
+	}
+	if crawlMetrics != nil {
+		crawlMetrics.parser = parser
+		server := &http.Server{Addr: o.metricsAddr, Handler: metricsHandler(crawlMetrics)}
+		go func() {
+			if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				l.Println("METRICS", "ERR", err)
+			}
+		}()
+		defer func() {
+			if o.metricsLinger > 0 && ctx.Err() == nil {
+				l.Println("Metrics are served for", o.metricsLinger, "more")
+				lingerMetrics(ctx, o.metricsLinger)
+			}
+			server.Close()
+		}()
+		l.Println("Metrics are served at", o.metricsAddr+"/metrics")
+	}
+
+	maps, err := newMapWriter(o.limitMapEntries, o.limitFileSizeBytes, o.mapFilename, o.outputFormat, o.outputDir)
+	if err != nil {
+		l.Println("ERR", err)
+		return nil, 1
+	}
+
+	var results <-chan sitemap.MapItem
+	if cp != nil {
+		numVisited, numQueued := len(cp.Visited), len(cp.Frontier)
+		if visited != nil {
+			numVisited += visited.Len()
+			numQueued += frontier.Len()
+		}
+		l.Printf(
+			"Parser has resumed, depth: %d, visited: %d, queued: %d...\n",
+			cp.Depth,
+			numVisited,
+			numQueued,
+		)
+		if results, err = parser.Resume(ctx, cp, o.numWorkers); err != nil {
+			l.Println("ERR", err)
+			return nil, 1
+		}
+	} else {
+		l.Println("Parser has launched...")
+		results = parser.Stream(ctx, o.startURL, o.depth, o.numWorkers)
+	}
+	progressDone := make(chan struct{})
+	progressStopped := make(chan struct{})
+	go func() {
+		defer close(progressStopped)
+		if o.progress {
+			reportProgress(parser, l, stdout, progressDone)
+		}
+	}()
+	found := 0
+	for item := range results {
+		found++
+		// items are written as soon as they are found, error is reported when files are committed
+		maps.Write(item)
+	}
+	close(progressDone)
+	<-progressStopped
+	if state != nil {
+		if ctx.Err() == nil && !o.resume && parser.Stats().Errors[sitemap.ErrorLogin] == 0 {
+			// pages of resumed or incomplete crawl may be not visited yet
+			l.Println("Crawl state pruned, num of removed pages:", state.Prune())
+		}
+		if err := saveCrawlState(stateFile, state); err != nil {
+			l.Println("STATE", "ERR", err)
+		} else {
+			l.Println("STATE", "OK", stateFile)
+		}
+	}
+	if collector != nil {
+		for file, err := range saveReport(collector.Report(), filepath.Join(o.outputDir, reportFilename)) {
+			if err != nil {
+				l.Println("REPORT", "ERR", file, err)
+			} else {
+				l.Println("REPORT", "OK", file)
+			}
+		}
+	}
+	if graph != nil {
+		for file, err := range saveGraph(graph, o.graphFormats, o.graphClusterDepth, filepath.Join(o.outputDir, graphFilename)) {
+			if err != nil {
+				l.Println("GRAPH", "ERR", file, err)
+			} else {
+				l.Println("GRAPH", "OK", file)
+			}
+		}
+	}
+	if ctx.Err() != nil {
+		maps.Discard()
+		l.Println("Interrupted, num of links found:", found)
+		if o.checkpointInterval > 0 {
+			l.Println("Crawl can be continued with -resume option")
+		}
+		return nil, 1
+	}
+	if parser.Stats().Errors[sitemap.ErrorLogin] > 0 {
+		maps.Discard()
+		l.Println("Aborted, login failed or session is lost, num of links found:", found)
+		return nil, 1
+	}
+	l.Println("Completed, num of links found:", found)
+	// crawl is completed, so checkpoint is not needed anymore
+	completed = true
+	os.Remove(checkpointFile)
+	if found == 0 {
+		l.Println("Stop on empty map")
+		return nil, 0
+	}
+
+	l.Println("Started saving site map...")
+	numErrors := 0
+	index := []string{}
+	for file, err := range maps.Commit() {
+		if err != nil {
+			numErrors++
+			l.Println("MAP", "ERR", file, err)
+		} else {
+			l.Println("MAP", "OK", file)
+		}
+		// index should contain URI, not local file names,
+		// map files are published at base URL or in directory of start URL
+		base := o.startURL.URL
+		if o.mapBaseURL != nil {
+			base = o.mapBaseURL
+		}
+		index = append(index, mapLink(base, filepath.Base(file)))
+	}
+	if numErrors > 0 {
+		l.Println("Map saving stage done with error(s):", numErrors)
+		return nil, 1
+	}
+
+	if len(index) > 1 {
+		l.Println("Started saving index ...")
+		numErrors = 0
+		for file, err := range ensureIndex(
+			index,
+			o.limitIndexEntries,
+			o.limitFileSizeBytes,
+			o.indexFilename,
+			o.outputDir,
+		) {
+			if err != nil {
+				numErrors++
+				l.Println("INDEX", "ERR", file, err)
+			} else {
+				l.Println("INDEX", "OK", file)
+			}
+		}
+		if numErrors > 0 {
+			l.Println("Index saving stage done with error(s):", numErrors)
+			return nil, 1
+		}
+	}
+
+	l.Println("All done")
+	return index, 0
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func Test_crawlOptions_requestOptions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "smgen-request")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cookieFile := filepath.Join(dir, "cookies.txt")
+	if err := ioutil.WriteFile(cookieFile, []byte("localhost\tFALSE\t/\tFALSE\t0\tsession\tabc\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	invalidCookieFile := filepath.Join(dir, "invalid.txt")
+	if err := ioutil.WriteFile(invalidCookieFile, []byte("localhost\tsession\tabc\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		args  []string
+		num   int
+		isErr bool
+	}{
+		{[]string{}, 0, false},
+		{[]string{"-user-agent", "smgen/1.0", "-header", "X-Env: staging", "-header", "Accept: text/html, */*"}, 3, false},
+		{[]string{"-basic-auth", "user:pass:word"}, 1, false},
+		{[]string{"-bearer-token", "token", "-cookie", "a=1", "-cookie", "b=", "-cookie-file", cookieFile}, 2, false},
+		{[]string{"-header", "X-Env"}, 0, true},
+		{[]string{"-header", "X Env: staging"}, 0, true},
+		{[]string{"-basic-auth", "user"}, 0, true},
+		{[]string{"-basic-auth", "user:pass", "-bearer-token", "token"}, 0, true},
+		{[]string{"-cookie", "=value"}, 0, true},
+		{[]string{"-cookie-file", filepath.Join(dir, "missing.txt")}, 0, true},
+		{[]string{"-cookie-file", invalidCookieFile}, 0, true},
+		{[]string{"-login-url", "http://localhost/login", "-login-fields", "user=u", "-login-fields", "password=a=b"}, 1, false},
+		{[]string{"-login-fields", "user=u"}, 0, true},
+		{[]string{"-login-url", "http://localhost/login", "-login-fields", "user"}, 0, true},
+		{[]string{"-login-url", "/login", "-login-fields", "user=u"}, 0, true},
+	}
+	for _, c := range cases {
+		args := append(c.args, "-output-dir", dir, "http://localhost/")
+		batch, err := parseCrawlOptions(args, ioutil.Discard)
+		if c.isErr != (err != nil) {
+			t.Errorf("Unexpected error for %v: %v", c.args, err)
+			continue
+		}
+		if err == nil && len(batch.sites[0].request) != c.num {
+			t.Errorf("Expected %d request options for %v, got %d", c.num, c.args, len(batch.sites[0].request))
+		}
+	}
+}
+
+func Test_run_authenticated(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, ok := r.BasicAuth()
+		cookie, _ := r.Cookie("session")
+		if !ok || user != "user" || password != "secret" || cookie == nil || cookie.Value != "abc" ||
+			r.UserAgent() != "smgen-test" || r.Header.Get("X-Env") != "staging" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head></head><body>Staging</body></html>`))
+	}))
+	defer server.Close()
+	dir, err := ioutil.TempDir("", "smgen-auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	config := filepath.Join(dir, "staging.yaml")
+	content := "url: " + server.URL + "/\nheader:\n  - \"X-Env: staging\"\nbasic-auth: user:${SMGEN_TEST_PASSWORD}\n"
+	if err := ioutil.WriteFile(config, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	os.Setenv("SMGEN_TEST_PASSWORD", "secret")
+	defer os.Unsetenv("SMGEN_TEST_PASSWORD")
+
+	stdout := &bytes.Buffer{}
+	args := []string{"-config", config, "-output-dir", dir, "-user-agent", "smgen-test", "-cookie", "session=abc"}
+	if code := run(args, stdout, ioutil.Discard); code != 0 {
+		t.Fatalf("Unexpected exit code %d, output:\n%s", code, stdout)
+	}
+	if !strings.Contains(stdout.String(), "Completed, num of links found: 1") {
+		t.Errorf("Expected authenticated page is found, output:\n%s", stdout)
+	}
+}
+
+func Test_run_formLogin(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		if r.PostFormValue("username") != "user" || r.PostFormValue("password") != "secret" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "ok", Path: "/"})
+	})
+	mux.HandleFunc("/members/", func(w http.ResponseWriter, r *http.Request) {
+		if cookie, err := r.Cookie("session"); err != nil || cookie.Value != "ok" {
+			http.Redirect(w, r, "/login", http.StatusFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head></head><body>Members</body></html>`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	dir, err := ioutil.TempDir("", "smgen-login")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	config := filepath.Join(dir, "members.yaml")
+	content := "url: " + server.URL + "/members/\nlogin:\n  url: " + server.URL + "/login\n" +
+		"  fields:\n    username: user\n    password: secret\n"
+	if err := ioutil.WriteFile(config, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		args     []string
+		expected int
+		output   string
+	}{
+		{[]string{"-config", config, "-output-dir", dir}, 0, "Completed, num of links found: 1"},
+		{
+			[]string{"-config", config, "-output-dir", dir, "-login-fields", "username=user", "-login-fields", "password=wrong"},
+			1,
+			"Aborted, login failed or session is lost",
+		},
+	}
+	for _, c := range cases {
+		stdout := &bytes.Buffer{}
+		if code := run(c.args, stdout, ioutil.Discard); code != c.expected {
+			t.Errorf("Unexpected exit code %d for %v, output:\n%s", code, c.args, stdout)
+		}
+		if !strings.Contains(stdout.String(), c.output) {
+			t.Errorf("Expected output contains %q, got:\n%s", c.output, stdout)
+		}
+	}
+}
+
+func Test_syncWriter(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w := &syncWriter{w: buf}
+	wg := sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				w.Write([]byte("line\n"))
+			}
+		}()
+	}
+	wg.Wait()
+	if lines := strings.Count(buf.String(), "line\n"); lines != 800 {
+		t.Fatal("expected 800 lines, got", lines)
+	}
+}
+
+func Test_run_combinedIndex(t *testing.T) {
+	server := httptest.NewServer(http.FileServer(http.Dir("../../internal/sitemap/testdata/simplesite")))
+	defer server.Close()
+	dir, err := ioutil.TempDir("", "smgen-batch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	local := strings.Replace(server.URL, "127.0.0.1", "localhost", 1)
+	args := []string{
+		"-output-dir", dir, "-depth", "2", "-map-limit", "2", "-combined-index", "all",
+		"-map-base-url", "https://maps.example.com/sites",
+		server.URL + "/homepage.html", local + "/homepage.html",
+	}
+	stdout := &bytes.Buffer{}
+	if code := run(args, stdout, ioutil.Discard); code != 0 {
+		t.Fatalf("Unexpected exit code %d, output:\n%s", code, stdout)
+	}
+
+	port := server.URL[strings.LastIndex(server.URL, ":")+1:]
+	maps := func(site string) []string {
+		base := "https://maps.example.com/sites/" + site + "_" + port + "_homepage.html/"
+		return []string{base + "sitemap1.xml", base + "sitemap2.xml"}
+	}
+	expected := map[string][]string{
+		filepath.Join(dir, "127.0.0.1_"+port+"_homepage.html", "sitemap_index.xml"): maps("127.0.0.1"),
+		filepath.Join(dir, "localhost_"+port+"_homepage.html", "sitemap_index.xml"): maps("localhost"),
+		filepath.Join(dir, "all.xml"): append(maps("127.0.0.1"), maps("localhost")...),
+	}
+	for file, links := range expected {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// order of map files in index is not defined
+		actual := locs(string(content))
+		sort.Strings(actual)
+		if !reflect.DeepEqual(actual, links) {
+			t.Errorf("Unexpected links of %s: %v, expected %v", file, actual, links)
+		}
+	}
+}
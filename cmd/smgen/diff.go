@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/wtask/sitemap/internal/sitemap"
+	"github.com/wtask/sitemap/internal/sitemap/load"
+	"github.com/wtask/sitemap/internal/sitemap/render"
+)
+
+// diffCommand - compares two site maps.
+// Returns exit code like diff utility does: 0 if site maps are equal, 1 if they differ, 2 on error.
+func diffCommand(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("diff", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, `Compare two site maps (local files or URLs, maps or indexes, plain or gzipped)
+and report added, removed and lastmod-changed URLs:
+
+	smgen diff [options] OLD NEW
+
+Options:
+
+`)
+		flags.PrintDefaults()
+		fmt.Fprint(stderr, "\n")
+	}
+	asJSON := flags.Bool("json", false, "Print difference in JSON format.")
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+	if flags.NArg() != 2 {
+		fmt.Fprint(stderr, "Error: OLD and NEW site maps are required.\n\n")
+		flags.Usage()
+		return 2
+	}
+
+	maps := [2][]sitemap.MapItem{}
+	for i, source := range flags.Args() {
+		m, err := load.Sitemap(context.Background(), source)
+		if err != nil {
+			fmt.Fprintln(stderr, "ERR", err)
+			return 2
+		}
+		maps[i] = m
+	}
+	d := sitemap.Diff(maps[0], maps[1])
+	var err error
+	if *asJSON {
+		err = render.DiffJSON(stdout, d)
+	} else {
+		err = render.DiffText(stdout, d)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "ERR", err)
+		return 2
+	}
+	if !d.Empty() {
+		return 1
+	}
+	return 0
+}
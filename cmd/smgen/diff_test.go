@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_run_diff(t *testing.T) {
+	dir, err := ioutil.TempDir("", "smgen-diff")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	old := filepath.Join(dir, "old.xml")
+	content := `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+	<url><loc>http://localhost/</loc><lastmod>2019-05-21</lastmod></url>
+	<url><loc>http://localhost/removed.html</loc></url>
+</urlset>
+`
+	if err := ioutil.WriteFile(old, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	new := filepath.Join(dir, "new.xml")
+	content = `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+	<url><loc>http://localhost/</loc><lastmod>2019-05-22</lastmod></url>
+	<url><loc>http://localhost/added.html</loc></url>
+</urlset>
+`
+	if err := ioutil.WriteFile(new, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name     string
+		args     []string
+		expected int
+		output   []string
+	}{
+		{"help", []string{"diff", "-h"}, 0, nil},
+		{"single map", []string{"diff", old}, 2, nil},
+		{"missing map", []string{"diff", old, filepath.Join(dir, "missing.xml")}, 2, nil},
+		{"same", []string{"diff", old, old}, 0, []string{"Added: 0, removed: 0, changed: 0"}},
+		{
+			"changed",
+			[]string{"diff", old, new},
+			1,
+			[]string{
+				"+ http://localhost/added.html\n",
+				"- http://localhost/removed.html\n",
+				"~ http://localhost/ \"2019-05-21T00:00:00Z\" -> \"2019-05-22T00:00:00Z\"\n",
+				"Added: 1, removed: 1, changed: 1\n",
+			},
+		},
+		{"json", []string{"diff", "-json", old, new}, 1, []string{`"added"`, `"removed"`, `"changed"`}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+			if code := run(c.args, stdout, stderr); code != c.expected {
+				t.Errorf("Unexpected exit code %d, expected %d, stdout:\n%s\nstderr:\n%s", code, c.expected, stdout, stderr)
+			}
+			for _, line := range c.output {
+				if !strings.Contains(stdout.String(), line) {
+					t.Errorf("Expected output contains %q, got:\n%s", line, stdout)
+				}
+			}
+			if c.name == "json" && !json.Valid(stdout.Bytes()) {
+				t.Errorf("Expected JSON output, got:\n%s", stdout)
+			}
+		})
+	}
+}
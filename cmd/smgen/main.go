@@ -2,22 +2,42 @@ package main
 
 import (
 	"fmt"
-	"log"
+	"io"
 	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/wtask/sitemap/internal/compression"
 
 	"github.com/wtask/sitemap/internal/sitemap/render"
+	"github.com/wtask/sitemap/internal/sitemap/report"
 
 	"github.com/wtask/sitemap/internal/sitemap"
 )
 
-// mapSaver - func which saves a whole map or it part and returns size of file and error.
-type mapSaver func(filename string, chunk []sitemap.MapItem) (int64, error)
+// crawlStateFilename - name of file inside output directory to keep crawl state between runs.
+const crawlStateFilename = "smgen.state.json"
+
+// checkpointFilename - name of file inside output directory to keep crawl progress.
+const checkpointFilename = "smgen.checkpoint.json"
+
+// frontierFilename, visitedFilename - names of files inside output directory to keep crawl queue and visited pages.
+const (
+	frontierFilename = "smgen.frontier.log"
+	visitedFilename  = "smgen.visited.log"
+)
+
+// reportFilename - base name of crawl report files inside output directory.
+const reportFilename = "smgen.report"
+
+// graphFilename - base name of link graph files inside output directory.
+const graphFilename = "smgen.graph"
+
+// visitedFalsePositiveRate - acceptable rate of pages which are wrongly considered visited with disk store.
+const visitedFalsePositiveRate = 0.0001
 
 // logger - internal logging interface with only used methods
 type logger interface {
@@ -25,94 +45,177 @@ type logger interface {
 	Printf(format string, v ...interface{})
 }
 
+// usage - common usage help, the rest of help is printed by crawl command.
+const usage = `Generate site map suggested by https://www.sitemaps.org/protocol.html, starting from given URI:
+
+	smgen [crawl] [options] URI...
+
+Validate existing site map files:
+
+	smgen validate FILE...
+
+Compare published site map against crawl results:
+
+	smgen audit [options] SITEMAP [URI]
+
+Compare two site maps:
+
+	smgen diff [options] OLD NEW
+
+Combine existing site map files into site map index:
+
+	smgen merge [options] FILE...
+
+Run "smgen COMMAND -h" or "smgen COMMAND -help" to get options of command.
+
+`
+
+// command - subcommand of smgen, returns exit code.
+type command func(args []string, stdout, stderr io.Writer) int
+
+// commands - subcommands by name, crawl is the default command and is run when name is omitted.
+var commands = map[string]command{
+	"crawl":    crawlCommand,
+	"validate": validateCommand,
+	"audit":    auditCommand,
+	"diff":     diffCommand,
+	"merge":    mergeCommand,
+}
+
 func main() {
-	var l logger = log.New(os.Stdout, "smgen ", log.Ldate|log.Ltime)
-
-	l.Printf(
-		"Started for %q, depth: %d, workers: %d, output format: %q, output dir: %s\n",
-		startURL.String(),
-		depth,
-		numWorkers,
-		outputFormat,
-		outputDir,
-	)
-
-	parser, err := sitemap.NewParser(
-		sitemap.WithErrorHandler(func(e error) {
-			l.Println("PARSER", "WRN", e)
-		}),
-	)
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run - dispatches arguments to subcommand and returns exit code.
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) > 0 {
+		if cmd, ok := commands[args[0]]; ok {
+			return cmd(args[1:], stdout, stderr)
+		}
+	}
+	return crawlCommand(args, stdout, stderr)
+}
+
+// loadCrawlState - reads crawl state from given file.
+// Returns empty state if file does not exist.
+func loadCrawlState(filename string) (*sitemap.CrawlState, error) {
+	f, err := os.Open(filename)
+	if os.IsNotExist(err) {
+		return sitemap.NewCrawlState(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can not open crawl state: %s", err)
+	}
+	defer f.Close()
+	return sitemap.LoadCrawlState(f)
+}
+
+// saveCrawlState - writes crawl state into given file.
+func saveCrawlState(filename string, state *sitemap.CrawlState) error {
+	f, err := os.OpenFile(filename, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
 	if err != nil {
-		l.Println("Parser can not be started:", err)
-		os.Exit(1)
+		return fmt.Errorf("can not open file: %s", err)
 	}
+	defer f.Close()
+	return state.Save(f)
+}
 
-	l.Println("Parser has launched...")
-	m := parser.Parse(startURL, depth, numWorkers)
-	l.Println("Completed, num of links found:", len(m))
-	if len(m) == 0 {
-		l.Println("Stop on empty map")
-		return
+// loadCheckpoint - reads crawl checkpoint from given file.
+func loadCheckpoint(filename string) (*sitemap.Checkpoint, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("can not open checkpoint: %s", err)
 	}
+	defer f.Close()
+	return sitemap.LoadCheckpoint(f)
+}
 
-	saver, err := buildMapSaver(outputFormat)
+// saveCheckpoint - writes crawl checkpoint into given file.
+// Checkpoint is written into temporary file at first, so previous checkpoint is kept if writing failed.
+func saveCheckpoint(filename string, cp *sitemap.Checkpoint) error {
+	tmp := filename + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
 	if err != nil {
-		l.Println("ERR", err)
-		os.Exit(1)
+		return fmt.Errorf("can not open file: %s", err)
 	}
+	err = cp.Save(f)
+	f.Close()
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, filename)
+}
 
-	l.Println("Started saving site map...")
-	numErrors := 0
-	index := []string{}
-	for file, err := range saveMap(
-		m,
-		limitMapEntries,
-		limitFileSizeBytes,
-		mapFilename,
-		outputFormat,
-		outputDir,
-		saver,
-	) {
+// saveReport - writes crawl report in JSON and HTML formats into files with given base name.
+// Returns the map of file names and errors if any occurred when file was saving.
+func saveReport(r *report.Report, basename string) map[string]error {
+	files := map[string]error{}
+	for ext, write := range map[string]func(io.Writer) error{
+		"json": r.WriteJSON,
+		"html": r.WriteHTML,
+	} {
+		filename := basename + "." + ext
+		f, err := os.OpenFile(filename, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
 		if err != nil {
-			numErrors++
-			l.Println("MAP", "ERR", file, err)
-		} else {
-			l.Println("MAP", "OK", file)
+			files[filename] = fmt.Errorf("can not open file: %s", err)
+			continue
 		}
-		// index should contain URI, not local file names,
-		// we use startURL as base URI for map files links
-		rel, _ := url.Parse(filepath.Base(file))
-		index = append(index, startURL.ResolveReference(rel).String())
-	}
-	if numErrors > 0 {
-		l.Println("Map saving stage done with error(s):", numErrors)
-		os.Exit(1)
+		err = write(f)
+		f.Close()
+		files[filename] = err
 	}
+	return files
+}
 
-	if len(index) > 1 {
-		l.Println("Started saving index ...")
-		numErrors = 0
-		for file, err := range ensureIndex(
-			index,
-			limitIndexEntries,
-			limitFileSizeBytes,
-			indexFilename,
-			outputDir,
-		) {
-			if err != nil {
-				numErrors++
-				l.Println("INDEX", "ERR", file, err)
-			} else {
-				l.Println("INDEX", "OK", file)
-			}
+// saveGraph - writes link graph in given formats into files with given base name, format is used as file extension.
+// Returns the map of file names and errors if any occurred when file was saving.
+func saveGraph(g *sitemap.LinkGraph, formats []string, clusterDepth uint, basename string) map[string]error {
+	renderers := map[string]func(io.Writer, []sitemap.GraphNode, []sitemap.GraphEdge, uint) error{
+		"dot":     render.GraphDOT,
+		"graphml": render.GraphML,
+		"json":    render.GraphJSON,
+	}
+	nodes, edges := g.Nodes(), g.Edges()
+	files := map[string]error{}
+	for _, format := range formats {
+		filename := basename + "." + format
+		write, ok := renderers[format]
+		if !ok {
+			files[filename] = fmt.Errorf("format %q is not supported", format)
+			continue
 		}
-		if numErrors > 0 {
-			l.Println("Index saving stage done with error(s):", numErrors)
-			os.Exit(1)
+		f, err := os.OpenFile(filename, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+		if err != nil {
+			files[filename] = fmt.Errorf("can not open file: %s", err)
+			continue
 		}
+		err = write(f, nodes, edges, clusterDepth)
+		f.Close()
+		files[filename] = err
+	}
+	return files
+}
+
+// parseBaseURL - parses absolute http(s) URL of directory where generated files are published,
+// trailing slash is added to path if it is missing.
+func parseBaseURL(s string) (*url.URL, error) {
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid base URL %q, absolute http(s) URL is expected", s)
+	}
+	if !strings.HasSuffix(u.Path, "/") {
+		u.Path += "/"
+		u.RawPath = ""
 	}
+	u.RawQuery, u.Fragment = "", ""
+	return u, nil
+}
 
-	l.Println("All done")
+// mapLink - returns URI of generated file, `base` is URL of directory where file is published
+// (or URL of document inside the directory) and `rel` is local path of file relative to the directory.
+func mapLink(base *url.URL, rel string) string {
+	return base.ResolveReference(&url.URL{Path: filepath.ToSlash(rel)}).String()
 }
 
 // replaceWithGzip - compress file into gzip and remove origin if there was no error.
@@ -126,90 +229,140 @@ func replaceWithGzip(origin, gz string) error {
 	return err
 }
 
-// saveMap - asynchronously saves whole site map into files with no more than `itemsPerFile` in each.
-// If resulting file size is over `maxFileSizeBytes`, source will be replaced with gzip-compressed one.
-// Returns the map of file names and errors if any occurred when file was saving or compressing.
-func saveMap(
-	m []sitemap.MapItem,
+// mapEncoder - writes site map items into single file in some format, see render.XMLMapWriter.
+type mapEncoder interface {
+	Write(item sitemap.MapItem) error
+	Close() error
+}
+
+// mapEncoderFactory - func which starts site map of some format with given writer.
+type mapEncoderFactory func(w io.Writer) (mapEncoder, error)
+
+// buildMapEncoder - factory method to return map encoding according given format.
+// Now builds XML-encoder only.
+func buildMapEncoder(format string) (mapEncoderFactory, error) {
+	switch format {
+	case "xml":
+		return func(w io.Writer) (mapEncoder, error) {
+			return render.NewXMLMapWriter(w)
+		}, nil
+	default:
+		return nil, fmt.Errorf("format %q is not supported", format)
+	}
+}
+
+// mapWriter - writes site map items into files with no more than `maxEntries` items in each
+// as soon as items are received, so the whole map is not kept in memory.
+// Files are written with temporary names until Commit is called.
+type mapWriter struct {
+	maxEntries                     int
+	maxFileSizeBytes               int64
+	basename, extension, outputDir string
+	encode                         mapEncoderFactory
+
+	file    *os.File
+	encoder mapEncoder
+	entries int      // num of entries in current file
+	parts   []string // temporary file names
+	err     error
+}
+
+// newMapWriter - creates writer of site map files, files are not created until the first item is written.
+func newMapWriter(
 	maxEntriesPerFile int,
 	maxFileSizeBytes int64,
 	basename, extension, outputDir string,
-	saver mapSaver,
-) map[string]error {
-	numFiles, reminder := len(m)/maxEntriesPerFile, len(m)%maxEntriesPerFile
-	if reminder > 0 {
-		numFiles++
+) (*mapWriter, error) {
+	encode, err := buildMapEncoder(extension)
+	if err != nil {
+		return nil, err
 	}
-	wg := sync.WaitGroup{}
-	mx := sync.Mutex{} // protects files
-	files := make(map[string]error, numFiles)
-	filename := fmt.Sprintf("%s.%s", basename, extension) // if single file only
-	for i := 0; i < numFiles; i++ {
-		if numFiles > 1 {
-			filename = fmt.Sprintf("%s%d.%s", basename, i+1, extension)
+	return &mapWriter{
+		maxEntries:       maxEntriesPerFile,
+		maxFileSizeBytes: maxFileSizeBytes,
+		basename:         basename,
+		extension:        extension,
+		outputDir:        outputDir,
+		encode:           encode,
+	}, nil
+}
+
+// Write - writes item into current file, the next file is started when current one is full.
+// The first error is returned for all subsequent calls.
+func (w *mapWriter) Write(item sitemap.MapItem) error {
+	if w.err != nil {
+		return w.err
+	}
+	if w.encoder == nil || w.entries >= w.maxEntries {
+		if w.err = w.closeFile(); w.err != nil {
+			return w.err
 		}
-		start := i * maxEntriesPerFile
-		end := start + maxEntriesPerFile
-		if end > len(m) {
-			end = len(m)
+		filename := filepath.Join(w.outputDir, fmt.Sprintf("%s%d.%s.tmp", w.basename, len(w.parts)+1, w.extension))
+		if w.file, w.err = os.OpenFile(filename, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644); w.err != nil {
+			w.err = fmt.Errorf("can not open file: %s", w.err)
+			return w.err
 		}
-		wg.Add(1)
-		// save whole map in chunks, compress results if needed
-		go func(filename string, chunk []sitemap.MapItem) {
-			defer wg.Done()
-			filesize, err := saver(filename, chunk)
-			if err == nil && filesize > maxFileSizeBytes {
-				// TODO We can start new goroutine here
-				if err = replaceWithGzip(filename, filename+".gzip"); err == nil {
-					filename += ".gzip"
-				}
-			}
-			mx.Lock()
-			files[filename] = err
-			mx.Unlock()
-		}(
-			filepath.Join(outputDir, filename),
-			m[start:end],
-		)
+		w.parts = append(w.parts, filename)
+		if w.encoder, w.err = w.encode(w.file); w.err != nil {
+			return w.err
+		}
+		w.entries = 0
 	}
-
-	wg.Wait()
-
-	return files
-}
-
-// buildMapSaver - factory method to return map saving according given format.
-// Now builds XML-saver only.
-func buildMapSaver(format string) (mapSaver, error) {
-	switch format {
-	case "xml":
-		return saveMapXML, nil
-	default:
-		return nil, fmt.Errorf("format %q is not supported", format)
+	w.entries++
+	if w.err = w.encoder.Write(item); w.err != nil {
+		w.err = fmt.Errorf("render site map item %s failed: %s", item.URI, w.err)
 	}
+	return w.err
 }
 
-// saveMapXML - saves site map in XML format into the single file.
-func saveMapXML(filename string, m []sitemap.MapItem) (int64, error) {
-	var size int64
-	if len(m) == 0 {
-		return size, fmt.Errorf("site map is empty")
+// closeFile - completes current file if any.
+func (w *mapWriter) closeFile() error {
+	if w.file == nil {
+		return nil
 	}
-	f, err := os.OpenFile(filename, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
-	if err != nil {
-		return size, fmt.Errorf("can not open file: %s", err)
+	err := w.encoder.Close()
+	if e := w.file.Close(); err == nil {
+		err = e
 	}
-	defer f.Close()
-	err = render.XMLMap(f, m)
-	st, _ := f.Stat()
-	if st != nil {
-		size = st.Size()
+	w.file, w.encoder = nil, nil
+	return err
+}
+
+// Commit - completes files and renames them, single file is named without number.
+// If resulting file size is over `maxFileSizeBytes`, it will be replaced with gzip-compressed one.
+// Returns the map of file names and errors if any occurred when file was saving or compressing.
+func (w *mapWriter) Commit() map[string]error {
+	if err := w.closeFile(); err != nil && w.err == nil {
+		w.err = err
 	}
-	if err != nil {
-		return size, fmt.Errorf("render site map (%d) as XML failed: %s", len(m), err)
+	files := make(map[string]error, len(w.parts))
+	for i, tmp := range w.parts {
+		filename := filepath.Join(w.outputDir, fmt.Sprintf("%s%d.%s", w.basename, i+1, w.extension))
+		if len(w.parts) == 1 {
+			filename = filepath.Join(w.outputDir, fmt.Sprintf("%s.%s", w.basename, w.extension))
+		}
+		if w.err != nil {
+			os.Remove(tmp)
+			files[filename] = w.err
+			continue
+		}
+		err := os.Rename(tmp, filename)
+		if st, _ := os.Stat(filename); err == nil && st != nil && st.Size() > w.maxFileSizeBytes {
+			if err = replaceWithGzip(filename, filename+".gzip"); err == nil {
+				filename += ".gzip"
+			}
+		}
+		files[filename] = err
 	}
+	return files
+}
 
-	return size, nil
+// Discard - removes all written files.
+func (w *mapWriter) Discard() {
+	w.closeFile()
+	for _, tmp := range w.parts {
+		os.Remove(tmp)
+	}
 }
 
 // saveIndex - generate single map index and saves it in XML format.
@@ -248,6 +401,16 @@ func ensureIndex(
 	if len(mapLinks) <= 1 {
 		return nil
 	}
+	return saveIndex(mapLinks, maxEntriesPerFile, maxFileSizeBytes, basename, outputDir)
+}
+
+// saveIndex - create a set of site map index files with no more than `maxEntriesPerFile` links in each.
+func saveIndex(
+	mapLinks []string,
+	maxEntriesPerFile int,
+	maxFileSizeBytes int64,
+	basename, outputDir string,
+) map[string]error {
 	numFiles, reminder := len(mapLinks)/maxEntriesPerFile, len(mapLinks)%maxEntriesPerFile
 	if reminder > 0 {
 		numFiles++
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/wtask/sitemap/internal/sitemap"
+)
+
+func Test_run(t *testing.T) {
+	server := httptest.NewServer(http.FileServer(http.Dir("../../internal/sitemap/testdata/simplesite")))
+	defer server.Close()
+	dir, err := ioutil.TempDir("", "smgen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	sitemapFile := filepath.Join(dir, "sitemap.xml")
+
+	cases := []struct {
+		name     string
+		args     []string
+		expected int
+		output   string
+	}{
+		{"help", []string{"-help"}, 0, ""},
+		{"crawl help", []string{"crawl", "-h"}, 0, ""},
+		{"no URI", []string{}, 2, ""},
+		{"unknown flag", []string{"-unknown", server.URL + "/homepage.html"}, 2, ""},
+		{
+			"default crawl",
+			[]string{"-depth", "2", "-output-dir", dir, server.URL + "/homepage.html"},
+			0,
+			"All done",
+		},
+		{"crawl", []string{"crawl", "-output-dir", dir, server.URL + "/homepage.html"}, 0, "All done"},
+		{
+			"batch",
+			[]string{
+				"-output-dir", dir, "-max-workers", "2", "-combined-index", "all", "-map-base-url", "http://maps.localhost/",
+				server.URL + "/homepage.html", strings.Replace(server.URL, "127.0.0.1", "localhost", 1) + "/homepage.html",
+			},
+			0,
+			"INDEX OK " + filepath.Join(dir, "all.xml"),
+		},
+		{
+			"crawl with seeds",
+			[]string{"-output-dir", dir, "-depth", "0", "-seeds", server.URL + "/faq.html", server.URL + "/homepage.html"},
+			0,
+			"Completed, num of links found: 2",
+		},
+		{
+			"crawl with disk store",
+			[]string{"-output-dir", dir, "-disk-store", "-checkpoint-interval", "1ms", server.URL + "/homepage.html"},
+			0,
+			"All done",
+		},
+		{"crawl with invalid seed", []string{"-seeds", "http://example.com/", server.URL + "/homepage.html"}, 2, ""},
+		{
+			"crawl with link sources",
+			[]string{"-output-dir", dir, "-link-sources", "a@href, link@href[next|prev]", server.URL + "/homepage.html"},
+			0,
+			"All done",
+		},
+		{
+			"crawl with resource types",
+			[]string{"-output-dir", dir, "-resource-types", "application/pdf, image/*", server.URL + "/homepage.html"},
+			0,
+			"All done",
+		},
+		{"crawl with invalid resource type", []string{"-resource-types", "pdf", server.URL + "/homepage.html"}, 2, ""},
+		{
+			"crawl with limits",
+			[]string{
+				"-output-dir", dir, "-head-probe", "-max-body-bytes", "100000", "-max-document-nodes", "10000",
+				server.URL + "/homepage.html",
+			},
+			0,
+			"All done",
+		},
+		{"crawl with invalid body limit", []string{"-max-body-bytes", "-1", server.URL + "/homepage.html"}, 2, ""},
+		{"crawl with invalid accepted type", []string{"-accepted-types", "html", server.URL + "/homepage.html"}, 2, ""},
+		{"crawl with invalid link source", []string{"-link-sources", "a", server.URL + "/homepage.html"}, 2, ""},
+		{"validate", []string{"validate", sitemapFile}, 0, ""},
+		{"validate missing file", []string{"validate", filepath.Join(dir, "missing.xml")}, 1, ""},
+		{"diff same", []string{"diff", sitemapFile, sitemapFile}, 0, "Added: 0, removed: 0, changed: 0"},
+		{"diff without args", []string{"diff"}, 2, ""},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+			code := run(c.args, stdout, stderr)
+			if code != c.expected {
+				t.Errorf("Unexpected exit code %d, expected %d, stdout:\n%s\nstderr:\n%s", code, c.expected, stdout, stderr)
+			}
+			if !strings.Contains(stdout.String(), c.output) {
+				t.Errorf("Expected output contains %q, got:\n%s", c.output, stdout)
+			}
+		})
+	}
+}
+
+func Test_mapWriter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "smgen-map")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	item := func(i int) sitemap.MapItem {
+		uri, _ := sitemap.NewURI(fmt.Sprintf("http://localhost/%d.html", i))
+		return sitemap.MapItem{URI: uri, DocumentMeta: nil}
+	}
+
+	cases := []struct {
+		name     string
+		num      int
+		maxSize  int64
+		expected []string
+	}{
+		{"single file", 2, 1 << 20, []string{"single.xml"}},
+		{"several files", 5, 1 << 20, []string{"several1.xml", "several2.xml", "several3.xml"}},
+		{"compressed", 2, 10, []string{"compressed.xml.gzip"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			basename := strings.Fields(c.name)[0]
+			w, err := newMapWriter(2, c.maxSize, basename, "xml", dir)
+			if err != nil {
+				t.Fatal("Unexpected newMapWriter() error:", err)
+			}
+			for i := 0; i < c.num; i++ {
+				if err := w.Write(item(i)); err != nil {
+					t.Fatal("Unexpected Write() error:", err)
+				}
+			}
+			actual := []string{}
+			for file, err := range w.Commit() {
+				if err != nil {
+					t.Error("Unexpected error for", file, err)
+				}
+				actual = append(actual, filepath.Base(file))
+			}
+			sort.Strings(actual)
+			if !reflect.DeepEqual(c.expected, actual) {
+				t.Errorf("Expected %v, got %v", c.expected, actual)
+			}
+			matches, _ := filepath.Glob(filepath.Join(dir, "*.tmp"))
+			if len(matches) > 0 {
+				t.Error("Temporary files are not removed:", matches)
+			}
+		})
+	}
+
+	w, _ := newMapWriter(2, 1<<20, "discarded", "xml", dir)
+	w.Write(item(0))
+	w.Discard()
+	if matches, _ := filepath.Glob(filepath.Join(dir, "discarded*")); len(matches) > 0 {
+		t.Error("Files are not removed on Discard():", matches)
+	}
+	if _, err := newMapWriter(2, 1<<20, "map", "txt", dir); err == nil {
+		t.Error("Expected error for unsupported format")
+	}
+}
+
+func Test_mapLink(t *testing.T) {
+	cases := []struct {
+		base     string
+		rel      string
+		expected string
+	}{
+		{"https://example.com", "sitemap.xml", "https://example.com/sitemap.xml"},
+		{"https://example.com/maps", "sitemap1.xml", "https://example.com/maps/sitemap1.xml"},
+		{"https://example.com/maps/?a=1#top", filepath.Join("site", "sitemap1.xml"), "https://example.com/maps/site/sitemap1.xml"},
+		{"http://example.com/", "localhost_8080_blog/sitemap.xml", "http://example.com/localhost_8080_blog/sitemap.xml"},
+		{"http://example.com/", "a b:c.xml", "http://example.com/a%20b:c.xml"},
+	}
+	for _, c := range cases {
+		base, err := parseBaseURL(c.base)
+		if err != nil {
+			t.Fatal("Unexpected parseBaseURL() error:", err)
+		}
+		if actual := mapLink(base, c.rel); actual != c.expected {
+			t.Errorf("Expected %q for %q and %q, got %q", c.expected, c.base, c.rel, actual)
+		}
+	}
+	for _, invalid := range []string{"", "/maps/", "ftp://example.com/", "example.com/maps"} {
+		if _, err := parseBaseURL(invalid); err == nil {
+			t.Errorf("Expected error for %q", invalid)
+		}
+	}
+}
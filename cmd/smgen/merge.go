@@ -0,0 +1,127 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/wtask/sitemap/internal/sitemap/validate"
+)
+
+// mergeCommand - saves site map index which refers to existing site map files.
+// Files are referred by URL built from base URL and path of file relative to output directory.
+// Returns exit code: 0 if index is saved, 1 if some file is not a valid site map or index can not be saved,
+// 2 on usage error.
+func mergeCommand(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("merge", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, `Combine existing site map files (plain or gzipped) into site map index,
+every file is referred by base URL and its path relative to output directory:
+
+	smgen merge [options] FILE...
+
+Options:
+
+`)
+		flags.PrintDefaults()
+		fmt.Fprint(stderr, "\n")
+	}
+	cwd, _ := os.Getwd()
+	baseURL := flags.String("base-url", "", "URL where output directory is published, like \"https://example.com/maps/\" (required).")
+	outputDir := flags.String("output-dir", cwd, "Output directory where index will be generated, every FILE must be inside it.")
+	indexFilename := flags.String("index-name", "sitemap_index", "Base name for site map INDEX.")
+	limitIndexEntries := flags.Int("index-limit", 50000, "Limit number of entries per index file.")
+	limitFileSizeBytes := flags.Int64(
+		"size-limit",
+		50000*1024*1024,
+		"Maximum size of any generated file in bytes. If file size is greater than limitation, file is compressed into gzip.",
+	)
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+	fail := func(format string, args ...interface{}) int {
+		fmt.Fprintf(stderr, format, args...)
+		flags.Usage()
+		return 2
+	}
+	if flags.NArg() == 0 {
+		return fail("Error: at least one FILE is required.\n\n")
+	}
+	if *baseURL == "" {
+		return fail("Error: base URL is required.\n\n")
+	}
+	base, err := parseBaseURL(*baseURL)
+	if err != nil {
+		return fail("Error: %s.\n\n", err)
+	}
+	if *limitIndexEntries < 1 {
+		return fail("Error: invalid index entries limitation (%d).\n\n", *limitIndexEntries)
+	}
+	if *limitFileSizeBytes <= 0 {
+		return fail("Error: invalid file size limitation (%d).\n\n", *limitFileSizeBytes)
+	}
+	dir, err := filepath.Abs(*outputDir)
+	if err != nil {
+		return fail("Error: unable to check output directory: %s.\n\n", err)
+	}
+	if stat, err := os.Stat(dir); err != nil || !stat.IsDir() {
+		return fail("Error: can not use output directory: %s.\n\n", *outputDir)
+	}
+
+	code := 0
+	links := []string{}
+	for _, filename := range flags.Args() {
+		abs, err := filepath.Abs(filename)
+		if err != nil {
+			fmt.Fprintf(stdout, "%s: ERR %s\n", filename, err)
+			code = 1
+			continue
+		}
+		rel, err := filepath.Rel(dir, abs)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			fmt.Fprintf(stdout, "%s: ERR file is outside of output directory %s\n", filename, *outputDir)
+			code = 1
+			continue
+		}
+		result, err := validate.File(filename)
+		if err != nil {
+			fmt.Fprintf(stdout, "%s: ERR %s\n", filename, err)
+			code = 1
+			continue
+		}
+		if result.Kind != validate.KindMap {
+			fmt.Fprintf(stdout, "%s: ERR not a site map\n", filename)
+			code = 1
+			continue
+		}
+		if !result.Valid() {
+			fmt.Fprintf(stdout, "%s: INVALID, violations: %d, see smgen validate\n", filename, len(result.Violations))
+			code = 1
+			continue
+		}
+		link := mapLink(base, rel)
+		fmt.Fprintf(stdout, "%s: OK, %s\n", filename, link)
+		links = append(links, link)
+	}
+	if code != 0 {
+		fmt.Fprintln(stdout, "Index is not saved")
+		return code
+	}
+
+	for file, err := range saveIndex(links, *limitIndexEntries, *limitFileSizeBytes, *indexFilename, dir) {
+		if err != nil {
+			code = 1
+			fmt.Fprintln(stdout, "INDEX", "ERR", file, err)
+		} else {
+			fmt.Fprintln(stdout, "INDEX", "OK", file)
+		}
+	}
+	return code
+}
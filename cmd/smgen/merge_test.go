@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/wtask/sitemap/internal/compression"
+)
+
+func Test_run_merge(t *testing.T) {
+	dir, err := ioutil.TempDir("", "smgen-merge")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	maps := filepath.Join(dir, "maps")
+	if err := os.MkdirAll(filepath.Join(maps, "blog"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"maps/sitemap.xml": `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">` +
+			`<url><loc>http://localhost/</loc></url></urlset>`,
+		"maps/blog/sitemap.xml": `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">` +
+			`<url><loc>http://localhost/blog/</loc></url></urlset>`,
+		"maps/index.xml": `<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">` +
+			`<sitemap><loc>http://localhost/sitemap.xml</loc></sitemap></sitemapindex>`,
+		"maps/invalid.xml": `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"><url></url></urlset>`,
+		"outside.xml": `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">` +
+			`<url><loc>http://localhost/</loc></url></urlset>`,
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	gz := filepath.Join(maps, "blog", "sitemap.xml.gz")
+	if err := compression.GzipFile(filepath.Join(maps, "blog", "sitemap.xml"), gz); err != nil {
+		t.Fatal(err)
+	}
+	file := func(name string) string {
+		return filepath.Join(dir, filepath.FromSlash(name))
+	}
+
+	cases := []struct {
+		name     string
+		args     []string
+		expected int
+		output   []string
+		index    map[string][]string // index file -> expected links
+	}{
+		{"help", []string{"merge", "-h"}, 0, nil, nil},
+		{"no files", []string{"merge", "-base-url", "https://example.com/"}, 2, nil, nil},
+		{"no base URL", []string{"merge", "-output-dir", maps, file("maps/sitemap.xml")}, 2, nil, nil},
+		{"invalid base URL", []string{"merge", "-base-url", "/maps/", file("maps/sitemap.xml")}, 2, nil, nil},
+		{
+			"maps",
+			[]string{
+				"merge", "-base-url", "https://example.com/maps", "-output-dir", maps,
+				file("maps/sitemap.xml"), file("maps/blog/sitemap.xml"), gz,
+			},
+			0,
+			[]string{"INDEX OK " + file("maps/sitemap_index.xml")},
+			map[string][]string{
+				"maps/sitemap_index.xml": {
+					"https://example.com/maps/sitemap.xml",
+					"https://example.com/maps/blog/sitemap.xml",
+					"https://example.com/maps/blog/sitemap.xml.gz",
+				},
+			},
+		},
+		{
+			"several indexes",
+			[]string{
+				"merge", "-base-url", "https://example.com/", "-output-dir", maps, "-index-name", "all", "-index-limit", "1",
+				file("maps/sitemap.xml"), file("maps/blog/sitemap.xml"),
+			},
+			0,
+			[]string{"INDEX OK " + file("maps/all1.xml"), "INDEX OK " + file("maps/all2.xml")},
+			map[string][]string{
+				"maps/all1.xml": {"https://example.com/sitemap.xml"},
+				"maps/all2.xml": {"https://example.com/blog/sitemap.xml"},
+			},
+		},
+		{
+			"outside of output directory",
+			[]string{"merge", "-base-url", "https://example.com/", "-output-dir", maps, file("outside.xml")},
+			1,
+			[]string{"outside.xml: ERR file is outside of output directory", "Index is not saved"},
+			nil,
+		},
+		{
+			"index",
+			[]string{"merge", "-base-url", "https://example.com/", "-output-dir", maps, file("maps/index.xml")},
+			1,
+			[]string{"index.xml: ERR not a site map"},
+			nil,
+		},
+		{
+			"invalid map",
+			[]string{"merge", "-base-url", "https://example.com/", "-output-dir", maps, file("maps/invalid.xml")},
+			1,
+			[]string{"invalid.xml: INVALID"},
+			nil,
+		},
+		{
+			"missing map",
+			[]string{"merge", "-base-url", "https://example.com/", "-output-dir", maps, file("maps/missing.xml")},
+			1,
+			[]string{"missing.xml: ERR"},
+			nil,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+			if code := run(c.args, stdout, stderr); code != c.expected {
+				t.Errorf("Unexpected exit code %d, expected %d, stdout:\n%s\nstderr:\n%s", code, c.expected, stdout, stderr)
+			}
+			for _, line := range c.output {
+				if !strings.Contains(stdout.String(), line) {
+					t.Errorf("Expected output contains %q, got:\n%s", line, stdout)
+				}
+			}
+			for name, links := range c.index {
+				data, err := ioutil.ReadFile(file(name))
+				if err != nil {
+					t.Fatal("Index is not saved:", err)
+				}
+				if actual := locs(string(data)); strings.Join(actual, " ") != strings.Join(links, " ") {
+					t.Errorf("Expected %s refers to %v, got %v", name, links, actual)
+				}
+			}
+		})
+	}
+}
+
+// locs - returns values of `loc` elements of site map or index.
+func locs(content string) []string {
+	result := []string{}
+	for _, part := range strings.Split(content, "<loc>")[1:] {
+		result = append(result, strings.TrimSpace(part[:strings.Index(part, "</loc>")]))
+	}
+	return result
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wtask/sitemap/internal/sitemap"
+)
+
+// fetchDurationBuckets - upper bounds of fetch latency histogram in seconds.
+var fetchDurationBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// metrics - collects crawl metrics and exposes them in Prometheus text format.
+type metrics struct {
+	parser *sitemap.Parser
+
+	mx           sync.Mutex
+	statusCodes  map[string]int64 // by status code, "0" - response is not received
+	contentTypes map[string]int64 // by media type
+	buckets      []int64          // fetch latency histogram, cumulative counters are calculated on output
+	durationSum  float64
+	durationNum  int64
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		statusCodes:  map[string]int64{},
+		contentTypes: map[string]int64{},
+		buckets:      make([]int64, len(fetchDurationBuckets)),
+	}
+}
+
+// hooks - returns parser hooks to collect metrics.
+func (m *metrics) hooks() sitemap.Hooks {
+	return sitemap.Hooks{OnFetch: m.observeFetch}
+}
+
+// observeFetch - counts completed request.
+func (m *metrics) observeFetch(e sitemap.FetchEvent) {
+	ctype := "none"
+	if e.ContentType != "" {
+		if media, _, err := mime.ParseMediaType(e.ContentType); err == nil {
+			ctype = media
+		} else {
+			ctype = "invalid"
+		}
+	}
+	seconds := e.Duration.Seconds()
+	m.mx.Lock()
+	defer m.mx.Unlock()
+	m.statusCodes[strconv.Itoa(e.StatusCode)]++
+	m.contentTypes[ctype]++
+	for i, bound := range fetchDurationBuckets {
+		if seconds <= bound {
+			m.buckets[i]++
+			break
+		}
+	}
+	m.durationSum += seconds
+	m.durationNum++
+}
+
+// metricsHandler - returns handler which serves metrics at "/metrics" path.
+func metricsHandler(m *metrics) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", m)
+	return mux
+}
+
+// ServeHTTP - writes metrics in Prometheus text exposition format.
+func (m *metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.write(w)
+}
+
+// write - writes all metrics in Prometheus text exposition format.
+func (m *metrics) write(w io.Writer) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	writeLabeled(w, "smgen_fetch_status_total", "Num of document requests by response status code.",
+		"counter", "code", m.statusCodes)
+	writeLabeled(w, "smgen_fetch_content_type_total", "Num of document requests by response content type.",
+		"counter", "content_type", m.contentTypes)
+
+	fmt.Fprint(w, "# HELP smgen_fetch_duration_seconds Latency of document requests.\n")
+	fmt.Fprint(w, "# TYPE smgen_fetch_duration_seconds histogram\n")
+	cumulative := int64(0)
+	for i, bound := range fetchDurationBuckets {
+		cumulative += m.buckets[i]
+		fmt.Fprintf(w, "smgen_fetch_duration_seconds_bucket{le=%q} %d\n", formatFloat(bound), cumulative)
+	}
+	fmt.Fprintf(w, "smgen_fetch_duration_seconds_bucket{le=\"+Inf\"} %d\n", m.durationNum)
+	fmt.Fprintf(w, "smgen_fetch_duration_seconds_sum %s\n", formatFloat(m.durationSum))
+	fmt.Fprintf(w, "smgen_fetch_duration_seconds_count %d\n", m.durationNum)
+
+	if m.parser == nil {
+		return
+	}
+	stats := m.parser.Stats()
+	errors := map[string]int64{}
+	for class, num := range stats.Errors {
+		errors[string(class)] = num
+	}
+	writeLabeled(w, "smgen_errors_total", "Num of crawl errors by class.", "counter", "class", errors)
+	writeSingle(w, "smgen_fetched_total", "Num of successfully fetched documents.", "counter", float64(stats.Fetched))
+	writeSingle(w, "smgen_downloaded_bytes_total", "Num of downloaded bytes of documents.", "counter", float64(stats.Bytes))
+	writeSingle(w, "smgen_queue_length", "Num of targets waiting to be processed.", "gauge", float64(stats.Queued))
+	writeSingle(w, "smgen_workers_busy", "Num of running workers.", "gauge", float64(stats.InFlight))
+	writeSingle(w, "smgen_workers_total", "Max num of concurrent workers.", "gauge", float64(stats.Workers))
+	utilization := 0.0
+	if stats.Workers > 0 {
+		utilization = float64(stats.InFlight) / float64(stats.Workers)
+	}
+	writeSingle(w, "smgen_worker_utilization", "Ratio of running workers to max num of workers.", "gauge", utilization)
+	writeSingle(w, "smgen_max_depth", "Maximum level of processed targets.", "gauge", float64(stats.MaxDepth))
+}
+
+// writeSingle - writes metric without labels.
+func writeSingle(w io.Writer, name, help, kind string, value float64) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %s\n", name, help, name, kind, name, formatFloat(value))
+}
+
+// writeLabeled - writes metric with single label, values are sorted by label.
+func writeLabeled(w io.Writer, name, help, kind, label string, values map[string]int64) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(w, "%s{%s=%s} %d\n", name, label, quoteLabel(k), values[k])
+	}
+}
+
+// quoteLabel - quotes label value as required by Prometheus text format.
+func quoteLabel(value string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	return `"` + r.Replace(value) + `"`
+}
+
+// formatFloat - formats number in the shortest representation.
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// lingerMetrics - waits given time after crawl, so final metrics can be scraped, or until context is done.
+func lingerMetrics(ctx context.Context, d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wtask/sitemap/internal/sitemap"
+)
+
+func Test_metrics_write(t *testing.T) {
+	m := newMetrics()
+	for _, e := range []sitemap.FetchEvent{
+		{StatusCode: 200, ContentType: "text/html; charset=utf-8", Duration: 31250 * time.Microsecond},
+		{StatusCode: 200, ContentType: "text/html", Duration: 250 * time.Millisecond},
+		{StatusCode: 404, ContentType: "text/html; charset", Duration: 1500 * time.Millisecond},
+		{StatusCode: 0, ContentType: "", Duration: 40 * time.Second},
+	} {
+		m.observeFetch(e)
+	}
+	expected := `# HELP smgen_fetch_status_total Num of document requests by response status code.
+# TYPE smgen_fetch_status_total counter
+smgen_fetch_status_total{code="0"} 1
+smgen_fetch_status_total{code="200"} 2
+smgen_fetch_status_total{code="404"} 1
+# HELP smgen_fetch_content_type_total Num of document requests by response content type.
+# TYPE smgen_fetch_content_type_total counter
+smgen_fetch_content_type_total{content_type="invalid"} 1
+smgen_fetch_content_type_total{content_type="none"} 1
+smgen_fetch_content_type_total{content_type="text/html"} 2
+# HELP smgen_fetch_duration_seconds Latency of document requests.
+# TYPE smgen_fetch_duration_seconds histogram
+smgen_fetch_duration_seconds_bucket{le="0.05"} 1
+smgen_fetch_duration_seconds_bucket{le="0.1"} 1
+smgen_fetch_duration_seconds_bucket{le="0.25"} 2
+smgen_fetch_duration_seconds_bucket{le="0.5"} 2
+smgen_fetch_duration_seconds_bucket{le="1"} 2
+smgen_fetch_duration_seconds_bucket{le="2.5"} 3
+smgen_fetch_duration_seconds_bucket{le="5"} 3
+smgen_fetch_duration_seconds_bucket{le="10"} 3
+smgen_fetch_duration_seconds_bucket{le="30"} 3
+smgen_fetch_duration_seconds_bucket{le="+Inf"} 4
+smgen_fetch_duration_seconds_sum 41.78125
+smgen_fetch_duration_seconds_count 4
+`
+	actual := &bytes.Buffer{}
+	m.write(actual)
+	if actual.String() != expected {
+		t.Errorf("Expected:\n%s\nactual:\n%s", expected, actual)
+	}
+}
+
+func Test_writeLabeled(t *testing.T) {
+	actual := &bytes.Buffer{}
+	writeLabeled(actual, "smgen_test_total", "Test metric.", "counter", "path", map[string]int64{
+		"/b":                 2,
+		`/a"quoted"\slashed`: 1,
+		"/multi\nline":       3,
+		"":                   4,
+	})
+	expected := `# HELP smgen_test_total Test metric.
+# TYPE smgen_test_total counter
+smgen_test_total{path=""} 4
+smgen_test_total{path="/a\"quoted\"\\slashed"} 1
+smgen_test_total{path="/b"} 2
+smgen_test_total{path="/multi\nline"} 3
+`
+	if actual.String() != expected {
+		t.Errorf("Expected:\n%s\nactual:\n%s", expected, actual)
+	}
+}
+
+func Test_metricsHandler(t *testing.T) {
+	site := httptest.NewServer(http.FileServer(http.Dir("../../internal/sitemap/testdata/simplesite")))
+	defer site.Close()
+
+	m := newMetrics()
+	parser, err := sitemap.NewParser(sitemap.WithHooks(m.hooks()))
+	if err != nil {
+		t.Fatal("Unexpected NewParser() error:", err)
+	}
+	m.parser = parser
+	root, _ := sitemap.NewURI(site.URL + "/homepage.html")
+	parser.Parse(root, 1, 2)
+
+	server := httptest.NewServer(metricsHandler(m))
+	defer server.Close()
+	resp, err := http.Get(server.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatal("Unexpected status:", resp.StatusCode)
+	}
+	if ctype := resp.Header.Get("Content-Type"); ctype != "text/plain; version=0.0.4; charset=utf-8" {
+		t.Error("Unexpected content type:", ctype)
+	}
+	for _, line := range []string{
+		`smgen_fetch_status_total{code="200"} 4`,
+		`smgen_fetch_duration_seconds_count 4`,
+		"# TYPE smgen_errors_total counter",
+		"smgen_fetched_total 4",
+		"smgen_queue_length 0",
+		"smgen_workers_busy 0",
+		"smgen_workers_total 2",
+		"smgen_worker_utilization 0",
+		"smgen_max_depth 1",
+	} {
+		if !strings.Contains(string(body), line+"\n") {
+			t.Errorf("Expected line %q in:\n%s", line, body)
+		}
+	}
+
+	resp, err = http.Get(server.URL + "/other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Error("Unexpected status of unknown path:", resp.StatusCode)
+	}
+}
+
+func Test_run_metricsLinger(t *testing.T) {
+	site := httptest.NewServer(http.FileServer(http.Dir("../../internal/sitemap/testdata/simplesite")))
+	defer site.Close()
+	dir, err := ioutil.TempDir("", "smgen-metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	buf := &bytes.Buffer{}
+	stdout := &syncWriter{w: buf}
+	output := func() string {
+		stdout.mx.Lock()
+		defer stdout.mx.Unlock()
+		return buf.String()
+	}
+	linger := time.Second
+	started := time.Now()
+	done := make(chan int)
+	go func() {
+		args := []string{
+			"-output-dir", dir,
+			"-metrics-addr", addr,
+			"-metrics-linger", linger.String(),
+			site.URL + "/homepage.html",
+		}
+		done <- run(args, stdout, ioutil.Discard)
+	}()
+	for !strings.Contains(output(), "Metrics are served for") {
+		select {
+		case code := <-done:
+			t.Fatalf("Crawl is finished without waiting, code %d, output:\n%s", code, output())
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	resp, err := http.Get("http://" + addr + "/metrics")
+	if err != nil {
+		t.Fatal("Metrics are not served after crawl:", err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(body), "smgen_fetched_total 4\n") {
+		t.Errorf("Expected final metrics, got:\n%s", body)
+	}
+	if code := <-done; code != 0 {
+		t.Fatalf("Unexpected exit code %d, output:\n%s", code, output())
+	}
+	if elapsed := time.Since(started); elapsed < linger {
+		t.Error("Metrics are served less than expected:", elapsed)
+	}
+	if _, err := http.Get("http://" + addr + "/metrics"); err == nil {
+		t.Error("Expected metrics server is closed")
+	}
+}
+
+func Test_lingerMetrics(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	started := time.Now()
+	lingerMetrics(ctx, time.Hour)
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Error("Waiting is not stopped by context:", elapsed)
+	}
+}
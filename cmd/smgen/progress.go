@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wtask/sitemap/internal/sitemap"
+)
+
+const (
+	// progressInterval - how often crawl progress is logged.
+	progressInterval = 5 * time.Second
+	// ttyProgressInterval - how often progress line is refreshed in terminal.
+	ttyProgressInterval = 500 * time.Millisecond
+)
+
+// reportProgress - periodically prints crawl statistics until `done` is closed.
+// If `out` is a terminal line, single progress line is refreshed in place, otherwise statistics are logged.
+func reportProgress(parser *sitemap.Parser, l logger, out io.Writer, done <-chan struct{}) {
+	term, _ := out.(*terminalLine)
+	interval := progressInterval
+	if term != nil {
+		interval = ttyProgressInterval
+	}
+	printProgress(parser.Stats, l, term, interval, done)
+}
+
+// printProgress - prints statistics returned by `stats` every `interval` and once more when `done` is closed.
+// Statistics are shown as progress line of `term` if it is not nil, otherwise they are logged.
+func printProgress(
+	stats func() sitemap.Stats,
+	l logger,
+	term *terminalLine,
+	interval time.Duration,
+	done <-chan struct{},
+) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			if term != nil {
+				term.finish(formatStats(stats()))
+			} else {
+				l.Println("PROGRESS", formatStats(stats()))
+			}
+			return
+		case <-ticker.C:
+			if term != nil {
+				term.show(formatStats(stats()))
+			} else {
+				l.Println("PROGRESS", formatStats(stats()))
+			}
+		}
+	}
+}
+
+// terminalLine - terminal output which keeps progress line below log lines,
+// progress line is erased before every write of log and is printed again after it.
+type terminalLine struct {
+	mx   sync.Mutex
+	out  io.Writer
+	line string // current progress line, empty if it is not shown
+}
+
+// Write - implements io.Writer for logger, every write is expected to end with newline.
+func (t *terminalLine) Write(p []byte) (int, error) {
+	t.mx.Lock()
+	defer t.mx.Unlock()
+	if t.line != "" {
+		fmt.Fprintf(t.out, "\r%s\r", strings.Repeat(" ", len(t.line)+4))
+	}
+	n, err := t.out.Write(p)
+	if t.line != "" {
+		fmt.Fprintf(t.out, "%s    ", t.line)
+	}
+	return n, err
+}
+
+// show - replaces progress line, trailing spaces erase the rest of previous line.
+func (t *terminalLine) show(line string) {
+	t.mx.Lock()
+	defer t.mx.Unlock()
+	fmt.Fprintf(t.out, "\r%s    ", line)
+	t.line = line
+}
+
+// finish - prints final progress line, the following output starts from new line.
+func (t *terminalLine) finish(line string) {
+	t.mx.Lock()
+	defer t.mx.Unlock()
+	fmt.Fprintf(t.out, "\r%s\n", line)
+	t.line = ""
+}
+
+// isTerminal - checks writer is a file of character device.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	stat, err := f.Stat()
+	return err == nil && stat.Mode()&os.ModeCharDevice != 0
+}
+
+// formatStats - returns human-readable statistics in single line.
+func formatStats(s sitemap.Stats) string {
+	numErrors := int64(0)
+	classes := []string{}
+	for class, num := range s.Errors {
+		numErrors += num
+		classes = append(classes, fmt.Sprintf("%s: %d", class, num))
+	}
+	sort.Strings(classes)
+	errors := fmt.Sprint(numErrors)
+	if len(classes) > 0 {
+		errors += " (" + strings.Join(classes, ", ") + ")"
+	}
+	return fmt.Sprintf(
+		"fetched: %d, queued: %d, in-flight: %d, errors: %s, downloaded: %s, %.1f pages/s, depth: %d, elapsed: %s",
+		s.Fetched,
+		s.Queued,
+		s.InFlight,
+		errors,
+		formatBytes(s.Bytes),
+		s.PagesPerSecond,
+		s.MaxDepth,
+		s.Elapsed.Round(time.Second),
+	)
+}
+
+// formatBytes - returns size in human-readable units.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for i := n / unit; i >= unit; i /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/wtask/sitemap/internal/sitemap"
+)
+
+func Test_formatStats(t *testing.T) {
+	cases := []struct {
+		stats    sitemap.Stats
+		expected string
+	}{
+		{
+			sitemap.Stats{Errors: map[sitemap.ErrorClass]int64{}},
+			"fetched: 0, queued: 0, in-flight: 0, errors: 0, downloaded: 0 B, 0.0 pages/s, depth: 0, elapsed: 0s",
+		},
+		{
+			sitemap.Stats{
+				Elapsed:        90*time.Second + 400*time.Millisecond,
+				Fetched:        120,
+				Queued:         35,
+				InFlight:       4,
+				Errors:         map[sitemap.ErrorClass]int64{sitemap.ErrorStatus: 3, sitemap.ErrorRequest: 1},
+				Bytes:          3 * 1024 * 1024,
+				PagesPerSecond: 1.33,
+				MaxDepth:       2,
+			},
+			"fetched: 120, queued: 35, in-flight: 4, errors: 4 (request: 1, status: 3), " +
+				"downloaded: 3.0 MiB, 1.3 pages/s, depth: 2, elapsed: 1m30s",
+		},
+	}
+	for _, c := range cases {
+		if actual := formatStats(c.stats); actual != c.expected {
+			t.Errorf("Expected %q, got %q", c.expected, actual)
+		}
+	}
+}
+
+func Test_formatBytes(t *testing.T) {
+	cases := []struct {
+		n        int64
+		expected string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1024, "1.0 KiB"},
+		{1536, "1.5 KiB"},
+		{5 * 1024 * 1024 * 1024, "5.0 GiB"},
+	}
+	for _, c := range cases {
+		if actual := formatBytes(c.n); actual != c.expected {
+			t.Errorf("Expected %q for %d, got %q", c.expected, c.n, actual)
+		}
+	}
+}
+
+func Test_printProgress(t *testing.T) {
+	cases := []struct {
+		name string
+		tty  bool
+	}{
+		{"log", false},
+		{"terminal", true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			fetched := int64(0)
+			stats := func() sitemap.Stats {
+				return sitemap.Stats{Fetched: atomic.AddInt64(&fetched, 1), Errors: map[sitemap.ErrorClass]int64{}}
+			}
+			logged, out := &bytes.Buffer{}, &bytes.Buffer{}
+			done, stopped := make(chan struct{}), make(chan struct{})
+			go func() {
+				defer close(stopped)
+				var term *terminalLine
+				if c.tty {
+					term = &terminalLine{out: out}
+				}
+				printProgress(stats, log.New(logged, "", 0), term, 10*time.Millisecond, done)
+			}()
+			for atomic.LoadInt64(&fetched) < 3 {
+				time.Sleep(time.Millisecond)
+			}
+			close(done)
+			select {
+			case <-stopped:
+			case <-time.After(5 * time.Second):
+				t.Fatal("Progress is not stopped when done is closed")
+			}
+			num := atomic.LoadInt64(&fetched)
+			time.Sleep(30 * time.Millisecond)
+			if atomic.LoadInt64(&fetched) != num {
+				t.Error("Progress is printed after done is closed")
+			}
+
+			var lines []string
+			if c.tty {
+				if logged.Len() > 0 {
+					t.Error("Unexpected log output for terminal:", logged)
+				}
+				if !strings.HasSuffix(out.String(), "\n") {
+					t.Errorf("Expected final progress line ends with newline, got %q", out)
+				}
+				lines = strings.Split(strings.TrimPrefix(strings.TrimSuffix(out.String(), "\n"), "\r"), "\r")
+				for i, line := range lines[:len(lines)-1] {
+					if !strings.HasSuffix(line, "    ") {
+						t.Errorf("Expected progress line #%d is padded to erase previous one, got %q", i, line)
+					}
+				}
+			} else {
+				if out.Len() > 0 {
+					t.Error("Unexpected terminal output for log:", out)
+				}
+				lines = strings.Split(strings.TrimSuffix(logged.String(), "\n"), "\n")
+				for i, line := range lines {
+					if !strings.HasPrefix(line, "PROGRESS fetched: ") {
+						t.Errorf("Unexpected progress line #%d: %q", i, line)
+					}
+				}
+			}
+			if int64(len(lines)) != num {
+				t.Errorf("Expected %d progress lines, got %d", num, len(lines))
+			}
+			if last := lines[len(lines)-1]; !strings.HasPrefix(strings.TrimPrefix(last, "PROGRESS "), fmt.Sprintf("fetched: %d,", num)) {
+				t.Errorf("Expected final statistics are printed, got %q", last)
+			}
+		})
+	}
+}
+
+func Test_terminalLine(t *testing.T) {
+	out := &bytes.Buffer{}
+	term := &terminalLine{out: out}
+	l := log.New(term, "", 0)
+	l.Println("started")
+	term.show("fetched: 1")
+	l.Println("log")
+	term.show("fetched: 2")
+	term.finish("fetched: 3")
+	l.Println("done")
+	expected := "started\n" +
+		"\rfetched: 1    " +
+		"\r              \rlog\nfetched: 1    " +
+		"\rfetched: 2    " +
+		"\rfetched: 3\n" +
+		"done\n"
+	if out.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, out.String())
+	}
+}
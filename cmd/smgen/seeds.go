@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/wtask/sitemap/internal/sitemap"
+)
+
+// stdin - source of seeds when seeds file is "-".
+var stdin io.Reader = os.Stdin
+
+// readSeeds - reads URIs from given reader, one per line; empty lines and lines started with "#" are skipped.
+func readSeeds(r io.Reader) ([]string, error) {
+	seeds := []string{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		seeds = append(seeds, line)
+	}
+	return seeds, scanner.Err()
+}
+
+// seedsSource - reads seeds files, every file is read once, so stdin can be shared by several sites.
+type seedsSource map[string][]string
+
+// read - returns seeds of given file, "-" means stdin.
+func (s seedsSource) read(filename string) ([]string, error) {
+	if seeds, ok := s[filename]; ok {
+		return seeds, nil
+	}
+	var r io.Reader = stdin
+	if filename != "-" {
+		f, err := os.Open(filename)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read seeds: %s", err)
+		}
+		defer f.Close()
+		r = f
+	}
+	seeds, err := readSeeds(r)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read seeds: %s", err)
+	}
+	s[filename] = seeds
+	return seeds, nil
+}
+
+// parseSeeds - converts seeds into URIs, every seed must belong to the same host as start URI.
+func parseSeeds(start *sitemap.URI, seeds []string) ([]*sitemap.URI, error) {
+	uris := []*sitemap.URI{}
+	for _, s := range seeds {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		uri, err := sitemap.NewURI(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid seed %q: %s", s, err)
+		}
+		if uri.Scheme != start.Scheme || uri.Host != start.Host {
+			return nil, fmt.Errorf("seed %q does not belong to %s://%s", s, start.Scheme, start.Host)
+		}
+		uris = append(uris, uri)
+	}
+	return uris, nil
+}
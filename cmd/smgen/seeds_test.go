@@ -0,0 +1,82 @@
+package main
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/wtask/sitemap/internal/sitemap"
+)
+
+func Test_readSeeds(t *testing.T) {
+	seeds, err := readSeeds(strings.NewReader("http://localhost/a.html\n\n  # comment\n  http://localhost/b/  \n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"http://localhost/a.html", "http://localhost/b/"}
+	if !reflect.DeepEqual(seeds, expected) {
+		t.Errorf("Expected %v, got %v", expected, seeds)
+	}
+}
+
+func Test_seedsSource(t *testing.T) {
+	dir, err := ioutil.TempDir("", "smgen-seeds")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "seeds.txt")
+	if err := ioutil.WriteFile(filename, []byte("http://localhost/file.html\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	defer func(r io.Reader) { stdin = r }(stdin)
+	stdin = strings.NewReader("http://localhost/stdin.html\n")
+
+	source := seedsSource{}
+	for i := 0; i < 2; i++ {
+		// stdin is read once and then cached
+		seeds, err := source.read("-")
+		if err != nil || !reflect.DeepEqual(seeds, []string{"http://localhost/stdin.html"}) {
+			t.Errorf("Unexpected seeds from stdin: %v, %v", seeds, err)
+		}
+	}
+	seeds, err := source.read(filename)
+	if err != nil || !reflect.DeepEqual(seeds, []string{"http://localhost/file.html"}) {
+		t.Errorf("Unexpected seeds from file: %v, %v", seeds, err)
+	}
+	if _, err := source.read(filepath.Join(dir, "missing.txt")); err == nil {
+		t.Error("Expected error for missing file")
+	}
+}
+
+func Test_parseSeeds(t *testing.T) {
+	start, _ := sitemap.NewURI("http://localhost/")
+	cases := []struct {
+		seeds    []string
+		expected []string
+		isErr    bool
+	}{
+		{[]string{" http://localhost/a.html", "", "http://localhost/b/"}, []string{"http://localhost/a.html", "http://localhost/b/"}, false},
+		{[]string{"/relative.html"}, nil, true},
+		{[]string{"https://localhost/a.html"}, nil, true},
+		{[]string{"http://example.com/a.html"}, nil, true},
+	}
+	for _, c := range cases {
+		uris, err := parseSeeds(start, c.seeds)
+		if c.isErr != (err != nil) {
+			t.Errorf("Unexpected error for %v: %v", c.seeds, err)
+			continue
+		}
+		actual := []string(nil)
+		for _, u := range uris {
+			actual = append(actual, u.String())
+		}
+		if !reflect.DeepEqual(actual, c.expected) {
+			t.Errorf("Expected %v, got %v", c.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/wtask/sitemap/internal/sitemap/validate"
+)
+
+// validateCommand - checks given site map and index files against the protocol.
+// Returns exit code: 0 if all files are valid, 1 if violations are found or file can not be read, 2 on usage error.
+func validateCommand(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("validate", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, `Validate site map and site map index files (plain or gzipped) against https://www.sitemaps.org/protocol.html:
+
+	smgen validate FILE...
+
+`)
+	}
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+	if flags.NArg() == 0 {
+		fmt.Fprint(stderr, "Error: at least one FILE is required.\n\n")
+		flags.Usage()
+		return 2
+	}
+
+	code := 0
+	for _, filename := range flags.Args() {
+		result, err := validate.File(filename)
+		if err != nil {
+			fmt.Fprintf(stdout, "%s: ERR %s\n", filename, err)
+			code = 1
+			continue
+		}
+		for _, v := range result.Violations {
+			if v.Line > 0 {
+				fmt.Fprintf(stdout, "%s:%d: %s\n", filename, v.Line, v.Message)
+			} else {
+				fmt.Fprintf(stdout, "%s: %s\n", filename, v.Message)
+			}
+		}
+		if result.Valid() {
+			fmt.Fprintf(stdout, "%s: OK, %s, entries: %d\n", filename, result.Kind, result.Entries)
+		} else {
+			fmt.Fprintf(stdout, "%s: INVALID, violations: %d\n", filename, len(result.Violations))
+			code = 1
+		}
+	}
+	return code
+}
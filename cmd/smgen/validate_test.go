@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/wtask/sitemap/internal/compression"
+)
+
+func Test_run_validate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "smgen-validate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	valid := filepath.Join(dir, "sitemap.xml")
+	content := `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+	<url><loc>http://localhost/</loc><lastmod>2019-05-21</lastmod></url>
+	<url><loc>http://localhost/faq.html</loc></url>
+</urlset>
+`
+	if err := ioutil.WriteFile(valid, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	gz := filepath.Join(dir, "sitemap.xml.gz")
+	if err := compression.GzipFile(valid, gz); err != nil {
+		t.Fatal(err)
+	}
+	invalid := filepath.Join(dir, "invalid.xml")
+	content = `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+	<url><loc>/relative.html</loc></url>
+</urlset>
+`
+	if err := ioutil.WriteFile(invalid, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name     string
+		args     []string
+		expected int
+		output   []string
+	}{
+		{"help", []string{"validate", "-h"}, 0, nil},
+		{"no files", []string{"validate"}, 2, nil},
+		{"valid", []string{"validate", valid}, 0, []string{valid + ": OK, urlset, entries: 2"}},
+		{"gzipped", []string{"validate", gz}, 0, []string{gz + ": OK, urlset, entries: 2"}},
+		{"invalid", []string{"validate", invalid}, 1, []string{invalid + ":2: ", invalid + ": INVALID, violations: 1"}},
+		{
+			"valid and invalid",
+			[]string{"validate", valid, invalid},
+			1,
+			[]string{valid + ": OK", invalid + ": INVALID"},
+		},
+		{"missing", []string{"validate", filepath.Join(dir, "missing.xml")}, 1, []string{"missing.xml: ERR "}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+			if code := run(c.args, stdout, stderr); code != c.expected {
+				t.Errorf("Unexpected exit code %d, expected %d, stdout:\n%s\nstderr:\n%s", code, c.expected, stdout, stderr)
+			}
+			for _, line := range c.output {
+				if !strings.Contains(stdout.String(), line) {
+					t.Errorf("Expected output contains %q, got:\n%s", line, stdout)
+				}
+			}
+		})
+	}
+}
@@ -3,6 +3,8 @@ module github.com/wtask/sitemap
 go 1.12
 
 require (
+	github.com/BurntSushi/toml v0.3.1
 	golang.org/x/net v0.0.0-20190514140710-3ec191127204
 	golang.org/x/text v0.3.2 // indirect
+	gopkg.in/yaml.v3 v3.0.1
 )
@@ -0,0 +1,202 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/wtask/sitemap/internal/sitemap"
+)
+
+// Issue - kind of problem with URL listed in site map.
+type Issue string
+
+const (
+	// IssueBroken - URL can not be fetched due to network error or unexpected status code
+	IssueBroken Issue = "broken"
+	// IssueNoIndex - document forbids indexing with robots meta tag or X-Robots-Tag header
+	IssueNoIndex Issue = "noindex"
+	// IssueUnreachable - document is available, but it is not linked from pages reachable from start URI
+	IssueUnreachable Issue = "unreachable"
+)
+
+// Finding - URL listed in site map with a problem.
+type Finding struct {
+	URI        string `json:"uri"`
+	Issue      Issue  `json:"issue"`
+	StatusCode int    `json:"status_code,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Result - outcome of audit.
+type Result struct {
+	// Published - num of unique URLs listed in site map
+	Published int `json:"published"`
+	// Reachable - num of indexable documents reachable from start URI
+	Reachable int `json:"reachable"`
+	// Issues - URLs listed in site map with problems, sorted by URI
+	Issues []Finding `json:"sitemap_issues"`
+	// Missing - indexable documents reachable from start URI, but not listed in site map, sorted
+	Missing []string `json:"missing_from_sitemap"`
+}
+
+// OK - reports neither issues nor missing documents are found.
+func (r *Result) OK() bool {
+	return len(r.Issues) == 0 && len(r.Missing) == 0
+}
+
+// WriteText - writes result as plain text, one finding per line.
+func (r *Result) WriteText(w io.Writer) error {
+	if _, err := fmt.Fprintf(w, "Published URLs: %d, reachable pages: %d\n", r.Published, r.Reachable); err != nil {
+		return err
+	}
+	for _, f := range r.Issues {
+		line := fmt.Sprintf("SITEMAP %s %s", f.Issue, f.URI)
+		if f.StatusCode != 0 {
+			line += fmt.Sprintf(" (%d)", f.StatusCode)
+		}
+		if f.Error != "" {
+			line += " " + f.Error
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	for _, uri := range r.Missing {
+		if _, err := fmt.Fprintln(w, "MISSING", uri); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteJSON - writes result in JSON format.
+func (r *Result) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// Run - crawls site starting from `root` and checks URLs of `published` site map against crawl results.
+// Published URLs, which are not discovered by crawl, are fetched separately to find out whether they are available,
+// they are fetched concurrently with the same num of workers as crawl.
+// Given options are used to build parser.
+func Run(
+	ctx context.Context,
+	published []sitemap.MapItem,
+	root *sitemap.URI,
+	depth, workers uint,
+	options ...sitemap.ParserOption,
+) (*Result, error) {
+	mx := sync.Mutex{}
+	fetches := map[string]sitemap.FetchEvent{}
+	options = append(options, sitemap.WithHooks(sitemap.Hooks{OnFetch: func(e sitemap.FetchEvent) {
+		mx.Lock()
+		defer mx.Unlock()
+		fetches[e.URI] = e
+	}}))
+	parser, err := sitemap.NewParser(options...)
+	if err != nil {
+		return nil, fmt.Errorf("audit.Run: %s", err)
+	}
+
+	crawled := map[string]*sitemap.DocumentMeta{}
+	for item := range parser.Stream(ctx, root, depth, workers) {
+		crawled[item.URI.String()] = item.DocumentMeta
+	}
+	probed := map[string]*sitemap.DocumentMeta{}
+	unprobed := []*sitemap.URI{}
+	for _, item := range published {
+		uri := item.URI.String()
+		if _, ok := crawled[uri]; ok {
+			continue
+		}
+		if _, ok := probed[uri]; ok {
+			continue
+		}
+		probed[uri] = nil
+		unprobed = append(unprobed, item.URI)
+	}
+	if len(unprobed) > 0 && ctx.Err() == nil {
+		// every URL is a seed of zero depth crawl, so links are not followed
+		probe := append(append([]sitemap.ParserOption{}, options...), sitemap.WithSeeds(unprobed[1:]...))
+		if parser, err = sitemap.NewParser(probe...); err != nil {
+			return nil, fmt.Errorf("audit.Run: %s", err)
+		}
+		for item := range parser.Stream(ctx, unprobed[0], 0, workers) {
+			probed[item.URI.String()] = item.DocumentMeta
+		}
+	}
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("audit.Run: %s", ctx.Err())
+	}
+
+	mx.Lock()
+	defer mx.Unlock()
+	return compare(published, crawled, probed, fetches), nil
+}
+
+// compare - builds audit result.
+// Arguments `crawled` and `probed` contain metadata of discovered and separately fetched documents,
+// metadata is nil if document was not fetched successfully.
+func compare(
+	published []sitemap.MapItem,
+	crawled, probed map[string]*sitemap.DocumentMeta,
+	fetches map[string]sitemap.FetchEvent,
+) *Result {
+	r := &Result{Issues: []Finding{}, Missing: []string{}}
+	listed := map[string]bool{}
+	for _, item := range published {
+		uri := item.URI.String()
+		if listed[uri] {
+			continue
+		}
+		listed[uri] = true
+		meta, linked := crawled[uri]
+		if !linked {
+			meta = probed[uri]
+		}
+		event := fetches[uri]
+		finding := Finding{URI: uri}
+		switch {
+		case meta == nil && !unsupported(event.Err):
+			finding.Issue = IssueBroken
+			finding.StatusCode = event.StatusCode
+			if event.Err != nil {
+				finding.Error = event.Err.Error()
+			}
+		case meta != nil && meta.NoIndex:
+			finding.Issue = IssueNoIndex
+		case !linked:
+			finding.Issue = IssueUnreachable
+		default:
+			continue
+		}
+		r.Issues = append(r.Issues, finding)
+	}
+	r.Published = len(listed)
+
+	for uri, meta := range crawled {
+		if meta == nil || meta.NoIndex {
+			continue
+		}
+		r.Reachable++
+		if !listed[uri] {
+			r.Missing = append(r.Missing, uri)
+		}
+	}
+
+	sort.Slice(r.Issues, func(i, j int) bool { return r.Issues[i].URI < r.Issues[j].URI })
+	sort.Strings(r.Missing)
+	return r
+}
+
+// unsupported - checks document was fetched successfully, but it is not HTML,
+// such documents are allowed in site map.
+func unsupported(err error) bool {
+	e, ok := err.(*sitemap.CrawlError)
+	return ok && e.Class == sitemap.ErrorContentType
+}
@@ -0,0 +1,177 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/wtask/sitemap/internal/sitemap"
+)
+
+func testServer() *httptest.Server {
+	pages := map[string]string{
+		"/":             `<a href="a.html"></a><a href="b.html"></a><a href="noindex.html"></a><a href="missing.html"></a><a href="doc.pdf"></a><a href="header.html"></a>`,
+		"/a.html":       `<a href="/"></a>`,
+		"/b.html":       `<p>not listed</p>`,
+		"/orphan.html":  `<p>not linked</p>`,
+		"/noindex.html": `<p>noindex by meta tag</p>`,
+		"/header.html":  `<p>noindex by header</p>`,
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/doc.pdf" {
+			w.Header().Set("Content-Type", "application/pdf")
+			w.Write([]byte("%PDF-1.4"))
+			return
+		}
+		page, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if r.URL.Path == "/header.html" {
+			w.Header().Set("X-Robots-Tag", "googlebot: noindex")
+		}
+		head := ""
+		if r.URL.Path == "/noindex.html" {
+			head = `<meta name="robots" content="noindex">`
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte("<html><head>" + head + "</head><body>" + page + "</body></html>"))
+	}))
+}
+
+func TestRun(t *testing.T) {
+	server := testServer()
+	defer server.Close()
+
+	published := []sitemap.MapItem{}
+	for _, path := range []string{
+		"/", "/a.html", "/a.html", "/noindex.html", "/missing.html", "/doc.pdf", "/header.html",
+		"/orphan.html", "/gone.html",
+	} {
+		uri, _ := sitemap.NewURI(server.URL + path)
+		published = append(published, sitemap.MapItem{URI: uri})
+	}
+	root, _ := sitemap.NewURI(server.URL + "/")
+
+	result, err := Run(context.Background(), published, root, 1, 2)
+	if err != nil {
+		t.Fatal("Unexpected Run() error:", err)
+	}
+
+	if result.Published != 8 || result.Reachable != 3 {
+		t.Errorf("Unexpected counters: published %d, reachable %d", result.Published, result.Reachable)
+	}
+	issues := map[string]Finding{}
+	for _, f := range result.Issues {
+		issues[strings.TrimPrefix(f.URI, server.URL)] = f
+	}
+	expected := map[string]Issue{
+		"/noindex.html": IssueNoIndex,
+		"/header.html":  IssueNoIndex,
+		"/missing.html": IssueBroken,
+		"/gone.html":    IssueBroken,
+		"/orphan.html":  IssueUnreachable,
+	}
+	if len(issues) != len(expected) {
+		t.Errorf("Unexpected issues: %+v", result.Issues)
+	}
+	for path, issue := range expected {
+		if issues[path].Issue != issue {
+			t.Errorf("Expected %q issue for %s, got: %+v", issue, path, issues[path])
+		}
+	}
+	if f := issues["/gone.html"]; f.StatusCode != http.StatusNotFound || f.Error == "" {
+		t.Errorf("Unexpected finding for broken URL: %+v", f)
+	}
+	if !reflect.DeepEqual(result.Missing, []string{server.URL + "/b.html"}) {
+		t.Errorf("Unexpected missing pages: %v", result.Missing)
+	}
+	if result.OK() {
+		t.Error("Expected result is not OK")
+	}
+}
+
+func TestRun_concurrentProbes(t *testing.T) {
+	running, max := int32(0), int32(0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			n := atomic.AddInt32(&running, 1)
+			defer atomic.AddInt32(&running, -1)
+			for m := atomic.LoadInt32(&max); n > m && !atomic.CompareAndSwapInt32(&max, m, n); m = atomic.LoadInt32(&max) {
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head></head><body><a href="/orphan/0.html">linked</a></body></html>`))
+	}))
+	defer server.Close()
+
+	published := []sitemap.MapItem{}
+	for i := 0; i < 8; i++ {
+		uri, _ := sitemap.NewURI(fmt.Sprintf("%s/orphan/%d.html", server.URL, i))
+		published = append(published, sitemap.MapItem{URI: uri})
+	}
+	root, _ := sitemap.NewURI(server.URL + "/")
+	result, err := Run(context.Background(), published, root, 0, 4)
+	if err != nil {
+		t.Fatal("Unexpected Run() error:", err)
+	}
+	if len(result.Issues) != 8 {
+		t.Errorf("Expected 8 unreachable URLs, got: %+v", result.Issues)
+	}
+	for _, f := range result.Issues {
+		if f.Issue != IssueUnreachable {
+			t.Errorf("Unexpected finding: %+v", f)
+		}
+	}
+	if max := atomic.LoadInt32(&max); max < 2 || max > 4 {
+		t.Error("Expected URLs are probed concurrently with at most 4 workers, max concurrent requests:", max)
+	}
+}
+
+func TestRun_canceled(t *testing.T) {
+	server := testServer()
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	root, _ := sitemap.NewURI(server.URL + "/")
+	if _, err := Run(ctx, nil, root, 1, 1); err == nil {
+		t.Error("Expected error for canceled audit")
+	}
+}
+
+func TestResult_WriteText(t *testing.T) {
+	r := &Result{
+		Published: 3,
+		Reachable: 2,
+		Issues: []Finding{
+			{URI: "http://localhost/a.html", Issue: IssueBroken, StatusCode: 404, Error: "not found"},
+			{URI: "http://localhost/b.html", Issue: IssueUnreachable},
+		},
+		Missing: []string{"http://localhost/c.html"},
+	}
+	buf := bytes.Buffer{}
+	if err := r.WriteText(&buf); err != nil {
+		t.Fatal("Unexpected WriteText() error:", err)
+	}
+	expected := `Published URLs: 3, reachable pages: 2
+SITEMAP broken http://localhost/a.html (404) not found
+SITEMAP unreachable http://localhost/b.html
+MISSING http://localhost/c.html
+`
+	if buf.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+	if (&Result{}).OK() != true {
+		t.Error("Expected empty result is OK")
+	}
+}
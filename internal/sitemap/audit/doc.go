@@ -0,0 +1,2 @@
+// Package audit compares published site map against results of site crawl.
+package audit
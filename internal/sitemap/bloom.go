@@ -0,0 +1,61 @@
+package sitemap
+
+import (
+	"hash/fnv"
+	"math"
+)
+
+// bloomFilter - probabilistic set of strings, which may report false positives, but never false negatives.
+type bloomFilter struct {
+	bits []uint64
+	m, k uint64
+}
+
+// newBloomFilter - creates filter for expected number of items `n` with given false positive rate `p`.
+func newBloomFilter(n uint, p float64) *bloomFilter {
+	if n == 0 {
+		n = 1
+	}
+	m := uint64(math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2)))
+	if m < 64 {
+		m = 64
+	}
+	k := uint64(math.Round(float64(m) / float64(n) * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+	return &bloomFilter{
+		bits: make([]uint64, (m+63)/64),
+		m:    m,
+		k:    k,
+	}
+}
+
+// hashes - returns two independent hashes of value for double hashing.
+func (b *bloomFilter) hashes(value string) (uint64, uint64) {
+	h1, h2 := fnv.New64a(), fnv.New64()
+	h1.Write([]byte(value))
+	h2.Write([]byte(value))
+	return h1.Sum64(), h2.Sum64() | 1
+}
+
+// add - puts value into filter.
+func (b *bloomFilter) add(value string) {
+	h1, h2 := b.hashes(value)
+	for i := uint64(0); i < b.k; i++ {
+		bit := (h1 + i*h2) % b.m
+		b.bits[bit/64] |= 1 << (bit % 64)
+	}
+}
+
+// has - checks value may be inside filter.
+func (b *bloomFilter) has(value string) bool {
+	h1, h2 := b.hashes(value)
+	for i := uint64(0); i < b.k; i++ {
+		bit := (h1 + i*h2) % b.m
+		if b.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
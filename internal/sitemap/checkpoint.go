@@ -0,0 +1,90 @@
+package sitemap
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Checkpoint - snapshot of crawl progress, which allows to resume interrupted crawl.
+type Checkpoint struct {
+	// Root - URI from which crawl was started
+	Root string `json:"root"`
+	// Depth - maximum depth of crawl
+	Depth uint `json:"depth"`
+	// Created - time when snapshot was taken
+	Created time.Time `json:"created"`
+	// Frontier - targets which are discovered but not completely processed yet
+	Frontier []CheckpointTarget `json:"frontier"`
+	// Visited - completely processed targets
+	Visited []CheckpointItem `json:"visited"`
+	// FrontierLog - position of queued targets inside file of DiskFrontier, which are not copied into Frontier
+	FrontierLog *LogPosition `json:"frontier_log,omitempty"`
+	// VisitedLog - position of processed targets inside file of DiskVisitedStore, which are not copied into Visited
+	VisitedLog *LogPosition `json:"visited_log,omitempty"`
+}
+
+// LogPosition - range of records inside file of disk store, see OpenDiskFrontier and OpenDiskVisitedStore.
+type LogPosition struct {
+	File   string `json:"file"`
+	Offset int64  `json:"offset"`
+	Size   int64  `json:"size"`
+}
+
+// CheckpointTarget - serializable representation of Target.
+type CheckpointTarget struct {
+	URI   string `json:"uri"`
+	Level uint   `json:"level"`
+}
+
+// CheckpointItem - serializable representation of MapItem.
+type CheckpointItem struct {
+	URI  string        `json:"uri"`
+	Meta *DocumentMeta `json:"meta,omitempty"`
+}
+
+// CheckpointHandler - func which should persist crawl checkpoint.
+type CheckpointHandler func(*Checkpoint)
+
+// LoadCheckpoint - reads checkpoint previously written with Checkpoint.Save.
+func LoadCheckpoint(r io.Reader) (*Checkpoint, error) {
+	cp := &Checkpoint{}
+	if err := json.NewDecoder(r).Decode(cp); err != nil {
+		return nil, fmt.Errorf("sitemap.LoadCheckpoint: unable to decode checkpoint: %s", err)
+	}
+	if _, err := NewURI(cp.Root); err != nil {
+		return nil, fmt.Errorf("sitemap.LoadCheckpoint: invalid root: %s", err)
+	}
+	return cp, nil
+}
+
+// Save - writes checkpoint in JSON format with given writer.
+func (cp *Checkpoint) Save(w io.Writer) error {
+	if cp == nil {
+		return fmt.Errorf("sitemap.Checkpoint.Save: checkpoint is nil")
+	}
+	return json.NewEncoder(w).Encode(cp)
+}
+
+// targets - converts frontier into list of targets, invalid URIs are skipped.
+func (cp *Checkpoint) targets() []Target {
+	targets := []Target{}
+	for _, t := range cp.Frontier {
+		if uri, err := NewURI(t.URI); err == nil {
+			targets = append(targets, Target{uri, t.Level})
+		}
+	}
+	return targets
+}
+
+// items - converts visited list into map items, invalid URIs are skipped.
+func (cp *Checkpoint) items() []MapItem {
+	items := []MapItem{}
+	for _, item := range cp.Visited {
+		if uri, err := NewURI(item.URI); err == nil {
+			items = append(items, MapItem{uri, item.Meta})
+		}
+	}
+	return items
+}
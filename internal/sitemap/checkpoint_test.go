@@ -0,0 +1,195 @@
+package sitemap
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCheckpoint_SaveLoad(t *testing.T) {
+	expected := &Checkpoint{
+		Root:    "http://localhost/",
+		Depth:   2,
+		Created: time.Date(2019, 5, 21, 23, 26, 0, 0, time.UTC),
+		Frontier: []CheckpointTarget{
+			{"http://localhost/faq.html", 1},
+		},
+		Visited: []CheckpointItem{
+			{"http://localhost/", &DocumentMeta{Modified: time.Date(2019, 5, 21, 23, 26, 0, 0, time.UTC)}},
+			{"http://localhost/notfound.html", nil},
+		},
+	}
+	buf := bytes.Buffer{}
+	if err := expected.Save(&buf); err != nil {
+		t.Fatal("Unexpected Save() error:", err)
+	}
+	actual, err := LoadCheckpoint(&buf)
+	if err != nil {
+		t.Fatal("Unexpected LoadCheckpoint() error:", err)
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Error("Expected:", expected, "actual:", actual)
+	}
+
+	for _, source := range []string{"", "{}", `{"root":"/relative"}`} {
+		if _, err := LoadCheckpoint(strings.NewReader(source)); err == nil {
+			t.Errorf("Expected error for %q", source)
+		}
+	}
+}
+
+func TestParser_Resume(t *testing.T) {
+	mx := sync.Mutex{}
+	requested := []string{}
+	files := http.FileServer(http.Dir("testdata/simplesite"))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mx.Lock()
+		requested = append(requested, r.URL.Path)
+		mx.Unlock()
+		files.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	cp := &Checkpoint{
+		Root:  server.URL + "/homepage.html",
+		Depth: 1,
+		Frontier: []CheckpointTarget{
+			{server.URL + "/faq.html", 1},
+			{server.URL + "/terms.html", 1},
+		},
+		Visited: []CheckpointItem{
+			{server.URL + "/homepage.html", &DocumentMeta{}},
+			{server.URL + "/protocol.html", &DocumentMeta{}},
+		},
+	}
+	parser, err := NewParser()
+	if err != nil {
+		t.Fatal("Unexpected NewParser() error:", err)
+	}
+	items, err := parser.Resume(context.Background(), cp, 2)
+	if err != nil {
+		t.Fatal("Unexpected Resume() error:", err)
+	}
+	found := []string{}
+	for item := range items {
+		found = append(found, strings.TrimPrefix(item.URI.String(), server.URL))
+	}
+	sort.Strings(found)
+	sort.Strings(requested)
+	expected := []string{"/faq.html", "/homepage.html", "/protocol.html", "/terms.html"}
+	if !reflect.DeepEqual(expected, found) {
+		t.Error("Expected items:", expected, "actual:", found)
+	}
+	expected = []string{"/faq.html", "/terms.html"}
+	if !reflect.DeepEqual(expected, requested) {
+		t.Error("Expected requests:", expected, "actual:", requested)
+	}
+
+	if _, err := parser.Resume(context.Background(), nil, 2); err == nil {
+		t.Error("Expected error for nil checkpoint")
+	}
+}
+
+func TestParser_checkpoint(t *testing.T) {
+	server := httptest.NewServer(http.FileServer(http.Dir("testdata/simplesite")))
+	defer server.Close()
+
+	var last *Checkpoint
+	parser, err := NewParser(WithCheckpoint(time.Nanosecond, func(cp *Checkpoint) {
+		last = cp
+	}))
+	if err != nil {
+		t.Fatal("Unexpected NewParser() error:", err)
+	}
+	root, _ := NewURI(server.URL + "/homepage.html")
+	parser.Parse(root, 1, 2)
+	if last == nil {
+		t.Fatal("Checkpoint handler was not called")
+	}
+	if last.Root != root.String() || last.Depth != 1 {
+		t.Error("Unexpected checkpoint root or depth:", last.Root, last.Depth)
+	}
+
+	if _, err := NewParser(WithCheckpoint(0, func(*Checkpoint) {})); err == nil {
+		t.Error("Expected error for zero checkpoint interval")
+	}
+	if _, err := NewParser(WithCheckpoint(time.Second, nil)); err == nil {
+		t.Error("Expected error for nil checkpoint handler")
+	}
+}
+
+func TestParser_checkpointInBackground(t *testing.T) {
+	server := httptest.NewServer(http.FileServer(http.Dir("testdata/simplesite")))
+	defer server.Close()
+
+	found := make(chan struct{}, 10)
+	calls := 0
+	parser, err := NewParser(
+		WithResultHandler(func(MapItem) { found <- struct{}{} }),
+		WithCheckpoint(time.Nanosecond, func(cp *Checkpoint) {
+			calls++
+			if calls > 1 {
+				return
+			}
+			// crawl must go on while handler is running
+			for i := 0; i < 2; i++ {
+				select {
+				case <-found:
+				case <-time.After(5 * time.Second):
+					t.Error("Crawl is paused while checkpoint handler is running")
+					return
+				}
+			}
+		}),
+	)
+	if err != nil {
+		t.Fatal("Unexpected NewParser() error:", err)
+	}
+	root, _ := NewURI(server.URL + "/homepage.html")
+	if items := parser.Parse(root, 1, 2); len(items) < 2 {
+		t.Fatal("Unexpected num of items:", len(items))
+	}
+}
+
+func TestParser_checkpointOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow.html" {
+			cancel()
+			<-r.Context().Done()
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><body><a href="/slow.html">slow</a></body></html>`))
+	}))
+	defer server.Close()
+
+	var last *Checkpoint
+	parser, err := NewParser(WithCheckpoint(time.Hour, func(cp *Checkpoint) {
+		last = cp
+	}))
+	if err != nil {
+		t.Fatal("Unexpected NewParser() error:", err)
+	}
+	root, _ := NewURI(server.URL + "/")
+	for range parser.Stream(ctx, root, 1, 1) {
+	}
+	if last == nil {
+		t.Fatal("Final checkpoint was not taken")
+	}
+	expected := []CheckpointTarget{{server.URL + "/slow.html", 1}}
+	if !reflect.DeepEqual(expected, last.Frontier) {
+		t.Error("Expected frontier:", expected, "actual:", last.Frontier)
+	}
+	if len(last.Visited) != 1 || last.Visited[0].URI != root.String() {
+		t.Error("Unexpected visited:", last.Visited)
+	}
+}
@@ -0,0 +1,176 @@
+package sitemap
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/http/httpguts"
+)
+
+// WithHTTPClient - use given client for all requests made with Parser instead of http.DefaultClient.
+// Redirect policy of client is replaced by Parser to record redirect chains.
+func WithHTTPClient(c *http.Client) ParserOption {
+	if c == nil {
+		return failedOption(fmt.Errorf("Invalid HTTP client (nil)"))
+	}
+	return func(p *Parser) error {
+		p.client = c
+		return nil
+	}
+}
+
+// WithHeader - add header to every request made with Parser.
+// Header can be added several times, headers which are set by Parser (Accept, conditional headers) can be overwritten.
+func WithHeader(name, value string) ParserOption {
+	if !httpguts.ValidHeaderFieldName(name) {
+		return failedOption(fmt.Errorf("Invalid header name %q", name))
+	}
+	if !httpguts.ValidHeaderFieldValue(value) {
+		return failedOption(fmt.Errorf("Invalid value of header %q", name))
+	}
+	return func(p *Parser) error {
+		if p.headers == nil {
+			p.headers = http.Header{}
+		}
+		p.headers.Add(name, value)
+		return nil
+	}
+}
+
+// WithUserAgent - send given User-Agent header instead of default one.
+func WithUserAgent(ua string) ParserOption {
+	if ua == "" {
+		return failedOption(fmt.Errorf("Invalid user agent (empty)"))
+	}
+	return withAuthority("User-Agent", ua)
+}
+
+// WithBasicAuth - authenticate every request with basic authentication scheme.
+func WithBasicAuth(username, password string) ParserOption {
+	if username == "" {
+		return failedOption(fmt.Errorf("Invalid basic auth username (empty)"))
+	}
+	credentials := base64.StdEncoding.EncodeToString([]byte(username + ":" + password))
+	return withAuthority("Authorization", "Basic "+credentials)
+}
+
+// WithBearerToken - authenticate every request with bearer token.
+func WithBearerToken(token string) ParserOption {
+	if token == "" {
+		return failedOption(fmt.Errorf("Invalid bearer token (empty)"))
+	}
+	return withAuthority("Authorization", "Bearer "+token)
+}
+
+// withAuthority - sets single-valued header.
+func withAuthority(name, value string) ParserOption {
+	if !httpguts.ValidHeaderFieldValue(value) {
+		return failedOption(fmt.Errorf("Invalid value of header %q", name))
+	}
+	return func(p *Parser) error {
+		if p.headers == nil {
+			p.headers = http.Header{}
+		}
+		p.headers.Set(name, value)
+		return nil
+	}
+}
+
+// WithCookieJar - send cookies from given jar and store cookies set by server into it.
+// Jar is used along with any client declared with WithHTTPClient.
+func WithCookieJar(jar http.CookieJar) ParserOption {
+	if jar == nil {
+		return failedOption(fmt.Errorf("Invalid cookie jar (nil)"))
+	}
+	return func(p *Parser) error {
+		p.jar = jar
+		return nil
+	}
+}
+
+// httpClient - returns copy of parser client, which records redirects into `redirects` and uses parser jar.
+func (p *Parser) httpClient(redirects *[]string) *http.Client {
+	client := *http.DefaultClient
+	if p.client != nil {
+		client = *p.client
+	}
+	if p.jar != nil {
+		client.Jar = p.jar
+	}
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) >= maxRedirects {
+			return fmt.Errorf("stopped after %d redirects", maxRedirects)
+		}
+		*redirects = append(*redirects, req.URL.String())
+		return nil
+	}
+	return &client
+}
+
+// LoadCookieFile - reads cookies in Netscape format (used by curl and browser extensions) and puts them into jar.
+// Every line of file contains tab-separated fields: domain, include subdomains flag, path, secure flag,
+// expiration time (Unix seconds, 0 for session cookie), name and value.
+// Empty lines and comments are skipped, "#HttpOnly_" prefix of domain marks HTTP-only cookie.
+// Returns num of loaded cookies.
+func LoadCookieFile(jar http.CookieJar, r io.Reader) (int, error) {
+	if jar == nil {
+		return 0, fmt.Errorf("sitemap.LoadCookieFile: jar is nil")
+	}
+	n, line := 0, 0
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line++
+		text := strings.TrimRight(scanner.Text(), "\r")
+		httpOnly := false
+		if strings.HasPrefix(text, "#HttpOnly_") {
+			text = strings.TrimPrefix(text, "#HttpOnly_")
+			httpOnly = true
+		}
+		if strings.TrimSpace(text) == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Split(text, "\t")
+		if len(fields) != 7 {
+			return n, fmt.Errorf("sitemap.LoadCookieFile: line %d: expected 7 tab-separated fields, got %d", line, len(fields))
+		}
+		domain, subdomains, path, secure, expires, name, value := fields[0], fields[1], fields[2], fields[3], fields[4], fields[5], fields[6]
+		host := strings.TrimPrefix(domain, ".")
+		if host == "" || name == "" {
+			return n, fmt.Errorf("sitemap.LoadCookieFile: line %d: empty domain or name", line)
+		}
+		cookie := &http.Cookie{
+			Name:     name,
+			Value:    value,
+			Path:     path,
+			Secure:   strings.EqualFold(secure, "TRUE"),
+			HttpOnly: httpOnly,
+		}
+		if strings.EqualFold(subdomains, "TRUE") {
+			cookie.Domain = host
+		}
+		if expires != "0" {
+			sec, err := strconv.ParseInt(expires, 10, 64)
+			if err != nil {
+				return n, fmt.Errorf("sitemap.LoadCookieFile: line %d: invalid expiration time %q", line, expires)
+			}
+			cookie.Expires = time.Unix(sec, 0)
+		}
+		scheme := "http"
+		if cookie.Secure {
+			scheme = "https"
+		}
+		jar.SetCookies(&url.URL{Scheme: scheme, Host: host, Path: path}, []*http.Cookie{cookie})
+		n++
+	}
+	if err := scanner.Err(); err != nil {
+		return n, fmt.Errorf("sitemap.LoadCookieFile: %s", err)
+	}
+	return n, nil
+}
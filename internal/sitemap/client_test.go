@@ -0,0 +1,164 @@
+package sitemap
+
+import (
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestParser_requestHeaders(t *testing.T) {
+	mx := sync.Mutex{}
+	requests := []*http.Request{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mx.Lock()
+		requests = append(requests, r)
+		mx.Unlock()
+		http.SetCookie(w, &http.Cookie{Name: "visited", Value: "1", Path: "/"})
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head></head><body><a href="/next.html">next</a></body></html>`))
+	}))
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+
+	cases := []struct {
+		name     string
+		options  []ParserOption
+		expected map[string]string
+	}{
+		{
+			"defaults",
+			nil,
+			map[string]string{"Accept": "text/html, application/xhtml+xml", "Authorization": "", "Cookie": ""},
+		},
+		{
+			"user agent and headers",
+			[]ParserOption{
+				WithUserAgent("smgen/1.0"),
+				WithHeader("X-Env", "staging"),
+				WithHeader("Accept", "text/html,application/xhtml+xml"),
+			},
+			map[string]string{"User-Agent": "smgen/1.0", "X-Env": "staging", "Accept": "text/html,application/xhtml+xml"},
+		},
+		{
+			"basic auth",
+			[]ParserOption{WithBasicAuth("user", "secret")},
+			map[string]string{"Authorization": "Basic dXNlcjpzZWNyZXQ="},
+		},
+		{
+			"bearer token",
+			[]ParserOption{WithBasicAuth("user", "secret"), WithBearerToken("token")},
+			map[string]string{"Authorization": "Bearer token"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			requests = requests[:0]
+			parser, err := NewParser(c.options...)
+			if err != nil {
+				t.Fatal("Unexpected NewParser() error:", err)
+			}
+			root, _ := NewURI(server.URL + "/")
+			parser.Parse(root, 0, 1)
+			if len(requests) != 1 {
+				t.Fatal("Unexpected num of requests:", len(requests))
+			}
+			for name, value := range c.expected {
+				if actual := requests[0].Header.Get(name); actual != value {
+					t.Errorf("Expected %s: %q, got %q", name, value, actual)
+				}
+			}
+		})
+	}
+
+	t.Run("cookie jar", func(t *testing.T) {
+		requests = requests[:0]
+		jar, _ := cookiejar.New(nil)
+		jar.SetCookies(serverURL, []*http.Cookie{{Name: "session", Value: "abc"}})
+		parser, err := NewParser(WithHTTPClient(&http.Client{}), WithCookieJar(jar))
+		if err != nil {
+			t.Fatal("Unexpected NewParser() error:", err)
+		}
+		root, _ := NewURI(server.URL + "/")
+		parser.Parse(root, 1, 1)
+		if len(requests) != 2 {
+			t.Fatal("Unexpected num of requests:", len(requests))
+		}
+		if cookie := requests[0].Header.Get("Cookie"); cookie != "session=abc" {
+			t.Errorf("Unexpected cookie of first request: %q", cookie)
+		}
+		// cookie set by server is stored into jar
+		if cookie := requests[1].Header.Get("Cookie"); !strings.Contains(cookie, "visited=1") {
+			t.Errorf("Unexpected cookie of second request: %q", cookie)
+		}
+	})
+
+	invalid := []ParserOption{
+		WithHTTPClient(nil),
+		WithCookieJar(nil),
+		WithUserAgent(""),
+		WithHeader("X Invalid", "value"),
+		WithHeader("X-Invalid", "line\nbreak"),
+		WithBasicAuth("", "secret"),
+		WithBearerToken(""),
+	}
+	for i, option := range invalid {
+		if _, err := NewParser(option); err == nil {
+			t.Errorf("Expected error for invalid option #%d", i)
+		}
+	}
+}
+
+func TestLoadCookieFile(t *testing.T) {
+	file := strings.Join([]string{
+		"# Netscape HTTP Cookie File",
+		"",
+		"localhost\tFALSE\t/\tFALSE\t0\tsession\tabc",
+		"#HttpOnly_.example.com\tTRUE\t/\tTRUE\t4102444800\ttoken\txyz",
+		"localhost\tFALSE\t/admin/\tFALSE\t0\tadmin\t1",
+		"localhost\tFALSE\t/\tFALSE\t1\texpired\t1",
+	}, "\n")
+	jar, _ := cookiejar.New(nil)
+	n, err := LoadCookieFile(jar, strings.NewReader(file))
+	if err != nil {
+		t.Fatal("Unexpected error:", err)
+	}
+	if n != 4 {
+		t.Error("Unexpected num of loaded cookies:", n)
+	}
+	cases := []struct {
+		uri      string
+		expected string
+	}{
+		{"http://localhost/", "session=abc"},
+		{"http://localhost/admin/page.html", "admin=1; session=abc"},
+		{"https://www.example.com/", "token=xyz"},
+		{"http://www.example.com/", ""},
+	}
+	for _, c := range cases {
+		u, _ := url.Parse(c.uri)
+		names := []string{}
+		for _, cookie := range jar.Cookies(u) {
+			names = append(names, cookie.Name+"="+cookie.Value)
+		}
+		if actual := strings.Join(names, "; "); actual != c.expected {
+			t.Errorf("Expected %q for %s, got %q", c.expected, c.uri, actual)
+		}
+	}
+
+	for _, invalid := range []string{
+		"localhost\tFALSE\t/\tFALSE\t0\tsession",
+		"localhost\tFALSE\t/\tFALSE\tnever\tsession\tabc",
+		"\tFALSE\t/\tFALSE\t0\tsession\tabc",
+	} {
+		if _, err := LoadCookieFile(jar, strings.NewReader(invalid)); err == nil {
+			t.Errorf("Expected error for %q", invalid)
+		}
+	}
+	if _, err := LoadCookieFile(nil, strings.NewReader("")); err == nil {
+		t.Error("Expected error for nil jar")
+	}
+}
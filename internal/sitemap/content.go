@@ -0,0 +1,172 @@
+package sitemap
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+)
+
+// DefaultAcceptedTypes - media types of documents which are parsed for links by default.
+var DefaultAcceptedTypes = []string{"text/html", "application/xhtml+xml"}
+
+// WithAcceptedTypes - declare media types of documents which are parsed for links instead of DefaultAcceptedTypes.
+// Type can be given with wildcard subtype, like "text/*". Documents of XML-based types (XHTML)
+// are parsed with respect to XML syntax: encoding of XML declaration and self-closing tags.
+func WithAcceptedTypes(types ...string) ParserOption {
+	if len(types) == 0 {
+		return failedOption(fmt.Errorf("Empty list of accepted types"))
+	}
+	accepted, err := parseMediaTypes(types)
+	if err != nil {
+		return failedOption(fmt.Errorf("Invalid accepted type %s", err))
+	}
+	return func(p *Parser) error {
+		p.accepted = accepted
+		return nil
+	}
+}
+
+// parseMediaTypes - validates media types and returns them without parameters.
+func parseMediaTypes(types []string) ([]string, error) {
+	media := make([]string, 0, len(types))
+	for _, t := range types {
+		m, _, err := mime.ParseMediaType(t)
+		if err != nil {
+			return nil, fmt.Errorf("%q: %s", t, err)
+		}
+		if !strings.Contains(m, "/") || strings.HasPrefix(m, "*/") {
+			return nil, fmt.Errorf("%q", t)
+		}
+		media = append(media, m)
+	}
+	return media, nil
+}
+
+// matchMediaType - checks content type matches any of types, exactly or by wildcard subtype.
+func matchMediaType(types []string, ctype string) bool {
+	media, _, err := mime.ParseMediaType(ctype)
+	if err != nil {
+		return false
+	}
+	for _, t := range types {
+		if t == media || strings.HasSuffix(t, "/*") && strings.HasPrefix(media, strings.TrimSuffix(t, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+// acceptedTypes - returns media types of documents parsed for links.
+func (p *Parser) acceptedTypes() []string {
+	if p.accepted == nil {
+		return DefaultAcceptedTypes
+	}
+	return p.accepted
+}
+
+// isDocument - checks content type of response is one of accepted types.
+func (p *Parser) isDocument(ctype string) bool {
+	return matchMediaType(p.acceptedTypes(), ctype)
+}
+
+// isXML - checks content type is XML-based, like "application/xhtml+xml".
+func isXML(ctype string) bool {
+	media, _, err := mime.ParseMediaType(ctype)
+	if err != nil {
+		return false
+	}
+	return strings.HasSuffix(media, "+xml") || media == "application/xml" || media == "text/xml"
+}
+
+// xmlEncoding - matches encoding of XML declaration.
+var xmlEncoding = regexp.MustCompile(`^\s*<\?xml\s[^>]*encoding\s*=\s*["']([A-Za-z0-9._-]+)["']`)
+
+// documentReader - returns reader of document content decoded into UTF-8.
+// Encoding of XML-based document without charset in content type is taken from XML declaration,
+// UTF-8 is used by default as XML requires. Self-closing tags of XML-based document are normalized,
+// see normalizeXHTML.
+func documentReader(r io.Reader, ctype string) (io.Reader, error) {
+	if !isXML(ctype) {
+		return charset.NewReader(r, ctype)
+	}
+	var utf8 io.Reader
+	if _, params, _ := mime.ParseMediaType(ctype); params["charset"] != "" {
+		decoded, err := charset.NewReader(r, ctype)
+		if err != nil {
+			return nil, err
+		}
+		utf8 = decoded
+	} else {
+		br := bufio.NewReader(r)
+		head, _ := br.Peek(1024)
+		label := "utf-8"
+		if m := xmlEncoding.FindSubmatch(head); m != nil {
+			label = string(m[1])
+		}
+		if hasBOM(head) {
+			// byte order mark takes precedence over declaration
+			label = ""
+		}
+		var err error
+		if label == "" {
+			utf8, err = charset.NewReader(br, ctype)
+		} else {
+			utf8, err = charset.NewReaderLabel(label, br)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return normalizeXHTML(utf8)
+}
+
+// hasBOM - checks content starts with UTF-8 or UTF-16 byte order mark.
+func hasBOM(head []byte) bool {
+	return bytes.HasPrefix(head, []byte{0xef, 0xbb, 0xbf}) ||
+		bytes.HasPrefix(head, []byte{0xfe, 0xff}) ||
+		bytes.HasPrefix(head, []byte{0xff, 0xfe})
+}
+
+// voidElements - HTML elements which never have content, so their self-closing tags are valid HTML.
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "param": true, "source": true, "track": true, "wbr": true,
+}
+
+// normalizeXHTML - replaces self-closing tags of non-void elements, like `<script src="..."/>`,
+// with start and end tags. HTML parser ignores self-closing syntax of such elements,
+// so `<script/>` or `<title/>` would consume the rest of document.
+func normalizeXHTML(r io.Reader) (io.Reader, error) {
+	out := &bytes.Buffer{}
+	z := html.NewTokenizer(r)
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			if z.Err() != io.EOF {
+				return nil, z.Err()
+			}
+			return out, nil
+		case html.SelfClosingTagToken:
+			raw := z.Raw()
+			name, _ := z.TagName()
+			if voidElements[string(name)] {
+				out.Write(raw)
+				continue
+			}
+			// tokenizer treats content of self-closing raw text element (script, style, title, etc.) as text
+			z.NextIsNotRawText()
+			raw = bytes.TrimRight(bytes.TrimSuffix(raw[:len(raw)-1], []byte("/")), " \t\r\n\f")
+			out.Write(raw)
+			fmt.Fprintf(out, "></%s>", name)
+		default:
+			out.Write(z.Raw())
+		}
+	}
+}
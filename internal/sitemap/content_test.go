@@ -0,0 +1,133 @@
+package sitemap
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestParser_acceptedTypes(t *testing.T) {
+	xhtml := `<?xml version="1.0" encoding="utf-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml"><head><script src="/app.js"/><title>XHTML</title></head>
+<body><a href="/a.html">a</a><br/><a href="/b.html"/></body></html>`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/page.xhtml":
+			w.Header().Set("Content-Type", "application/xhtml+xml")
+		case "/page.xml":
+			w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+		}
+		w.Write([]byte(xhtml))
+	}))
+	defer server.Close()
+
+	cases := []struct {
+		name    string
+		path    string
+		options []ParserOption
+		isErr   bool
+	}{
+		{"default xhtml", "/page.xhtml", nil, false},
+		{"default xml", "/page.xml", nil, true},
+		{"configured xml", "/page.xml", []ParserOption{WithAcceptedTypes("text/html", "application/xml")}, false},
+		{"configured wildcard", "/page.xml", []ParserOption{WithAcceptedTypes("application/*")}, false},
+		{"not configured xhtml", "/page.xhtml", []ParserOption{WithAcceptedTypes("text/html")}, true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			parser, err := NewParser(c.options...)
+			if err != nil {
+				t.Fatal("Unexpected NewParser() error:", err)
+			}
+			uri, _ := NewURI(server.URL + c.path)
+			doc, _, err := parser.fetchDocument(context.Background(), uri, nil)
+			if c.isErr {
+				if classify(err) != ErrorContentType {
+					t.Errorf("Expected content type error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal("Unexpected error:", err)
+			}
+			expected := []string{server.URL + "/a.html", server.URL + "/b.html"}
+			if links := documentLinks(uri, doc, nil, DefaultLinkSources); !reflect.DeepEqual(links, expected) {
+				t.Errorf("Expected %v, got %v", expected, links)
+			}
+		})
+	}
+
+	invalid := []ParserOption{
+		WithAcceptedTypes(),
+		WithAcceptedTypes("html"),
+		WithAcceptedTypes("*/*"),
+	}
+	for i, option := range invalid {
+		if _, err := NewParser(option); err == nil {
+			t.Errorf("Expected error for invalid option #%d", i)
+		}
+	}
+}
+
+func Test_documentReader(t *testing.T) {
+	cases := []struct {
+		name     string
+		ctype    string
+		content  string
+		expected string
+	}{
+		{"html", "text/html; charset=windows-1251", "<p>\xcf\xf0\xe8\xe2\xe5\xf2</p>", "<p>Привет</p>"},
+		{
+			"xml declaration",
+			"application/xhtml+xml",
+			"<?xml version='1.0' encoding='windows-1251'?><p>\xcf\xf0\xe8\xe2\xe5\xf2</p>",
+			"<?xml version='1.0' encoding='windows-1251'?><p>Привет</p>",
+		},
+		{
+			"charset of content type",
+			"application/xhtml+xml; charset=windows-1251",
+			"<?xml version='1.0' encoding='utf-8'?><p>\xcf\xf0\xe8\xe2\xe5\xf2</p>",
+			"<?xml version='1.0' encoding='utf-8'?><p>Привет</p>",
+		},
+		{"utf-8 by default", "application/xhtml+xml", "<p>Привет</p>", "<p>Привет</p>"},
+		{
+			"self-closing tags",
+			"application/xhtml+xml",
+			`<head><script src="a.js" /><style/><link rel="next" href="2.html"/></head><body><a href="/"/><br/></body>`,
+			`<head><script src="a.js"></script><style></style><link rel="next" href="2.html"/></head><body><a href="/"></a><br/></body>`,
+		},
+		{"html self-closing tags", "text/html", `<script src="a.js"/>`, `<script src="a.js"/>`},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r, err := documentReader(strings.NewReader(c.content), c.ctype)
+			if err != nil {
+				t.Fatal("Unexpected error:", err)
+			}
+			actual, err := ioutil.ReadAll(r)
+			if err != nil {
+				t.Fatal("Unexpected error:", err)
+			}
+			if string(actual) != c.expected {
+				t.Errorf("Expected %q, got %q", c.expected, actual)
+			}
+		})
+	}
+
+	// XHTML is parsed into the same tree as HTML written without self-closing tags
+	r, _ := documentReader(strings.NewReader(`<html><head><title/></head><body><p>text</p></body></html>`), "application/xhtml+xml")
+	doc, err := html.Parse(r)
+	if err != nil {
+		t.Fatal("Unexpected error:", err)
+	}
+	if p := firstNode("p", doc); p == nil || p.FirstChild == nil || p.FirstChild.Data != "text" {
+		t.Error("Expected paragraph is parsed after self-closing title")
+	}
+}
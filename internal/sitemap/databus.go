@@ -12,15 +12,32 @@ type Target struct {
 type DocumentMeta struct {
 	// Modified - document modification time
 	Modified time.Time
+	// ETag - entity tag of document, if server provides it
+	ETag string
+	// LastModified - raw value of Last-Modified header, if server provides it
+	LastModified string
+	// Hash - hex-encoded SHA-256 of document content
+	Hash string
+	// Size - num of bytes of document body downloaded
+	Size int64
+	// NotModified - server responded that document was not changed since previous crawl,
+	// so the document body was not fetched
+	NotModified bool
+	// NoIndex - document must not be indexed, as declared by robots meta tag or X-Robots-Tag header
+	NoIndex bool
+	// FinalURL - URL of document after redirects, empty if request was not redirected
+	FinalURL string
+
+	headerLinks []headerLink // links declared with Link header
 }
 
 // completedTarget - processed target data
 type completedTarget struct {
 	Target
-	err  error
-	meta *DocumentMeta // task document metadata
-	// errors <-chan error
-	targets <-chan Target
+	err      error
+	warnings []error       // non-fatal errors
+	meta     *DocumentMeta // task document metadata
+	targets  []Target      // links of document which should be processed
 }
 
 // MapItem - final result of site map
@@ -0,0 +1,76 @@
+package sitemap
+
+import (
+	"sort"
+	"time"
+)
+
+// MapChange - URI which modification time differs between two site maps.
+type MapChange struct {
+	*URI
+	// Old, New - modification time in old and new site map, zero if it is not defined
+	Old, New time.Time
+}
+
+// MapDiff - difference between two site maps, every list is sorted by URI.
+type MapDiff struct {
+	// Added - items of new site map which are absent in old one
+	Added []MapItem
+	// Removed - items of old site map which are absent in new one
+	Removed []MapItem
+	// Changed - URIs with changed modification time
+	Changed []MapChange
+}
+
+// Empty - reports site maps are equal.
+func (d MapDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// Diff - compares two site maps by URI and modification time.
+// Items without URI are ignored, if URI is listed several times, the first item is used.
+func Diff(old, new []MapItem) MapDiff {
+	before, after := mapIndex(old), mapIndex(new)
+	d := MapDiff{Added: []MapItem{}, Removed: []MapItem{}, Changed: []MapChange{}}
+	for uri, item := range after {
+		previous, ok := before[uri]
+		if !ok {
+			d.Added = append(d.Added, item)
+			continue
+		}
+		if was, is := modified(previous), modified(item); !was.Equal(is) {
+			d.Changed = append(d.Changed, MapChange{item.URI, was, is})
+		}
+	}
+	for uri, item := range before {
+		if _, ok := after[uri]; !ok {
+			d.Removed = append(d.Removed, item)
+		}
+	}
+	sort.Slice(d.Added, func(i, j int) bool { return d.Added[i].URI.String() < d.Added[j].URI.String() })
+	sort.Slice(d.Removed, func(i, j int) bool { return d.Removed[i].URI.String() < d.Removed[j].URI.String() })
+	sort.Slice(d.Changed, func(i, j int) bool { return d.Changed[i].URI.String() < d.Changed[j].URI.String() })
+	return d
+}
+
+// mapIndex - indexes site map items by URI.
+func mapIndex(m []MapItem) map[string]MapItem {
+	index := make(map[string]MapItem, len(m))
+	for _, item := range m {
+		if item.URI == nil {
+			continue
+		}
+		if _, ok := index[item.URI.String()]; !ok {
+			index[item.URI.String()] = item
+		}
+	}
+	return index
+}
+
+// modified - returns modification time of item, zero if metadata is not defined.
+func modified(item MapItem) time.Time {
+	if item.DocumentMeta == nil {
+		return time.Time{}
+	}
+	return item.Modified
+}
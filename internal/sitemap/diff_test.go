@@ -0,0 +1,59 @@
+package sitemap
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDiff(t *testing.T) {
+	uri := func(s string) *URI {
+		u, _ := NewURI(s)
+		return u
+	}
+	before := time.Date(2019, 5, 21, 23, 26, 0, 0, time.UTC)
+	after := time.Date(2019, 5, 22, 10, 0, 0, 0, time.UTC)
+	moscow, _ := time.LoadLocation("Europe/Moscow")
+
+	old := []MapItem{
+		{uri("http://localhost/"), &DocumentMeta{Modified: before}},
+		{uri("http://localhost/removed.html"), &DocumentMeta{Modified: before}},
+		{uri("http://localhost/changed.html"), &DocumentMeta{Modified: before}},
+		{uri("http://localhost/same-instant.html"), &DocumentMeta{Modified: before}},
+		{uri("http://localhost/no-meta.html"), nil},
+		{nil, &DocumentMeta{Modified: before}},
+	}
+	new := []MapItem{
+		{uri("http://localhost/added.html"), nil},
+		{uri("http://localhost/changed.html"), &DocumentMeta{Modified: after}},
+		{uri("http://localhost/same-instant.html"), &DocumentMeta{Modified: before.In(moscow)}},
+		{uri("http://localhost/no-meta.html"), &DocumentMeta{Modified: after}},
+		{uri("http://localhost/"), &DocumentMeta{Modified: before}},
+		{uri("http://localhost/"), &DocumentMeta{Modified: after}},
+	}
+
+	d := Diff(old, new)
+	if !reflect.DeepEqual(d.Added, []MapItem{new[0]}) {
+		t.Errorf("Unexpected added items: %v", d.Added)
+	}
+	if !reflect.DeepEqual(d.Removed, []MapItem{old[1]}) {
+		t.Errorf("Unexpected removed items: %v", d.Removed)
+	}
+	expected := []MapChange{
+		{uri("http://localhost/changed.html"), before, after},
+		{uri("http://localhost/no-meta.html"), time.Time{}, after},
+	}
+	if !reflect.DeepEqual(d.Changed, expected) {
+		t.Errorf("Unexpected changed items: %v", d.Changed)
+	}
+	if d.Empty() {
+		t.Error("Expected diff is not empty")
+	}
+
+	if d := Diff(old, old); !d.Empty() {
+		t.Errorf("Expected empty diff, got: %v", d)
+	}
+	if d := Diff(nil, nil); !d.Empty() || d.Added == nil || d.Removed == nil || d.Changed == nil {
+		t.Errorf("Expected empty non-nil lists, got: %#v", d)
+	}
+}
@@ -0,0 +1,487 @@
+package sitemap
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// maxRecordSize - maximum size of single record inside on-disk logs.
+const maxRecordSize = 1024 * 1024
+
+// DiskFrontier - Frontier which keeps queued targets inside append-only file,
+// so memory usage does not depend on the number of queued targets.
+// The file is truncated every time the queue becomes empty, until checkpoint refers to the file.
+type DiskFrontier struct {
+	mx       sync.Mutex
+	filename string
+	file     *os.File
+	w        *bufio.Writer
+	tail     *fileCursor
+	r        *bufio.Reader
+	size     int64 // num of bytes written
+	num      int
+	pinned   bool         // checkpoint refers to the file, so it is neither truncated nor removed
+	opened   *LogPosition // position from which frontier is opened
+}
+
+// fileCursor - reads file sequentially from given position, does not depend on file offset.
+type fileCursor struct {
+	file *os.File
+	pos  int64
+}
+
+func (c *fileCursor) Read(p []byte) (int, error) {
+	n, err := c.file.ReadAt(p, c.pos)
+	c.pos += int64(n)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+// NewDiskFrontier - creates empty frontier inside given file. Existing file is truncated.
+func NewDiskFrontier(filename string) (*DiskFrontier, error) {
+	file, err := os.OpenFile(filename, os.O_CREATE|os.O_TRUNC|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("sitemap.NewDiskFrontier: %s", err)
+	}
+	f := &DiskFrontier{filename: filename, file: file}
+	if err := f.reset(); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("sitemap.NewDiskFrontier: %s", err)
+	}
+	return f, nil
+}
+
+// OpenDiskFrontier - opens frontier inside file from position recorded by checkpoint, see Parser.Resume.
+// Targets which were pushed after checkpoint was taken are discarded.
+func OpenDiskFrontier(pos LogPosition) (*DiskFrontier, error) {
+	file, err := openLog(pos)
+	if err != nil {
+		return nil, fmt.Errorf("sitemap.OpenDiskFrontier: %s", err)
+	}
+	num := 0
+	if err := scanRecords(io.NewSectionReader(file, pos.Offset, pos.Size-pos.Offset), func([]byte) (bool, error) {
+		num++
+		return true, nil
+	}); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("sitemap.OpenDiskFrontier: %s", err)
+	}
+	f := &DiskFrontier{
+		filename: pos.File,
+		file:     file,
+		w:        bufio.NewWriter(file),
+		tail:     &fileCursor{file, pos.Offset},
+		size:     pos.Size,
+		num:      num,
+		pinned:   true,
+		opened:   &pos,
+	}
+	f.r = bufio.NewReader(f.tail)
+	return f, nil
+}
+
+// openLog - opens file of disk store and discards records written after given position.
+func openLog(pos LogPosition) (*os.File, error) {
+	if pos.Offset < 0 || pos.Size < pos.Offset {
+		return nil, fmt.Errorf("invalid position %d-%d of %s", pos.Offset, pos.Size, pos.File)
+	}
+	file, err := os.OpenFile(pos.File, os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	stat, err := file.Stat()
+	if err == nil && stat.Size() < pos.Size {
+		err = fmt.Errorf("%s is shorter than checkpoint position %d", pos.File, pos.Size)
+	}
+	if err == nil {
+		err = file.Truncate(pos.Size)
+	}
+	if err == nil {
+		_, err = file.Seek(pos.Size, io.SeekStart)
+	}
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return file, nil
+}
+
+// reset - truncates file and starts reading and writing from the beginning.
+func (f *DiskFrontier) reset() error {
+	if err := f.file.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := f.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	f.w = bufio.NewWriter(f.file)
+	f.tail = &fileCursor{f.file, 0}
+	f.r = bufio.NewReader(f.tail)
+	f.size = 0
+	return nil
+}
+
+// Push - appends target to the tail of queue.
+func (f *DiskFrontier) Push(t Target) error {
+	record, err := json.Marshal(CheckpointTarget{t.URI.String(), t.Level})
+	if err != nil {
+		return fmt.Errorf("sitemap.DiskFrontier.Push: %s", err)
+	}
+	f.mx.Lock()
+	defer f.mx.Unlock()
+	n, err := f.w.Write(append(record, '\n'))
+	f.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("sitemap.DiskFrontier.Push: %s", err)
+	}
+	f.num++
+	return nil
+}
+
+// Pop - removes target from the head of queue, returns false if queue is empty.
+func (f *DiskFrontier) Pop() (Target, bool, error) {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+	if f.num == 0 {
+		return Target{}, false, nil
+	}
+	if err := f.w.Flush(); err != nil {
+		return Target{}, false, fmt.Errorf("sitemap.DiskFrontier.Pop: %s", err)
+	}
+	line, err := f.r.ReadBytes('\n')
+	// broken record is dropped too, so it does not fail every next call
+	f.num--
+	if f.num == 0 && !f.pinned {
+		if err := f.reset(); err != nil {
+			return Target{}, false, fmt.Errorf("sitemap.DiskFrontier.Pop: %s", err)
+		}
+	}
+	if err != nil {
+		return Target{}, false, fmt.Errorf("sitemap.DiskFrontier.Pop: %s", err)
+	}
+	t, err := decodeTarget(line)
+	if err != nil {
+		return Target{}, false, fmt.Errorf("sitemap.DiskFrontier.Pop: %s", err)
+	}
+	return t, true, nil
+}
+
+// Len - returns number of queued targets.
+func (f *DiskFrontier) Len() int {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+	return f.num
+}
+
+// Range - calls f for every queued target in order until f returns false.
+// Frontier must not be changed by fn.
+func (f *DiskFrontier) Range(fn func(Target) bool) error {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+	if err := f.w.Flush(); err != nil {
+		return fmt.Errorf("sitemap.DiskFrontier.Range: %s", err)
+	}
+	head := f.tail.pos - int64(f.r.Buffered())
+	return scanRecords(io.NewSectionReader(f.file, head, f.size-head), func(line []byte) (bool, error) {
+		t, err := decodeTarget(line)
+		if err != nil {
+			return false, fmt.Errorf("sitemap.DiskFrontier.Range: %s", err)
+		}
+		return fn(t), nil
+	})
+}
+
+// snapshot - returns position of queued targets inside file, the file is pinned since then.
+func (f *DiskFrontier) snapshot() (storeSnapshot, error) {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+	if err := f.w.Flush(); err != nil {
+		return storeSnapshot{}, fmt.Errorf("sitemap.DiskFrontier.snapshot: %s", err)
+	}
+	f.pinned = true
+	head := f.tail.pos - int64(f.r.Buffered())
+	return storeSnapshot{log: &LogPosition{f.filename, head, f.size}}, nil
+}
+
+// origin - returns position from which frontier is opened, see OpenDiskFrontier.
+func (f *DiskFrontier) origin() *LogPosition {
+	return f.opened
+}
+
+// Close - closes underlying file, the file is removed unless checkpoint refers to it.
+func (f *DiskFrontier) Close() error {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	if f.pinned {
+		return nil
+	}
+	return os.Remove(f.filename)
+}
+
+// decodeTarget - decodes single record of frontier file.
+func decodeTarget(line []byte) (Target, error) {
+	record := CheckpointTarget{}
+	if err := json.Unmarshal(line, &record); err != nil {
+		return Target{}, err
+	}
+	uri, err := NewURI(record.URI)
+	if err != nil {
+		return Target{}, err
+	}
+	return Target{uri, record.Level}, nil
+}
+
+// DiskVisitedStore - VisitedStore which keeps items inside append-only file
+// and uses bloom filter to check item is stored, so memory usage is bounded by filter size.
+// Positives of bloom filter are confirmed with records of file: records of the same hash bucket
+// are linked into list, only offsets of list heads are kept in memory.
+type DiskVisitedStore struct {
+	mx       sync.Mutex
+	filename string
+	file     *os.File
+	w        *bufio.Writer
+	r        *bufio.Reader // reads records of bucket lists
+	filter   *bloomFilter
+	heads    []int64 // offset+1 of the last record of every bucket
+	size     int64   // num of bytes written
+	num      int
+	pinned   bool         // checkpoint refers to the file, so it is not removed
+	opened   *LogPosition // position from which store is opened
+}
+
+// visitedRecord - record of DiskVisitedStore file, `Prev` is offset+1 of previous record of the same bucket.
+type visitedRecord struct {
+	CheckpointItem
+	Prev int64 `json:"prev,omitempty"`
+}
+
+// newVisitedIndex - returns list heads of index for expected number of items,
+// there are about 4 records per bucket when store is full.
+func newVisitedIndex(capacity uint) []int64 {
+	buckets := capacity / 4
+	if buckets == 0 {
+		buckets = 1
+	}
+	return make([]int64, buckets)
+}
+
+// NewDiskVisitedStore - creates empty store inside given file. Existing file is truncated.
+// Argument `capacity` is expected number of items and `fpRate` is acceptable false positive rate of bloom filter.
+func NewDiskVisitedStore(filename string, capacity uint, fpRate float64) (*DiskVisitedStore, error) {
+	if fpRate <= 0 || fpRate >= 1 {
+		return nil, fmt.Errorf("sitemap.NewDiskVisitedStore: invalid false positive rate %v", fpRate)
+	}
+	file, err := os.OpenFile(filename, os.O_CREATE|os.O_TRUNC|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("sitemap.NewDiskVisitedStore: %s", err)
+	}
+	return &DiskVisitedStore{
+		filename: filename,
+		file:     file,
+		w:        bufio.NewWriter(file),
+		r:        bufio.NewReaderSize(nil, 512),
+		filter:   newBloomFilter(capacity, fpRate),
+		heads:    newVisitedIndex(capacity),
+	}, nil
+}
+
+// OpenDiskVisitedStore - opens store inside file from position recorded by checkpoint, see Parser.Resume.
+// Items which were added after checkpoint was taken are discarded.
+func OpenDiskVisitedStore(pos LogPosition, capacity uint, fpRate float64) (*DiskVisitedStore, error) {
+	if fpRate <= 0 || fpRate >= 1 {
+		return nil, fmt.Errorf("sitemap.OpenDiskVisitedStore: invalid false positive rate %v", fpRate)
+	}
+	if pos.Offset != 0 {
+		return nil, fmt.Errorf("sitemap.OpenDiskVisitedStore: invalid offset %d", pos.Offset)
+	}
+	file, err := openLog(pos)
+	if err != nil {
+		return nil, fmt.Errorf("sitemap.OpenDiskVisitedStore: %s", err)
+	}
+	s := &DiskVisitedStore{
+		filename: pos.File,
+		file:     file,
+		w:        bufio.NewWriter(file),
+		r:        bufio.NewReaderSize(nil, 512),
+		filter:   newBloomFilter(capacity, fpRate),
+		heads:    newVisitedIndex(capacity),
+		size:     pos.Size,
+		pinned:   true,
+		opened:   &pos,
+	}
+	offset := int64(0)
+	if err := scanRecords(io.NewSectionReader(file, 0, pos.Size), func(line []byte) (bool, error) {
+		record := CheckpointItem{}
+		if err := json.Unmarshal(line, &record); err != nil {
+			return false, err
+		}
+		s.filter.add(record.URI)
+		s.heads[s.bucket(record.URI)] = offset + 1
+		offset += int64(len(line)) + 1
+		s.num++
+		return true, nil
+	}); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("sitemap.OpenDiskVisitedStore: %s", err)
+	}
+	return s, nil
+}
+
+// Add - stores item, returns false if item with the same URI is already stored.
+func (s *DiskVisitedStore) Add(item MapItem) (bool, error) {
+	uri := item.URI.String()
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	stored, err := s.stored(uri)
+	if err != nil {
+		return false, fmt.Errorf("sitemap.DiskVisitedStore.Add: %s", err)
+	}
+	if stored {
+		return false, nil
+	}
+	b := s.bucket(uri)
+	record, err := json.Marshal(visitedRecord{CheckpointItem{uri, item.DocumentMeta}, s.heads[b]})
+	if err != nil {
+		return false, fmt.Errorf("sitemap.DiskVisitedStore.Add: %s", err)
+	}
+	offset := s.size
+	n, err := s.w.Write(append(record, '\n'))
+	s.size += int64(n)
+	if err != nil {
+		return false, fmt.Errorf("sitemap.DiskVisitedStore.Add: %s", err)
+	}
+	s.heads[b] = offset + 1
+	s.filter.add(uri)
+	s.num++
+	return true, nil
+}
+
+// Has - checks item with given URI is stored.
+// If file can not be read, positive result of bloom filter is returned.
+func (s *DiskVisitedStore) Has(uri string) bool {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	stored, err := s.stored(uri)
+	return stored || err != nil
+}
+
+// bucket - returns index bucket of URI.
+func (s *DiskVisitedStore) bucket(uri string) int {
+	h, _ := s.filter.hashes(uri)
+	return int(h % uint64(len(s.heads)))
+}
+
+// stored - checks item with given URI is stored, positive result of bloom filter is confirmed
+// by records of URI bucket. Caller must hold the lock.
+func (s *DiskVisitedStore) stored(uri string) (bool, error) {
+	if !s.filter.has(uri) {
+		return false, nil
+	}
+	next := s.heads[s.bucket(uri)]
+	if next > s.size-int64(s.w.Buffered()) {
+		// the last record of bucket is not written yet
+		if err := s.w.Flush(); err != nil {
+			return false, err
+		}
+	}
+	for next > 0 {
+		s.r.Reset(io.NewSectionReader(s.file, next-1, s.size-next+1))
+		line, err := s.r.ReadBytes('\n')
+		if err != nil {
+			return false, err
+		}
+		record := visitedRecord{}
+		if err := json.Unmarshal(line, &record); err != nil {
+			return false, err
+		}
+		if record.URI == uri {
+			return true, nil
+		}
+		next = record.Prev
+	}
+	return false, nil
+}
+
+// Len - returns number of stored items.
+func (s *DiskVisitedStore) Len() int {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	return s.num
+}
+
+// Range - calls f for every stored item until f returns false.
+// Items added while Range is running are not passed to f.
+func (s *DiskVisitedStore) Range(f func(MapItem) bool) error {
+	s.mx.Lock()
+	err := s.w.Flush()
+	size := s.size
+	s.mx.Unlock()
+	if err != nil {
+		return fmt.Errorf("sitemap.DiskVisitedStore.Range: %s", err)
+	}
+	return scanRecords(io.NewSectionReader(s.file, 0, size), func(line []byte) (bool, error) {
+		record := CheckpointItem{}
+		if err := json.Unmarshal(line, &record); err != nil {
+			return false, fmt.Errorf("sitemap.DiskVisitedStore.Range: %s", err)
+		}
+		uri, err := NewURI(record.URI)
+		if err != nil {
+			return false, fmt.Errorf("sitemap.DiskVisitedStore.Range: %s", err)
+		}
+		return f(MapItem{uri, record.Meta}), nil
+	})
+}
+
+// snapshot - returns position of stored items inside file, the file is pinned since then.
+func (s *DiskVisitedStore) snapshot() (storeSnapshot, error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	if err := s.w.Flush(); err != nil {
+		return storeSnapshot{}, fmt.Errorf("sitemap.DiskVisitedStore.snapshot: %s", err)
+	}
+	s.pinned = true
+	return storeSnapshot{log: &LogPosition{s.filename, 0, s.size}}, nil
+}
+
+// origin - returns position from which store is opened, see OpenDiskVisitedStore.
+func (s *DiskVisitedStore) origin() *LogPosition {
+	return s.opened
+}
+
+// Close - closes underlying file, the file is removed unless checkpoint refers to it.
+func (s *DiskVisitedStore) Close() error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	if err := s.file.Close(); err != nil {
+		return err
+	}
+	if s.pinned {
+		return nil
+	}
+	return os.Remove(s.filename)
+}
+
+// scanRecords - reads newline-separated records and passes them to f until f returns false or error.
+func scanRecords(r io.Reader, f func([]byte) (bool, error)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxRecordSize)
+	for scanner.Scan() {
+		next, err := f(scanner.Bytes())
+		if err != nil {
+			return err
+		}
+		if !next {
+			return nil
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,341 @@
+package sitemap
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDiskFrontier(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitemap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "frontier.log")
+	f, err := NewDiskFrontier(filename)
+	if err != nil {
+		t.Fatal("Unexpected NewDiskFrontier() error:", err)
+	}
+	testFrontier(t, f)
+	if stat, err := os.Stat(filename); err != nil || stat.Size() != 0 {
+		t.Error("Expected empty file for empty frontier:", stat, err)
+	}
+	if err := f.Close(); err != nil {
+		t.Error("Unexpected Close() error:", err)
+	}
+	if _, err := os.Stat(filename); !os.IsNotExist(err) {
+		t.Error("Expected file is removed on Close():", err)
+	}
+}
+
+func TestDiskFrontier_truncated(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitemap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "frontier.log")
+	f, err := NewDiskFrontier(filename)
+	if err != nil {
+		t.Fatal("Unexpected NewDiskFrontier() error:", err)
+	}
+	defer f.Close()
+	for _, u := range []string{"http://localhost/a.html", "http://localhost/b.html"} {
+		uri, _ := NewURI(u)
+		if err := f.Push(Target{uri, 1}); err != nil {
+			t.Fatal("Unexpected Push() error:", err)
+		}
+	}
+	// records are flushed by Range, then the second one is cut
+	if err := f.Range(func(Target) bool { return true }); err != nil {
+		t.Fatal("Unexpected Range() error:", err)
+	}
+	stat, _ := os.Stat(filename)
+	if err := os.Truncate(filename, stat.Size()-5); err != nil {
+		t.Fatal(err)
+	}
+
+	if target, ok, err := f.Pop(); err != nil || !ok || target.URI.String() != "http://localhost/a.html" {
+		t.Fatal("Unexpected first Pop():", target, ok, err)
+	}
+	if _, _, err := f.Pop(); err == nil {
+		t.Error("Expected error of truncated record")
+	}
+	if f.Len() != 0 {
+		t.Error("Expected broken record is dropped, len:", f.Len())
+	}
+	if _, ok, err := f.Pop(); ok || err != nil {
+		t.Error("Expected empty frontier, got", ok, err)
+	}
+}
+
+func TestParser_truncatedFrontier(t *testing.T) {
+	server := httptest.NewServer(http.FileServer(http.Dir("testdata/simplesite")))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "sitemap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "frontier.log")
+	frontier, err := NewDiskFrontier(filename)
+	if err != nil {
+		t.Fatal("Unexpected NewDiskFrontier() error:", err)
+	}
+	defer frontier.Close()
+	page, _ := NewURI(server.URL + "/faq.html")
+	frontier.Push(Target{page, 1})
+	frontier.Range(func(Target) bool { return true })
+	stat, _ := os.Stat(filename)
+	if err := os.Truncate(filename, stat.Size()-5); err != nil {
+		t.Fatal(err)
+	}
+
+	errs := make(chan error, 10)
+	checkpoints := int32(0)
+	parser, err := NewParser(
+		WithFrontier(frontier),
+		WithErrorHandler(func(err error) { errs <- err }),
+		WithCheckpoint(time.Millisecond, func(*Checkpoint) { atomic.AddInt32(&checkpoints, 1) }),
+	)
+	if err != nil {
+		t.Fatal("Unexpected NewParser() error:", err)
+	}
+	root, _ := NewURI(server.URL + "/homepage.html")
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		parser.Parse(root, 1, 2)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Crawl is not stopped by broken frontier")
+	}
+	close(errs)
+	classes := []ErrorClass{}
+	for err := range errs {
+		classes = append(classes, classify(err))
+	}
+	if len(classes) != 1 || classes[0] != ErrorStore {
+		t.Error("Expected single store error, got", classes)
+	}
+	if atomic.LoadInt32(&checkpoints) == 0 {
+		t.Error("Expected final checkpoint")
+	}
+}
+
+func TestDiskVisitedStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitemap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if _, err := NewDiskVisitedStore(filepath.Join(dir, "invalid.log"), 10, 0); err == nil {
+		t.Error("Expected error for zero false positive rate")
+	}
+	s, err := NewDiskVisitedStore(filepath.Join(dir, "visited.log"), 10, 0.0001)
+	if err != nil {
+		t.Fatal("Unexpected NewDiskVisitedStore() error:", err)
+	}
+	defer s.Close()
+	testVisitedStore(t, s)
+}
+
+// saturate - sets all bits of bloom filter, so it reports positive for any value.
+func saturate(b *bloomFilter) {
+	for i := range b.bits {
+		b.bits[i] = ^uint64(0)
+	}
+}
+
+func TestDiskVisitedStore_falsePositive(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitemap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s, err := NewDiskVisitedStore(filepath.Join(dir, "visited.log"), 4, 0.0001)
+	if err != nil {
+		t.Fatal("Unexpected NewDiskVisitedStore() error:", err)
+	}
+	defer s.Close()
+	saturate(s.filter)
+	testVisitedStore(t, s)
+
+	cp, err := s.snapshot()
+	if err != nil {
+		t.Fatal("Unexpected snapshot() error:", err)
+	}
+	opened, err := OpenDiskVisitedStore(*cp.log, 4, 0.0001)
+	if err != nil {
+		t.Fatal("Unexpected OpenDiskVisitedStore() error:", err)
+	}
+	defer opened.Close()
+	saturate(opened.filter)
+	if !opened.Has("http://localhost/9.html") || opened.Has("http://localhost/10.html") {
+		t.Error("Unexpected Has() results of opened store")
+	}
+}
+
+func Test_bloomFilter(t *testing.T) {
+	const n = 10000
+	b := newBloomFilter(n, 0.01)
+	for i := 0; i < n; i++ {
+		b.add(fmt.Sprintf("http://localhost/%d.html", i))
+	}
+	for i := 0; i < n; i++ {
+		if !b.has(fmt.Sprintf("http://localhost/%d.html", i)) {
+			t.Fatal("False negative for", i)
+		}
+	}
+	positives := 0
+	for i := n; i < 2*n; i++ {
+		if b.has(fmt.Sprintf("http://localhost/%d.html", i)) {
+			positives++
+		}
+	}
+	if rate := float64(positives) / n; rate > 0.02 {
+		t.Error("Unexpected false positive rate:", rate)
+	}
+}
+
+func TestParser_diskStores(t *testing.T) {
+	server := httptest.NewServer(http.FileServer(http.Dir("testdata/simplesite")))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "sitemap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	frontier, err := NewDiskFrontier(filepath.Join(dir, "frontier.log"))
+	if err != nil {
+		t.Fatal("Unexpected NewDiskFrontier() error:", err)
+	}
+	defer frontier.Close()
+	visited, err := NewDiskVisitedStore(filepath.Join(dir, "visited.log"), 100, 0.0001)
+	if err != nil {
+		t.Fatal("Unexpected NewDiskVisitedStore() error:", err)
+	}
+	defer visited.Close()
+
+	// every check of bloom filter is positive, so all of them must be confirmed
+	saturate(visited.filter)
+
+	parser, err := NewParser(WithFrontier(frontier), WithVisitedStore(visited))
+	if err != nil {
+		t.Fatal("Unexpected NewParser() error:", err)
+	}
+	root, _ := NewURI(server.URL + "/homepage.html")
+	found := []string{}
+	for _, item := range parser.Parse(root, 1, 2) {
+		found = append(found, strings.TrimPrefix(item.URI.String(), server.URL))
+	}
+	sort.Strings(found)
+	expected := "/faq.html /homepage.html /protocol.html /terms.html"
+	if actual := strings.Join(found, " "); actual != expected {
+		t.Error("Expected:", expected, "actual:", actual)
+	}
+}
+
+func TestParser_resumeDiskStores(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	slow := int32(0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow.html" && atomic.AddInt32(&slow, 1) == 1 {
+			cancel()
+			<-r.Context().Done()
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><body><a href="/slow.html">slow</a><a href="/a.html">a</a></body></html>`))
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "sitemap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	frontier, err := NewDiskFrontier(filepath.Join(dir, "frontier.log"))
+	if err != nil {
+		t.Fatal("Unexpected NewDiskFrontier() error:", err)
+	}
+	visited, err := NewDiskVisitedStore(filepath.Join(dir, "visited.log"), 100, 0.0001)
+	if err != nil {
+		t.Fatal("Unexpected NewDiskVisitedStore() error:", err)
+	}
+	var cp *Checkpoint
+	parser, err := NewParser(
+		WithFrontier(frontier),
+		WithVisitedStore(visited),
+		WithCheckpoint(time.Hour, func(c *Checkpoint) { cp = c }),
+	)
+	if err != nil {
+		t.Fatal("Unexpected NewParser() error:", err)
+	}
+	root, _ := NewURI(server.URL + "/")
+	for range parser.Stream(ctx, root, 1, 1) {
+	}
+	frontier.Close()
+	visited.Close()
+	if cp == nil || cp.FrontierLog == nil || cp.VisitedLog == nil {
+		t.Fatal("Expected checkpoint with positions of disk stores:", cp)
+	}
+	if len(cp.Visited) != 0 {
+		t.Error("Visited items are copied into checkpoint:", cp.Visited)
+	}
+
+	if _, err := parser.Resume(context.Background(), cp, 1); err == nil {
+		t.Error("Expected error when stores are not opened from checkpoint")
+	}
+	frontier, err = OpenDiskFrontier(*cp.FrontierLog)
+	if err != nil {
+		t.Fatal("Unexpected OpenDiskFrontier() error:", err)
+	}
+	defer frontier.Close()
+	visited, err = OpenDiskVisitedStore(*cp.VisitedLog, 100, 0.0001)
+	if err != nil {
+		t.Fatal("Unexpected OpenDiskVisitedStore() error:", err)
+	}
+	defer visited.Close()
+	parser, err = NewParser(WithFrontier(frontier), WithVisitedStore(visited))
+	if err != nil {
+		t.Fatal("Unexpected NewParser() error:", err)
+	}
+	items, err := parser.Resume(context.Background(), cp, 1)
+	if err != nil {
+		t.Fatal("Unexpected Resume() error:", err)
+	}
+	found := []string{}
+	for item := range items {
+		found = append(found, strings.TrimPrefix(item.URI.String(), server.URL))
+	}
+	sort.Strings(found)
+	expected := "/ /a.html /slow.html"
+	if actual := strings.Join(found, " "); actual != expected {
+		t.Error("Expected:", expected, "actual:", actual)
+	}
+
+	if _, err := OpenDiskFrontier(LogPosition{cp.FrontierLog.File, 0, 1 << 20}); err == nil {
+		t.Error("Expected error for position beyond end of file")
+	}
+}
@@ -0,0 +1,58 @@
+package sitemap
+
+// ErrorClass - category of errors occurred while crawling.
+type ErrorClass string
+
+const (
+	// ErrorRequest - request can not be made or response is not received (network errors, timeouts, etc.)
+	ErrorRequest ErrorClass = "request"
+	// ErrorStatus - response has unexpected status code
+	ErrorStatus ErrorClass = "status"
+	// ErrorContentType - response has unsupported content type
+	ErrorContentType ErrorClass = "content-type"
+	// ErrorParse - response body can not be decoded or parsed
+	ErrorParse ErrorClass = "parse"
+	// ErrorBodyLimit - response body is larger than allowed, see WithMaxBodyBytes
+	ErrorBodyLimit ErrorClass = "body-limit"
+	// ErrorNodeLimit - document contains more nodes than allowed, see WithMaxDocumentNodes
+	ErrorNodeLimit ErrorClass = "node-limit"
+	// ErrorMetadata - document metadata can not be parsed, this error is not fatal for document
+	ErrorMetadata ErrorClass = "metadata"
+	// ErrorLogin - login form is rejected or document is redirected to login page, crawl is aborted
+	ErrorLogin ErrorClass = "login"
+	// ErrorStore - frontier or visited store failed
+	ErrorStore ErrorClass = "store"
+	// ErrorOther - any other error
+	ErrorOther ErrorClass = "other"
+)
+
+// CrawlError - error occurred while target was processed.
+type CrawlError struct {
+	// URI - target URI, may be empty if error is not related to single target
+	URI string
+	// Class - category of error
+	Class ErrorClass
+	// Err - underlying error
+	Err error
+}
+
+// Error - returns message of underlying error.
+func (e *CrawlError) Error() string {
+	if e.Err == nil {
+		return string(e.Class)
+	}
+	return e.Err.Error()
+}
+
+// Unwrap - returns underlying error.
+func (e *CrawlError) Unwrap() error {
+	return e.Err
+}
+
+// classify - returns category of error.
+func classify(err error) ErrorClass {
+	if e, ok := err.(*CrawlError); ok {
+		return e.Class
+	}
+	return ErrorOther
+}
@@ -0,0 +1,207 @@
+package sitemap
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Frontier - queue of targets which are waiting to be processed.
+// Implementations must be safe for concurrent use.
+type Frontier interface {
+	// Push - appends target to the tail of queue.
+	Push(t Target) error
+	// Pop - removes target from the head of queue, returns false if queue is empty.
+	// Target which can not be read is removed too, error of Pop stops the crawl.
+	Pop() (Target, bool, error)
+	// Len - returns number of queued targets.
+	Len() int
+	// Range - calls f for every queued target in order until f returns false.
+	Range(f func(Target) bool) error
+}
+
+// VisitedStore - storage of processed targets.
+// Implementations must be safe for concurrent use.
+type VisitedStore interface {
+	// Add - stores item, returns false if item with the same URI is already stored.
+	Add(item MapItem) (bool, error)
+	// Has - checks item with given URI is stored.
+	Has(uri string) bool
+	// Len - returns number of stored items.
+	Len() int
+	// Range - calls f for every stored item until f returns false.
+	Range(f func(MapItem) bool) error
+}
+
+// snapshotter - optional interface of Frontier and VisitedStore, which takes snapshot of content
+// without copying it, so workers are not paused while checkpoint is serialized.
+type snapshotter interface {
+	snapshot() (storeSnapshot, error)
+}
+
+// storeSnapshot - content of Frontier or VisitedStore at the moment of checkpoint,
+// it must not be changed by store after snapshot is taken.
+// Disk stores return position of their file instead of content.
+type storeSnapshot struct {
+	targets []Target
+	items   []MapItem
+	log     *LogPosition
+}
+
+// logStore - optional interface of Frontier and VisitedStore, which are opened from position of checkpoint.
+type logStore interface {
+	origin() *LogPosition
+}
+
+// openedFrom - checks store is opened from given position of checkpoint.
+func openedFrom(store interface{}, log *LogPosition) bool {
+	s, ok := store.(logStore)
+	return ok && s.origin() != nil && *s.origin() == *log
+}
+
+// snapshotFrontier - returns snapshot of queued targets, frontier without snapshots support is copied.
+func snapshotFrontier(f Frontier) (storeSnapshot, error) {
+	if s, ok := f.(snapshotter); ok {
+		return s.snapshot()
+	}
+	snapshot := storeSnapshot{}
+	err := f.Range(func(t Target) bool {
+		snapshot.targets = append(snapshot.targets, t)
+		return true
+	})
+	return snapshot, err
+}
+
+// snapshotVisited - returns snapshot of stored items, store without snapshots support is copied.
+func snapshotVisited(s VisitedStore) (storeSnapshot, error) {
+	if s, ok := s.(snapshotter); ok {
+		return s.snapshot()
+	}
+	snapshot := storeSnapshot{}
+	err := s.Range(func(item MapItem) bool {
+		snapshot.items = append(snapshot.items, item)
+		return true
+	})
+	return snapshot, err
+}
+
+// MemoryFrontier - Frontier which keeps all targets in memory.
+type MemoryFrontier struct {
+	mx      sync.Mutex
+	targets []Target
+	shared  bool // targets are referenced by snapshot
+}
+
+// NewMemoryFrontier - creates empty in-memory frontier, it is default Frontier of Parser.
+func NewMemoryFrontier() *MemoryFrontier {
+	return &MemoryFrontier{targets: []Target{}}
+}
+
+// Push - appends target to the tail of queue.
+func (f *MemoryFrontier) Push(t Target) error {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+	if len(f.targets) == cap(f.targets) {
+		// append allocates new array, snapshot keeps the old one
+		f.shared = false
+	}
+	f.targets = append(f.targets, t)
+	return nil
+}
+
+// Pop - removes target from the head of queue, returns false if queue is empty.
+func (f *MemoryFrontier) Pop() (Target, bool, error) {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+	if len(f.targets) == 0 {
+		return Target{}, false, nil
+	}
+	t := f.targets[0]
+	if !f.shared {
+		f.targets[0] = Target{} // release URI
+	}
+	f.targets = f.targets[1:]
+	return t, true, nil
+}
+
+// Len - returns number of queued targets.
+func (f *MemoryFrontier) Len() int {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+	return len(f.targets)
+}
+
+// Range - calls f for every queued target in order until f returns false.
+func (f *MemoryFrontier) Range(fn func(Target) bool) error {
+	f.mx.Lock()
+	targets := append([]Target{}, f.targets...)
+	f.mx.Unlock()
+	for _, t := range targets {
+		if !fn(t) {
+			break
+		}
+	}
+	return nil
+}
+
+// snapshot - returns queued targets without copying, array of queue is shared with snapshot until queue grows.
+func (f *MemoryFrontier) snapshot() (storeSnapshot, error) {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+	f.shared = true
+	return storeSnapshot{targets: f.targets[:len(f.targets):len(f.targets)]}, nil
+}
+
+// MemoryVisitedStore - VisitedStore which keeps all items in memory.
+type MemoryVisitedStore struct {
+	items sync.Map
+	num   int64 // atomic
+
+	mx    sync.Mutex // protects order
+	order []MapItem  // items in order they were added, append-only
+}
+
+// NewMemoryVisitedStore - creates empty in-memory store, it is default VisitedStore of Parser.
+func NewMemoryVisitedStore() *MemoryVisitedStore {
+	return &MemoryVisitedStore{}
+}
+
+// Add - stores item, returns false if item with the same URI is already stored.
+func (s *MemoryVisitedStore) Add(item MapItem) (bool, error) {
+	if _, loaded := s.items.LoadOrStore(item.URI.String(), item); loaded {
+		return false, nil
+	}
+	s.mx.Lock()
+	s.order = append(s.order, item)
+	s.mx.Unlock()
+	atomic.AddInt64(&s.num, 1)
+	return true, nil
+}
+
+// Has - checks item with given URI is stored.
+func (s *MemoryVisitedStore) Has(uri string) bool {
+	_, ok := s.items.Load(uri)
+	return ok
+}
+
+// Len - returns number of stored items.
+func (s *MemoryVisitedStore) Len() int {
+	return int(atomic.LoadInt64(&s.num))
+}
+
+// snapshot - returns stored items without copying, list of items is append-only.
+func (s *MemoryVisitedStore) snapshot() (storeSnapshot, error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	return storeSnapshot{items: s.order[:len(s.order):len(s.order)]}, nil
+}
+
+// Range - calls f for every stored item until f returns false.
+func (s *MemoryVisitedStore) Range(f func(MapItem) bool) error {
+	s.items.Range(func(_ interface{}, value interface{}) bool {
+		if item, ok := value.(MapItem); ok {
+			return f(item)
+		}
+		return true
+	})
+	return nil
+}
@@ -0,0 +1,133 @@
+package sitemap
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// testFrontier - checks FIFO order, Len and Range of any Frontier implementation.
+func testFrontier(t *testing.T, f Frontier) {
+	t.Helper()
+	target := func(i int) Target {
+		u, _ := NewURI(fmt.Sprintf("http://localhost/%d.html", i))
+		return Target{u, uint(i)}
+	}
+	if _, ok, err := f.Pop(); ok || err != nil {
+		t.Fatal("Unexpected Pop() result for empty frontier:", ok, err)
+	}
+	for round := 0; round < 2; round++ {
+		for i := 0; i < 5; i++ {
+			if err := f.Push(target(i)); err != nil {
+				t.Fatal("Unexpected Push() error:", err)
+			}
+		}
+		if f.Len() != 5 {
+			t.Error("Unexpected Len():", f.Len())
+		}
+		if first, ok, err := f.Pop(); !ok || err != nil || first.URI.String() != target(0).URI.String() {
+			t.Fatal("Unexpected Pop() result:", first, ok, err)
+		}
+		queued := []string{}
+		if err := f.Range(func(t Target) bool {
+			queued = append(queued, fmt.Sprintf("%s %d", t.URI, t.Level))
+			return true
+		}); err != nil {
+			t.Fatal("Unexpected Range() error:", err)
+		}
+		expected := []string{}
+		for i := 1; i < 5; i++ {
+			expected = append(expected, fmt.Sprintf("%s %d", target(i).URI, i))
+		}
+		if !reflect.DeepEqual(expected, queued) {
+			t.Error("Expected:", expected, "actual:", queued)
+		}
+		for i := 1; i < 5; i++ {
+			next, ok, err := f.Pop()
+			if !ok || err != nil || next.URI.String() != target(i).URI.String() || next.Level != uint(i) {
+				t.Fatal("Unexpected Pop() result:", next, ok, err)
+			}
+		}
+		if _, ok, err := f.Pop(); ok || err != nil || f.Len() != 0 {
+			t.Fatal("Expected empty frontier:", ok, err, f.Len())
+		}
+	}
+}
+
+// testVisitedStore - checks Add, Has, Len and Range of any VisitedStore implementation.
+func testVisitedStore(t *testing.T, s VisitedStore) {
+	t.Helper()
+	uri := func(i int) *URI {
+		u, _ := NewURI(fmt.Sprintf("http://localhost/%d.html", i))
+		return u
+	}
+	for i := 0; i < 10; i++ {
+		if added, err := s.Add(MapItem{uri(i), &DocumentMeta{ETag: fmt.Sprint(i)}}); !added || err != nil {
+			t.Fatal("Unexpected Add() result:", added, err)
+		}
+	}
+	if added, err := s.Add(MapItem{uri(3), nil}); added || err != nil {
+		t.Error("Unexpected Add() result for duplicate:", added, err)
+	}
+	if s.Len() != 10 {
+		t.Error("Unexpected Len():", s.Len())
+	}
+	if !s.Has(uri(9).String()) || s.Has(uri(10).String()) {
+		t.Error("Unexpected Has() results")
+	}
+	actual := []string{}
+	if err := s.Range(func(item MapItem) bool {
+		actual = append(actual, item.URI.String()+" "+item.ETag)
+		return true
+	}); err != nil {
+		t.Fatal("Unexpected Range() error:", err)
+	}
+	sort.Strings(actual)
+	expected := []string{}
+	for i := 0; i < 10; i++ {
+		expected = append(expected, uri(i).String()+" "+fmt.Sprint(i))
+	}
+	sort.Strings(expected)
+	if !reflect.DeepEqual(expected, actual) {
+		t.Error("Expected:", expected, "actual:", actual)
+	}
+}
+
+func TestMemoryFrontier(t *testing.T) {
+	testFrontier(t, NewMemoryFrontier())
+}
+
+func TestMemoryVisitedStore(t *testing.T) {
+	testVisitedStore(t, NewMemoryVisitedStore())
+}
+
+func TestMemoryStores_snapshot(t *testing.T) {
+	target := func(i int) Target {
+		u, _ := NewURI(fmt.Sprintf("http://localhost/%d.html", i))
+		return Target{u, uint(i)}
+	}
+	f := NewMemoryFrontier()
+	s := NewMemoryVisitedStore()
+	for i := 0; i < 3; i++ {
+		f.Push(target(i))
+		s.Add(MapItem{target(i).URI, nil})
+	}
+	queued, _ := f.snapshot()
+	stored, _ := s.snapshot()
+	// snapshots must not be affected by changes of stores
+	f.Pop()
+	f.Pop()
+	for i := 3; i < 10; i++ {
+		f.Push(target(i))
+		s.Add(MapItem{target(i).URI, nil})
+	}
+	if len(queued.targets) != 3 || len(stored.items) != 3 {
+		t.Fatal("Unexpected snapshot size:", len(queued.targets), len(stored.items))
+	}
+	for i := 0; i < 3; i++ {
+		if queued.targets[i].URI.String() != target(i).URI.String() || stored.items[i].URI.String() != target(i).URI.String() {
+			t.Errorf("Unexpected snapshot entry #%d: %v, %v", i, queued.targets[i], stored.items[i])
+		}
+	}
+}
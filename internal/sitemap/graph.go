@@ -0,0 +1,95 @@
+package sitemap
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// GraphNode - document of link graph.
+type GraphNode struct {
+	// URI - document URI
+	URI string
+	// Level - minimum level at which document was discovered, 0 for root
+	Level uint
+}
+
+// GraphEdge - link from one document to another.
+type GraphEdge struct {
+	Source string
+	Target string
+}
+
+// LinkGraph - thread-safe graph of in-scope links between site documents.
+// Links to out of scope documents and links of document to itself are not retained.
+type LinkGraph struct {
+	mx    sync.Mutex
+	nodes map[string]uint
+	edges map[GraphEdge]bool
+}
+
+// NewLinkGraph - builds empty graph.
+func NewLinkGraph() *LinkGraph {
+	return &LinkGraph{
+		nodes: map[string]uint{},
+		edges: map[GraphEdge]bool{},
+	}
+}
+
+// WithLinkGraph - retain edge list of crawled documents in given graph.
+func WithLinkGraph(g *LinkGraph) ParserOption {
+	if g == nil {
+		return failedOption(fmt.Errorf("Invalid link graph (nil)"))
+	}
+	return WithHooks(Hooks{OnLinks: g.add})
+}
+
+// add - adds outgoing in-scope links of document.
+func (g *LinkGraph) add(e LinkEvent) {
+	g.mx.Lock()
+	defer g.mx.Unlock()
+	g.node(e.Source, e.Level)
+	for _, l := range e.InScope {
+		if l == e.Source {
+			continue
+		}
+		g.node(l, e.Level+1)
+		g.edges[GraphEdge{e.Source, l}] = true
+	}
+}
+
+// node - adds node or lowers level of existing one.
+func (g *LinkGraph) node(uri string, level uint) {
+	if known, ok := g.nodes[uri]; !ok || level < known {
+		g.nodes[uri] = level
+	}
+}
+
+// Nodes - returns all documents of graph sorted by URI.
+func (g *LinkGraph) Nodes() []GraphNode {
+	g.mx.Lock()
+	defer g.mx.Unlock()
+	nodes := make([]GraphNode, 0, len(g.nodes))
+	for uri, level := range g.nodes {
+		nodes = append(nodes, GraphNode{uri, level})
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].URI < nodes[j].URI })
+	return nodes
+}
+
+// Edges - returns all links of graph sorted by source and target.
+func (g *LinkGraph) Edges() []GraphEdge {
+	g.mx.Lock()
+	defer g.mx.Unlock()
+	edges := make([]GraphEdge, 0, len(g.edges))
+	for e := range g.edges {
+		edges = append(edges, e)
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].Source != edges[j].Source {
+			return edges[i].Source < edges[j].Source
+		}
+		return edges[i].Target < edges[j].Target
+	})
+	return edges
+}
@@ -0,0 +1,64 @@
+package sitemap
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestWithLinkGraph(t *testing.T) {
+	if _, err := NewParser(WithLinkGraph(nil)); err == nil {
+		t.Error("Expected error for nil graph")
+	}
+}
+
+func TestLinkGraph(t *testing.T) {
+	server := httptest.NewServer(http.FileServer(http.Dir("testdata/simplesite")))
+	defer server.Close()
+
+	graph := NewLinkGraph()
+	parser, err := NewParser(WithLinkGraph(graph))
+	if err != nil {
+		t.Fatal("Unexpected NewParser() error:", err)
+	}
+	root, _ := NewURI(server.URL + "/homepage.html")
+	parser.Parse(root, 1, 2)
+
+	expectedNodes := []GraphNode{
+		{server.URL + "/faq.html", 1},
+		{server.URL + "/homepage.html", 0},
+		{server.URL + "/protocol.html", 1},
+		{server.URL + "/terms.html", 1},
+	}
+	if nodes := graph.Nodes(); !reflect.DeepEqual(nodes, expectedNodes) {
+		t.Errorf("Unexpected nodes: %v", nodes)
+	}
+	// self-link "#top" and out of scope ftp link are not retained
+	expectedEdges := []GraphEdge{
+		{server.URL + "/homepage.html", server.URL + "/faq.html"},
+		{server.URL + "/homepage.html", server.URL + "/protocol.html"},
+		{server.URL + "/homepage.html", server.URL + "/terms.html"},
+	}
+	if edges := graph.Edges(); !reflect.DeepEqual(edges, expectedEdges) {
+		t.Errorf("Unexpected edges: %v", edges)
+	}
+}
+
+func TestLinkGraph_add(t *testing.T) {
+	graph := NewLinkGraph()
+	graph.add(LinkEvent{Source: "http://localhost/a/", Level: 2, InScope: []string{"http://localhost/a/b"}})
+	graph.add(LinkEvent{Source: "http://localhost/", Level: 0, InScope: []string{"http://localhost/a/b"}})
+
+	expected := []GraphNode{
+		{"http://localhost/", 0},
+		{"http://localhost/a/", 2},
+		{"http://localhost/a/b", 1},
+	}
+	if nodes := graph.Nodes(); !reflect.DeepEqual(nodes, expected) {
+		t.Errorf("Unexpected nodes: %v", nodes)
+	}
+	if edges := graph.Edges(); len(edges) != 2 {
+		t.Errorf("Unexpected edges: %v", edges)
+	}
+}
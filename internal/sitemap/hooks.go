@@ -0,0 +1,73 @@
+package sitemap
+
+import "time"
+
+// FetchEvent - describes completed request of document.
+type FetchEvent struct {
+	// URI - requested URI
+	URI string
+	// StatusCode - status code of response, 0 if response was not received
+	StatusCode int
+	// ContentType - value of Content-Type header of response
+	ContentType string
+	// Duration - time spent to fetch and parse document
+	Duration time.Duration
+	// Size - num of downloaded bytes of document body
+	Size int64
+	// Err - error occurred while document was fetched, if any
+	Err error
+	// Redirects - URIs to which request was redirected in order they were followed,
+	// the last one is the final URI of response; empty if there was no redirect
+	Redirects []string
+}
+
+// LinkEvent - describes outgoing links of processed document.
+type LinkEvent struct {
+	// Source - URI of document which contains links
+	Source string
+	// Level - level of document, 0 for root
+	Level uint
+	// Links - absolute links found in document, in order of appearance
+	Links []string
+	// InScope - links which are allowed to crawl, subset of Links
+	InScope []string
+	// Followed - in-scope links are queued to crawl, false when document is at max depth
+	Followed bool
+}
+
+// Hooks - instrumentation callbacks of Parser, every callback is optional.
+// Callbacks are called synchronously from parser goroutines,
+// so they must be safe for concurrent use and should return quickly.
+type Hooks struct {
+	// OnFetch - called when request of document is completed
+	OnFetch func(FetchEvent)
+	// OnLinks - called when outgoing links of fetched or not modified document are collected
+	OnLinks func(LinkEvent)
+}
+
+// WithHooks - register instrumentation callbacks.
+// Option can be used several times, all registered callbacks are called in order of registration.
+func WithHooks(h Hooks) ParserOption {
+	return func(p *Parser) error {
+		p.hooks = append(p.hooks, h)
+		return nil
+	}
+}
+
+// onFetch - calls all registered OnFetch callbacks.
+func (p *Parser) onFetch(e FetchEvent) {
+	for _, h := range p.hooks {
+		if h.OnFetch != nil {
+			h.OnFetch(e)
+		}
+	}
+}
+
+// onLinks - calls all registered OnLinks callbacks.
+func (p *Parser) onLinks(e LinkEvent) {
+	for _, h := range p.hooks {
+		if h.OnLinks != nil {
+			h.OnLinks(e)
+		}
+	}
+}
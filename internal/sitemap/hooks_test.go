@@ -0,0 +1,116 @@
+package sitemap
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestParser_hooks(t *testing.T) {
+	server := httptest.NewServer(http.FileServer(http.Dir("testdata/simplesite")))
+	defer server.Close()
+
+	mx := sync.Mutex{}
+	events := map[string]FetchEvent{}
+	numCalls := 0
+	parser, err := NewParser(
+		WithHooks(Hooks{OnFetch: func(e FetchEvent) {
+			mx.Lock()
+			defer mx.Unlock()
+			events[strings.TrimPrefix(e.URI, server.URL)] = e
+		}}),
+		WithHooks(Hooks{OnFetch: func(e FetchEvent) {
+			mx.Lock()
+			defer mx.Unlock()
+			numCalls++
+		}}),
+		WithHooks(Hooks{}),
+	)
+	if err != nil {
+		t.Fatal("Unexpected NewParser() error:", err)
+	}
+	root, _ := NewURI(server.URL + "/homepage.witherror.html")
+	parser.Parse(root, 1, 2)
+
+	if len(events) != 5 || numCalls != 5 {
+		t.Fatal("Unexpected num of events:", len(events), numCalls)
+	}
+	notFound, ok := events["/notfound.php"]
+	if !ok {
+		t.Fatal("Event for /notfound.php not found")
+	}
+	if notFound.StatusCode != http.StatusNotFound || notFound.Err == nil {
+		t.Error("Unexpected event for not found document:", notFound)
+	}
+	homepage := events["/homepage.witherror.html"]
+	if homepage.StatusCode != http.StatusOK ||
+		!strings.HasPrefix(homepage.ContentType, "text/html") ||
+		homepage.Size == 0 ||
+		homepage.Duration == 0 ||
+		homepage.Err != nil {
+		t.Error("Unexpected event for homepage:", homepage)
+	}
+}
+
+func TestParser_hooksLinks(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/a.html", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><body><a href="old.html"></a><a href="http://example.com/"></a></body></html>`))
+	})
+	mux.HandleFunc("/old.html", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/b.html", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/b.html", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><body><a href="a.html"></a></body></html>`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	mx := sync.Mutex{}
+	fetches := map[string]FetchEvent{}
+	links := map[string]LinkEvent{}
+	parser, err := NewParser(WithHooks(Hooks{
+		OnFetch: func(e FetchEvent) {
+			mx.Lock()
+			defer mx.Unlock()
+			fetches[strings.TrimPrefix(e.URI, server.URL)] = e
+		},
+		OnLinks: func(e LinkEvent) {
+			mx.Lock()
+			defer mx.Unlock()
+			links[strings.TrimPrefix(e.Source, server.URL)] = e
+		},
+	}))
+	if err != nil {
+		t.Fatal("Unexpected NewParser() error:", err)
+	}
+	root, _ := NewURI(server.URL + "/a.html")
+	parser.Parse(root, 1, 2)
+
+	if len(fetches["/a.html"].Redirects) != 0 {
+		t.Error("Unexpected redirects for /a.html:", fetches["/a.html"].Redirects)
+	}
+	if r := fetches["/old.html"].Redirects; len(r) != 1 || r[0] != server.URL+"/b.html" {
+		t.Error("Unexpected redirects for /old.html:", r)
+	}
+
+	a, ok := links["/a.html"]
+	if !ok {
+		t.Fatal("Link event for /a.html not found")
+	}
+	if a.Level != 0 || !a.Followed || len(a.Links) != 2 ||
+		len(a.InScope) != 1 || a.InScope[0] != server.URL+"/old.html" {
+		t.Error("Unexpected link event for /a.html:", a)
+	}
+	old, ok := links["/old.html"]
+	if !ok {
+		t.Fatal("Link event for /old.html not found")
+	}
+	if old.Level != 1 || old.Followed || len(old.InScope) != 1 || old.InScope[0] != server.URL+"/a.html" {
+		t.Error("Unexpected link event for /old.html:", old)
+	}
+}
@@ -1,73 +1,278 @@
 package sitemap
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
-
 	"strings"
 	"time"
 
 	"golang.org/x/net/html"
-	"golang.org/x/net/html/charset"
 )
 
+// maxRedirects - max num of redirects followed for single request, the same as http.Client does by default.
+const maxRedirects = 10
+
 // fetchDocument - build http GET request, fetch response body and parse given HTML into document tree.
-// Only "text/html" content type is fetched.
-func fetchDocument(uri *URI, timeout time.Duration) (*html.Node, *DocumentMeta, error) {
-	var cancel context.CancelFunc
+// Only documents of accepted types (see WithAcceptedTypes) are fetched. Responses of resource types (see WithResourceTypes)
+// are returned as metadata without document.
+// If `cached` state is not nil, the request is made conditional. When server reports the document is not modified,
+// nil document is returned along with metadata marked as NotModified.
+func (p *Parser) fetchDocument(
+	ctx context.Context,
+	uri *URI,
+	cached *PageState,
+) (doc *html.Node, meta *DocumentMeta, err error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	// TODO overwrite timeout if it is 0, for example set it to max allowed timeout
+	if p.requestTimeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, p.requestTimeout)
+		defer cancel()
+	}
+	event := FetchEvent{URI: uri.String()}
+	start := time.Now()
 	defer func() {
-		if cancel != nil {
-			cancel()
+		event.Duration = time.Since(start)
+		event.Err = err
+		if meta != nil {
+			event.Size = meta.Size
 		}
+		p.onFetch(event)
 	}()
-	ctx := context.Background()
-	// TODO overwrite timeout if it is 0, for example set it to max allowed timeout
-	if timeout > 0 {
-		ctx, cancel = context.WithTimeout(ctx, timeout)
-	}
-	// TODO to avoid non text/html responses may to use HEAD first?
 	url := uri.String()
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, nil, fmt.Errorf("preparing request to %q failed: %s", url, err)
+	// resource expected from extension of path is probed without downloading its body,
+	// any other document is probed if HEAD probe is enabled
+	resource, ranged := p.expectResource(uri), false
+	probe := resource || p.headProbe
+	method := "GET"
+	if probe {
+		method = "HEAD"
 	}
-	req.Header.Set("Accept", "text/html")
-	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	resp, err := p.request(ctx, method, uri, cached, "", &event.Redirects)
 	defer func() {
 		if resp != nil {
 			resp.Body.Close()
 		}
 	}()
+	if err == nil && probe &&
+		(resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented) {
+		// server does not support HEAD, only the first byte of resource is requested
+		resp.Body.Close()
+		event.Redirects = nil
+		probe, ranged = resource, resource
+		byteRange := ""
+		if ranged {
+			byteRange = "bytes=0-0"
+		}
+		resp, err = p.request(ctx, "GET", uri, cached, byteRange, &event.Redirects)
+	}
+	if err == nil && probe && isSuccess(resp.StatusCode, ranged) &&
+		p.isDocument(resp.Header.Get("Content-Type")) && !p.exceedsBodyLimit(resp.ContentLength) {
+		// probe allows to fetch document, whole document is requested
+		resp.Body.Close()
+		event.Redirects = nil
+		ranged = false
+		resp, err = p.request(ctx, "GET", uri, cached, "", &event.Redirects)
+	}
 	if err != nil {
-		return nil, nil, fmt.Errorf("request to %s failed: %s", url, err)
+		return nil, nil, err
 	}
-	if resp.StatusCode != http.StatusOK {
-		return nil, nil, fmt.Errorf("cannot fetch %s, status code: %d", url, resp.StatusCode)
+	event.StatusCode = resp.StatusCode
+	event.ContentType = resp.Header.Get("Content-Type")
+	if p.login != nil && len(event.Redirects) > 0 && p.login.isPage(resp.Request.URL) && !p.login.isPage(uri.URL) {
+		return nil, nil, &CrawlError{
+			url,
+			ErrorLogin,
+			fmt.Errorf("%s is redirected to login page %s, session is lost", url, resp.Request.URL),
+		}
+	}
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		return nil, notModifiedMeta(resp.Header, cached), nil
+	}
+	if !isSuccess(resp.StatusCode, ranged) {
+		return nil, nil, &CrawlError{
+			url,
+			ErrorStatus,
+			fmt.Errorf("cannot fetch %s, status code: %d", url, resp.StatusCode),
+		}
 	}
-
-	// TODO Need to check final URI from response: resp.Request.URL.String()
-	// If redirect was occurred we get different URL and we have to deal with this situation.
 
 	ctype := resp.Header.Get("Content-Type")
-	if !strings.Contains(ctype, "text/html") {
-		return nil, nil, fmt.Errorf("%s, invalid content type: %q", url, ctype)
+	if p.isResource(ctype) {
+		// resource is recorded without its content
+		meta = resourceMeta(resp.Header)
+		if len(event.Redirects) > 0 {
+			meta.FinalURL = resp.Request.URL.String()
+		}
+		return nil, meta, nil
+	}
+	if !p.isDocument(ctype) {
+		return nil, nil, &CrawlError{url, ErrorContentType, fmt.Errorf("%s, invalid content type: %q", url, ctype)}
+	}
+	if p.exceedsBodyLimit(resp.ContentLength) {
+		return nil, nil, &CrawlError{
+			url,
+			ErrorBodyLimit,
+			fmt.Errorf("%s, size %d exceeds limit of %d bytes", url, resp.ContentLength, p.maxBodyBytes),
+		}
 	}
 
-	utf8, err := charset.NewReader(resp.Body, ctype)
+	body := &countingReader{r: resp.Body, limit: p.maxBodyBytes}
+	utf8, err := documentReader(body, ctype)
+	if body.exceeded() {
+		return nil, nil, body.limitError(url)
+	}
 	if err != nil {
-		return nil, nil, fmt.Errorf("unable to decode %q: %s", url, err)
+		return nil, nil, &CrawlError{url, ErrorParse, fmt.Errorf("unable to decode %q: %s", url, err)}
 	}
 
-	meta := &DocumentMeta{
-		Modified: modifiedTime(resp.Header),
+	meta = &DocumentMeta{
+		Modified:     modifiedTime(resp.Header),
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		NoIndex:      robotsNoIndex(resp.Header["X-Robots-Tag"]...),
+		headerLinks:  parseLinkHeader(resp.Header["Link"]...),
+	}
+	if len(event.Redirects) > 0 {
+		// relative links of redirected document are resolved against its final URL
+		meta.FinalURL = resp.Request.URL.String()
+	}
+
+	src := utf8
+	if p.maxNodes > 0 {
+		// nodes are counted before parsing, so tree of too large document is not built
+		data, err := ioutil.ReadAll(utf8)
+		if body.exceeded() {
+			return nil, nil, body.limitError(url)
+		}
+		if err != nil {
+			return nil, nil, &CrawlError{url, ErrorParse, fmt.Errorf("unable to read %q: %s", url, err)}
+		}
+		if exceedsNodeLimit(data, p.maxNodes) {
+			return nil, nil, &CrawlError{
+				url,
+				ErrorNodeLimit,
+				fmt.Errorf("%s, num of document nodes exceeds limit of %d", url, p.maxNodes),
+			}
+		}
+		src = bytes.NewReader(data)
 	}
 
-	doc, err := html.Parse(utf8)
+	doc, err = html.Parse(src)
+	meta.Size = body.n
+	if body.exceeded() {
+		return nil, nil, body.limitError(url)
+	}
+	if err != nil {
+		err = &CrawlError{url, ErrorParse, err}
+	}
 
 	return doc, meta, err
 }
 
+// request - makes request of given method, partial content is requested if `byteRange` is not empty.
+// If `cached` state is not nil, the request is made conditional. URIs of followed redirects are appended to `redirects`.
+func (p *Parser) request(
+	ctx context.Context,
+	method string,
+	uri *URI,
+	cached *PageState,
+	byteRange string,
+	redirects *[]string,
+) (*http.Response, error) {
+	url := uri.String()
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return nil, &CrawlError{url, ErrorRequest, fmt.Errorf("preparing request to %q failed: %s", url, err)}
+	}
+	req.Header.Set("Accept", p.accept())
+	for name, values := range p.headers {
+		req.Header[name] = values
+	}
+	if cached != nil {
+		if cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+	if byteRange != "" {
+		req.Header.Set("Range", byteRange)
+	}
+	resp, err := p.httpClient(redirects).Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, &CrawlError{url, ErrorRequest, fmt.Errorf("request to %s failed: %s", url, err)}
+	}
+	return resp, nil
+}
+
+// isSuccess - checks status code of successful response, partial content is expected only for range request.
+func isSuccess(status int, ranged bool) bool {
+	return status == http.StatusOK || ranged && status == http.StatusPartialContent
+}
+
+// countingReader - counts num of bytes read from underlying reader.
+// If limit is positive, reading is failed as soon as num of bytes exceeds limit.
+type countingReader struct {
+	r     io.Reader
+	n     int64
+	limit int64
+}
+
+// errBodyLimit - error of reading beyond limit of countingReader.
+var errBodyLimit = errors.New("body size limit is exceeded")
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	if c.exceeded() {
+		return 0, errBodyLimit
+	}
+	if c.limit > 0 && int64(len(p)) > c.limit-c.n+1 {
+		// read a byte beyond limit to detect body is larger
+		p = p[:c.limit-c.n+1]
+	}
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	if c.exceeded() {
+		return n, errBodyLimit
+	}
+	return n, err
+}
+
+// exceeded - checks more bytes than allowed by limit are read.
+func (c *countingReader) exceeded() bool {
+	return c.limit > 0 && c.n > c.limit
+}
+
+// limitError - returns error of exceeded limit for given URI.
+func (c *countingReader) limitError(uri string) error {
+	return &CrawlError{uri, ErrorBodyLimit, fmt.Errorf("%s, body exceeds limit of %d bytes", uri, c.limit)}
+}
+
+// notModifiedMeta - builds metadata for document which was not changed since previous crawl.
+// Validators, which server sent along 304 response, take precedence over cached ones.
+func notModifiedMeta(headers http.Header, cached *PageState) *DocumentMeta {
+	meta := &DocumentMeta{
+		Modified:     cached.Modified,
+		ETag:         cached.ETag,
+		LastModified: cached.LastModified,
+		Hash:         cached.Hash,
+		NotModified:  true,
+		NoIndex:      cached.NoIndex,
+	}
+	if etag := headers.Get("ETag"); etag != "" {
+		meta.ETag = etag
+	}
+	return meta
+}
+
 func modifiedTime(headers http.Header) time.Time {
 	for _, h := range []string{"Last-Modified", "Date"} {
 		if val := headers.Get(h); val != "" {
@@ -79,6 +284,82 @@ func modifiedTime(headers http.Header) time.Time {
 	return time.Time{}
 }
 
+// contentHash - returns hex-encoded SHA-256 of normalized document content.
+// Comments are ignored, whitespace inside text is collapsed,
+// elements matched by `volatile` selector are skipped with all their descendants.
+func contentHash(doc *html.Node, volatile selector) string {
+	if doc == nil {
+		return ""
+	}
+	hash := sha256.New()
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		switch n.Type {
+		case html.CommentNode:
+			return
+		case html.TextNode:
+			if text := strings.Join(strings.Fields(n.Data), " "); text != "" {
+				fmt.Fprintf(hash, "%q", text)
+			}
+		case html.ElementNode:
+			if volatile.match(n) {
+				return
+			}
+			fmt.Fprintf(hash, "<%s", n.Data)
+			for _, a := range n.Attr {
+				fmt.Fprintf(hash, " %s=%q", a.Key, a.Val)
+			}
+			fmt.Fprint(hash, ">")
+			defer fmt.Fprintf(hash, "</%s>", n.Data)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// stableModified - returns modification time of document.
+// When server does not declare Last-Modified, document modification time falls back to the Date header.
+// In this case modification time of previous crawl is kept if document content is not changed.
+func stableModified(meta *DocumentMeta, cached *PageState) time.Time {
+	if _, err := http.ParseTime(meta.LastModified); err == nil {
+		return meta.Modified
+	}
+	if cached != nil && cached.Hash != "" && cached.Hash == meta.Hash && !cached.Modified.IsZero() {
+		return cached.Modified
+	}
+	return meta.Modified
+}
+
+// robotsNoIndex - checks any of robots directives lists forbids indexing.
+// Lists are taken from robots meta tags or X-Robots-Tag headers, user agent prefixes like "googlebot:" are ignored.
+func robotsNoIndex(lists ...string) bool {
+	for _, list := range lists {
+		for _, directive := range strings.Split(strings.ToLower(list), ",") {
+			directive = strings.TrimSpace(directive)
+			if i := strings.Index(directive, ":"); i >= 0 && !strings.HasPrefix(directive, "unavailable_after") {
+				directive = strings.TrimSpace(directive[i+1:])
+			}
+			if directive == "noindex" || directive == "none" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// documentNoIndex - checks robots meta tags of document forbid indexing.
+func documentNoIndex(doc *html.Node) bool {
+	for _, m := range collectNodes("meta", firstNode("head", doc), nil) {
+		if strings.EqualFold(attribute("name", m), "robots") && robotsNoIndex(attribute("content", m)) {
+			return true
+		}
+	}
+	return false
+}
+
 // firstNode - parses elements tree to find first node for given tag.
 // Returns nil if node for tag is not found.
 func firstNode(tag string, tree *html.Node) *html.Node {
@@ -110,19 +391,27 @@ func attribute(name string, element *html.Node) string {
 	return ""
 }
 
-// collectAttributes - parses elements tree and collects all attributes values for given tag.
-// You can pass nil for values, but always check length of results.
-func collectAttributes(tag, attr string, tree *html.Node, values []string) []string {
+// collectNodes - parses elements tree and collects all elements for given tag.
+// You can pass nil for nodes, but always check length of results.
+func collectNodes(tag string, tree *html.Node, nodes []*html.Node) []*html.Node {
 	if tree == nil {
-		return values
+		return nodes
 	}
 	if tree.Type == html.ElementNode && tree.Data == tag {
-		if v := attribute(attr, tree); v != "" {
-			values = append(values, v)
-		}
+		nodes = append(nodes, tree)
 	}
 	for n := tree.FirstChild; n != nil; n = n.NextSibling {
-		values = collectAttributes(tag, attr, n, values)
+		nodes = collectNodes(tag, n, nodes)
+	}
+	return nodes
+}
+
+// hasClass - checks class attribute of single document element contains given class name.
+func hasClass(class string, element *html.Node) bool {
+	for _, c := range strings.Fields(attribute("class", element)) {
+		if c == class {
+			return true
+		}
 	}
-	return values
+	return false
 }
@@ -2,9 +2,9 @@ package sitemap
 
 import (
 	"bytes"
+	"context"
 	"net/http"
 	"net/http/httptest"
-	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -32,7 +32,7 @@ func Test_fetchDocument(t *testing.T) {
 	for _, c := range cases {
 		uri, _ := NewURI(server.URL + c.path)
 		t.Log(uri.String())
-		doc, meta, err := fetchDocument(uri, 0)
+		doc, meta, err := (&Parser{}).fetchDocument(context.Background(), uri, nil)
 		switch {
 		case err != nil:
 			if c.errMsg == "" {
@@ -61,6 +61,54 @@ func Test_fetchDocument(t *testing.T) {
 	}
 }
 
+func Test_fetchDocumentConditional(t *testing.T) {
+	server := httptest.NewServer(http.FileServer(http.Dir("testdata")))
+	defer server.Close()
+
+	uri, _ := NewURI(server.URL + "/valid.html")
+	doc, meta, err := (&Parser{}).fetchDocument(context.Background(), uri, nil)
+	if err != nil {
+		t.Fatal("Unexpected error:", err)
+	}
+	if doc == nil || meta == nil {
+		t.Fatal("Got nil document or metadata without error")
+	}
+	if meta.NotModified {
+		t.Error("Unexpected NotModified for unconditional request")
+	}
+	if meta.LastModified == "" {
+		t.Fatal("Expected non-empty Last-Modified, got:", *meta)
+	}
+
+	cached := &PageState{
+		LastModified: meta.LastModified,
+		Modified:     meta.Modified,
+		Hash:         "0a1b",
+	}
+	doc, notModified, err := (&Parser{}).fetchDocument(context.Background(), uri, cached)
+	if err != nil {
+		t.Fatal("Unexpected error:", err)
+	}
+	if doc != nil {
+		t.Error("Got non-nil document for not modified response")
+	}
+	if notModified == nil || !notModified.NotModified {
+		t.Fatal("Expected not modified metadata, got:", notModified)
+	}
+	if notModified.Hash != cached.Hash || !notModified.Modified.Equal(cached.Modified) {
+		t.Error("Expected cached metadata:", *cached, "got:", *notModified)
+	}
+
+	cached.LastModified = "Wed, 21 Oct 2015 07:28:00 GMT"
+	doc, meta, err = (&Parser{}).fetchDocument(context.Background(), uri, cached)
+	if err != nil {
+		t.Fatal("Unexpected error:", err)
+	}
+	if doc == nil || meta == nil || meta.NotModified {
+		t.Error("Expected document to be fetched again when it was modified")
+	}
+}
+
 func Test_firstNode(t *testing.T) {
 	content := `
 	<!doctype html>
@@ -139,61 +187,6 @@ func Test_attribute(t *testing.T) {
 	}
 }
 
-func Test_collectAttributes(t *testing.T) {
-	content := `
-	<!doctype html>
-
-	<html lang="en">
-	<head>
-		<meta charset="utf-8">
-		<title>Test valid HTML</title>
-		<meta name="description" content="Test valid HTML">
-		<meta name="author" content="github.com/wtask/sitemap">
-	</head>
-
-	<body>
-		<h1>Test valid HTML</h1>
-		<a href="http://host/main.html">Home page</a>
-		<p>Text paragraph, see <a href="/details.php">details</a>
-		</p>
-		<a>Invalid anchor</a>
-	</body>
-	</html>	
-	`
-	doc, _ := html.Parse(bytes.NewReader([]byte(content)))
-
-	head := firstNode("head", doc)
-	if head == nil {
-		t.Fatal("<head> node not found")
-	}
-	names := collectAttributes("meta", "name", head, nil)
-	if len(names) != 2 {
-		t.Error("Unexpected num of names:", len(names))
-	}
-	expected := []string{"description", "author"}
-	if !reflect.DeepEqual(names, expected) {
-		t.Error("Expected names:", expected, "got:", names)
-	}
-
-	body := firstNode("body", doc)
-	if body == nil {
-		t.Fatal("<body> node not found")
-	}
-	links := collectAttributes("a", "href", body, nil)
-	if len(links) != 2 {
-		t.Error("Unexpected num of links:", len(links))
-	}
-	expected = []string{"http://host/main.html", "/details.php"}
-	if !reflect.DeepEqual(links, expected) {
-		t.Error("Expected links:", expected, "got:", links)
-	}
-
-	nothing := collectAttributes("img", "scr", nil, nil)
-	if nothing != nil || len(nothing) != 0 {
-		t.Error("Unexpected non-empty collection for nil tree:", nothing)
-	}
-}
-
 func Test_modifiedTime(test *testing.T) {
 	type header struct {
 		name, value string
@@ -258,3 +251,125 @@ func Test_modifiedTime(test *testing.T) {
 		}
 	}
 }
+
+func Test_contentHash(t *testing.T) {
+	parse := func(content string) *html.Node {
+		doc, err := html.Parse(strings.NewReader(content))
+		if err != nil {
+			t.Fatal("Unexpected parse error:", err)
+		}
+		return doc
+	}
+	volatile, err := parseSelector("#counter, .banner")
+	if err != nil {
+		t.Fatal("Unexpected parseSelector() error:", err)
+	}
+
+	origin := contentHash(
+		parse(`<html><body><h1>Title</h1><p>Some  text</p><span id="counter">1</span></body></html>`),
+		volatile,
+	)
+	if origin == "" {
+		t.Fatal("Unexpected empty hash")
+	}
+	same := []string{
+		// whitespace and comments are ignored
+		`<html><body>
+			<h1>Title</h1>
+			<!-- generated at 12:00 -->
+			<p>Some
+			text</p><span id="counter">1</span>
+		</body></html>`,
+		// volatile regions are ignored
+		`<html><body><h1>Title</h1><p>Some  text</p><span id="counter">2</span></body></html>`,
+		`<html><body><h1>Title</h1><div class="top banner"><b>Sale</b></div><p>Some text</p></body></html>`,
+	}
+	for _, content := range same {
+		if actual := contentHash(parse(content), volatile); actual != origin {
+			t.Error("Expected the same hash for:", content)
+		}
+	}
+	changed := []string{
+		`<html><body><h1>Title</h1><p>Other text</p><span id="counter">1</span></body></html>`,
+		`<html><body><h2>Title</h2><p>Some text</p><span id="counter">1</span></body></html>`,
+		`<html><body><h1 class="x">Title</h1><p>Some text</p><span id="counter">1</span></body></html>`,
+	}
+	for _, content := range changed {
+		if actual := contentHash(parse(content), volatile); actual == origin {
+			t.Error("Expected changed hash for:", content)
+		}
+	}
+	if contentHash(nil, nil) != "" {
+		t.Error("Expected empty hash for nil document")
+	}
+}
+
+func Test_stableModified(t *testing.T) {
+	previous := time.Date(2019, 5, 19, 9, 5, 0, 0, time.UTC)
+	now := time.Date(2019, 5, 21, 23, 26, 0, 0, time.UTC)
+	cases := []struct {
+		meta     DocumentMeta
+		cached   *PageState
+		expected time.Time
+	}{
+		{DocumentMeta{Modified: now, Hash: "a"}, nil, now},
+		{DocumentMeta{Modified: now, Hash: "a"}, &PageState{Modified: previous, Hash: "a"}, previous},
+		{DocumentMeta{Modified: now, Hash: "b"}, &PageState{Modified: previous, Hash: "a"}, now},
+		{DocumentMeta{Modified: now, Hash: ""}, &PageState{Modified: previous, Hash: ""}, now},
+		{DocumentMeta{Modified: now, Hash: "a"}, &PageState{Hash: "a"}, now},
+		{
+			DocumentMeta{Modified: now, Hash: "a", LastModified: "Tue, 21 May 2019 23:26:00 GMT"},
+			&PageState{Modified: previous, Hash: "a"},
+			now,
+		},
+	}
+	for _, c := range cases {
+		if actual := stableModified(&c.meta, c.cached); !actual.Equal(c.expected) {
+			t.Error("Expected:", c.expected, "actual:", actual, "for:", c.meta, c.cached)
+		}
+	}
+}
+
+func Test_robotsNoIndex(t *testing.T) {
+	cases := []struct {
+		lists    []string
+		expected bool
+	}{
+		{nil, false},
+		{[]string{""}, false},
+		{[]string{"index, follow"}, false},
+		{[]string{"noindex"}, true},
+		{[]string{"NoIndex, NoFollow"}, true},
+		{[]string{"none"}, true},
+		{[]string{"nofollow", "googlebot: noindex"}, true},
+		{[]string{"unavailable_after: 25 Jun 2010 15:00:00 PST"}, false},
+		{[]string{"noindexing"}, false},
+	}
+	for _, c := range cases {
+		if actual := robotsNoIndex(c.lists...); actual != c.expected {
+			t.Error("Expected:", c.expected, "actual:", actual, "for:", c.lists)
+		}
+	}
+}
+
+func Test_documentNoIndex(t *testing.T) {
+	cases := []struct {
+		source   string
+		expected bool
+	}{
+		{`<html><head><title>A</title></head><body></body></html>`, false},
+		{`<html><head><meta name="robots" content="noindex,follow"></head></html>`, true},
+		{`<html><head><meta name="ROBOTS" content="NONE"></head></html>`, true},
+		{`<html><head><meta name="description" content="noindex"></head></html>`, false},
+		{`<html><head></head><body><meta name="robots" content="noindex"></body></html>`, false},
+	}
+	for _, c := range cases {
+		doc, err := html.Parse(strings.NewReader(c.source))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual := documentNoIndex(doc); actual != c.expected {
+			t.Error("Expected:", c.expected, "actual:", actual, "for:", c.source)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package sitemap
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// ModifiedSource - source of document modification time.
+type ModifiedSource string
+
+const (
+	// ModifiedHeader - Last-Modified HTTP header.
+	ModifiedHeader ModifiedSource = "header"
+	// ModifiedDocument - metadata declared inside document:
+	// article:modified_time, og:updated_time, <meta name="last-modified">,
+	// JSON-LD dateModified and <time> elements marked as modification date.
+	ModifiedDocument ModifiedSource = "document"
+)
+
+// DefaultModifiedSources - default precedence of modification time sources.
+var DefaultModifiedSources = []ModifiedSource{ModifiedHeader, ModifiedDocument}
+
+// dateLayouts - common date formats used inside documents.
+var dateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	http.TimeFormat,
+	time.RFC1123Z,
+	time.RFC1123,
+	time.RFC850,
+	time.ANSIC,
+}
+
+// parseDate - parses date in one of common formats.
+func parseDate(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unknown date format %q", value)
+}
+
+// metaDate - candidate of modification time found inside document.
+type metaDate struct {
+	origin, value string
+}
+
+// documentDates - collects candidates of modification time from document in order of reliability.
+func documentDates(doc *html.Node) []metaDate {
+	dates := []metaDate{}
+	head := firstNode("head", doc)
+	for _, prop := range []string{"article:modified_time", "og:updated_time"} {
+		for _, meta := range collectNodes("meta", head, nil) {
+			if attribute("property", meta) == prop {
+				dates = append(dates, metaDate{prop, attribute("content", meta)})
+			}
+		}
+	}
+	for _, meta := range collectNodes("meta", head, nil) {
+		if strings.EqualFold(attribute("name", meta), "last-modified") ||
+			strings.EqualFold(attribute("http-equiv", meta), "last-modified") {
+			dates = append(dates, metaDate{"meta last-modified", attribute("content", meta)})
+		}
+	}
+	for _, script := range collectNodes("script", doc, nil) {
+		if !strings.EqualFold(strings.TrimSpace(attribute("type", script)), "application/ld+json") ||
+			script.FirstChild == nil {
+			continue
+		}
+		var data interface{}
+		if err := json.Unmarshal([]byte(script.FirstChild.Data), &data); err != nil {
+			continue
+		}
+		if value := jsonLDModified(data); value != "" {
+			dates = append(dates, metaDate{"JSON-LD dateModified", value})
+		}
+	}
+	for _, t := range collectNodes("time", firstNode("body", doc), nil) {
+		if attribute("itemprop", t) != "dateModified" &&
+			!hasClass("updated", t) {
+			continue
+		}
+		value := attribute("datetime", t)
+		if value == "" && t.FirstChild != nil && t.FirstChild.Type == html.TextNode {
+			value = t.FirstChild.Data
+		}
+		dates = append(dates, metaDate{"<time>", value})
+	}
+	return dates
+}
+
+// jsonLDModified - searches dateModified property inside decoded JSON-LD data.
+func jsonLDModified(data interface{}) string {
+	switch v := data.(type) {
+	case map[string]interface{}:
+		if s, ok := v["dateModified"].(string); ok && s != "" {
+			return s
+		}
+		for _, key := range []string{"@graph", "mainEntity", "mainEntityOfPage"} {
+			if s := jsonLDModified(v[key]); s != "" {
+				return s
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if s := jsonLDModified(item); s != "" {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+// documentModified - returns first valid modification time declared inside document.
+// Values which can not be parsed are reported as errors.
+func documentModified(doc *html.Node) (time.Time, []error) {
+	var errs []error
+	for _, d := range documentDates(doc) {
+		t, err := parseDate(d.value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %s", d.origin, err))
+			continue
+		}
+		return t, errs
+	}
+	return time.Time{}, errs
+}
+
+// resolveModified - resolves document modification time according given precedence of sources.
+// If neither of sources declares modification time, the result of stableModified is used.
+// The second result contains errors for unparseable values.
+func resolveModified(
+	sources []ModifiedSource,
+	doc *html.Node,
+	meta *DocumentMeta,
+	cached *PageState,
+) (time.Time, []error) {
+	var errs []error
+	for _, source := range sources {
+		switch source {
+		case ModifiedHeader:
+			if t, err := http.ParseTime(meta.LastModified); err == nil {
+				return t, errs
+			}
+		case ModifiedDocument:
+			t, e := documentModified(doc)
+			errs = append(errs, e...)
+			if !t.IsZero() {
+				return t, errs
+			}
+		}
+	}
+	return stableModified(meta, cached), errs
+}
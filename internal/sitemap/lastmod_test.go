@@ -0,0 +1,136 @@
+package sitemap
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+func Test_parseDate(t *testing.T) {
+	msk := time.FixedZone("", 3*60*60)
+	cases := []struct {
+		value    string
+		valid    bool
+		expected time.Time
+	}{
+		{"2019-05-21T23:26:00+03:00", true, time.Date(2019, 5, 21, 23, 26, 0, 0, msk)},
+		{"2019-05-21T23:26:00.123Z", true, time.Date(2019, 5, 21, 23, 26, 0, 123000000, time.UTC)},
+		{"2019-05-21T23:26:00+0300", true, time.Date(2019, 5, 21, 23, 26, 0, 0, msk)},
+		{"2019-05-21T23:26+03:00", true, time.Date(2019, 5, 21, 23, 26, 0, 0, msk)},
+		{"2019-05-21T23:26:00", true, time.Date(2019, 5, 21, 23, 26, 0, 0, time.UTC)},
+		{"2019-05-21 23:26:00", true, time.Date(2019, 5, 21, 23, 26, 0, 0, time.UTC)},
+		{" 2019-05-21 ", true, time.Date(2019, 5, 21, 0, 0, 0, 0, time.UTC)},
+		{"Tue, 21 May 2019 23:26:00 GMT", true, time.Date(2019, 5, 21, 23, 26, 0, 0, time.UTC)},
+		{"Tue, 21 May 2019 23:26:00 +0300", true, time.Date(2019, 5, 21, 23, 26, 0, 0, msk)},
+		{"", false, time.Time{}},
+		{"yesterday", false, time.Time{}},
+		{"21.05.2019", false, time.Time{}},
+	}
+	for _, c := range cases {
+		actual, err := parseDate(c.value)
+		if err != nil && c.valid {
+			t.Errorf("Unexpected error %q for %q", err, c.value)
+		}
+		if err == nil && !c.valid {
+			t.Errorf("Error was expected for %q, but it did not happened", c.value)
+		}
+		if !actual.Equal(c.expected) {
+			t.Errorf("Expected %v for %q, got %v", c.expected, c.value, actual)
+		}
+	}
+}
+
+func Test_documentModified(t *testing.T) {
+	cases := []struct {
+		content  string
+		expected time.Time
+		numErrs  int
+	}{
+		{`<html><head><title>No dates</title></head><body></body></html>`, time.Time{}, 0},
+		{
+			`<html><head>
+				<meta property="og:updated_time" content="2019-05-20T10:00:00Z">
+				<meta property="article:modified_time" content="2019-05-21T10:00:00Z">
+			</head></html>`,
+			time.Date(2019, 5, 21, 10, 0, 0, 0, time.UTC),
+			0,
+		},
+		{
+			`<html><head><meta name="Last-Modified" content="Tue, 21 May 2019 10:00:00 GMT"></head></html>`,
+			time.Date(2019, 5, 21, 10, 0, 0, 0, time.UTC),
+			0,
+		},
+		{
+			`<html><head>
+				<meta property="article:modified_time" content="last week">
+				<script type="application/ld+json">
+					{"@context": "https://schema.org", "@graph": [{"@type": "WebSite"}, {"@type": "Article", "dateModified": "2019-05-21"}]}
+				</script>
+			</head></html>`,
+			time.Date(2019, 5, 21, 0, 0, 0, 0, time.UTC),
+			1,
+		},
+		{
+			`<html><body>
+				<time datetime="2019-01-01">published</time>
+				<time itemprop="dateModified" datetime="2019-05-21T10:00:00Z">updated</time>
+			</body></html>`,
+			time.Date(2019, 5, 21, 10, 0, 0, 0, time.UTC),
+			0,
+		},
+		{
+			`<html><body><time class="entry updated">2019-05-21</time></body></html>`,
+			time.Date(2019, 5, 21, 0, 0, 0, 0, time.UTC),
+			0,
+		},
+		{
+			`<html><body><time class="updated" datetime="in May"></time></body></html>`,
+			time.Time{},
+			1,
+		},
+	}
+	for _, c := range cases {
+		doc, _ := html.Parse(strings.NewReader(c.content))
+		actual, errs := documentModified(doc)
+		if !actual.Equal(c.expected) {
+			t.Errorf("Expected %v, got %v for %s", c.expected, actual, c.content)
+		}
+		if len(errs) != c.numErrs {
+			t.Errorf("Expected %d error(s), got %v for %s", c.numErrs, errs, c.content)
+		}
+	}
+}
+
+func Test_resolveModified(t *testing.T) {
+	doc, _ := html.Parse(strings.NewReader(
+		`<html><head><meta property="article:modified_time" content="2019-05-20T10:00:00Z"></head></html>`,
+	))
+	empty, _ := html.Parse(strings.NewReader(`<html></html>`))
+	header := time.Date(2019, 5, 21, 10, 0, 0, 0, time.UTC)
+	document := time.Date(2019, 5, 20, 10, 0, 0, 0, time.UTC)
+	date := time.Date(2019, 5, 22, 10, 0, 0, 0, time.UTC)
+	withHeader := &DocumentMeta{Modified: header, LastModified: "Tue, 21 May 2019 10:00:00 GMT"}
+	withDate := &DocumentMeta{Modified: date}
+
+	cases := []struct {
+		sources  []ModifiedSource
+		doc      *html.Node
+		meta     *DocumentMeta
+		expected time.Time
+	}{
+		{DefaultModifiedSources, doc, withHeader, header},
+		{DefaultModifiedSources, doc, withDate, document},
+		{[]ModifiedSource{ModifiedDocument, ModifiedHeader}, doc, withHeader, document},
+		{[]ModifiedSource{ModifiedDocument, ModifiedHeader}, empty, withHeader, header},
+		{[]ModifiedSource{ModifiedHeader}, doc, withDate, date},
+		{DefaultModifiedSources, empty, withDate, date},
+	}
+	for _, c := range cases {
+		actual, _ := resolveModified(c.sources, c.doc, c.meta, nil)
+		if !actual.Equal(c.expected) {
+			t.Errorf("Expected %v, got %v for %v", c.expected, actual, c.sources)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package sitemap
+
+import (
+	"bytes"
+	"fmt"
+
+	"golang.org/x/net/html"
+)
+
+// WithHeadProbe - make HEAD request before GET, so responses which are not HTML documents
+// or which are larger than allowed (see WithMaxBodyBytes) are not downloaded.
+// If server does not support HEAD, document is requested with GET as usual.
+func WithHeadProbe() ParserOption {
+	return func(p *Parser) error {
+		p.headProbe = true
+		return nil
+	}
+}
+
+// WithMaxBodyBytes - declare max size of response body, larger documents are not parsed
+// and ErrorBodyLimit is reported. Body is checked against Content-Length header first, if server declares it,
+// otherwise reading of body is stopped as soon as limit is exceeded.
+func WithMaxBodyBytes(n int64) ParserOption {
+	if n <= 0 {
+		return failedOption(fmt.Errorf("Invalid max body size %d", n))
+	}
+	return func(p *Parser) error {
+		p.maxBodyBytes = n
+		return nil
+	}
+}
+
+// WithMaxDocumentNodes - declare max num of nodes (elements, text, comments) of document, larger documents are dropped
+// and ErrorNodeLimit is reported. Nodes are counted by tokens of document source before it is parsed,
+// so tree of larger document is never built. Source is kept in memory while counting, use WithMaxBodyBytes to limit it.
+func WithMaxDocumentNodes(n int) ParserOption {
+	if n <= 0 {
+		return failedOption(fmt.Errorf("Invalid max num of document nodes %d", n))
+	}
+	return func(p *Parser) error {
+		p.maxNodes = n
+		return nil
+	}
+}
+
+// exceedsBodyLimit - checks declared size of response body exceeds limit, unknown size (-1) never exceeds it.
+func (p *Parser) exceedsBodyLimit(size int64) bool {
+	return p.maxBodyBytes > 0 && size > p.maxBodyBytes
+}
+
+// exceedsNodeLimit - checks num of tokens of document source, which become nodes of parsed tree, exceeds `max`.
+// Tokenizing is stopped as soon as limit is exceeded.
+func exceedsNodeLimit(src []byte, max int) bool {
+	z := html.NewTokenizer(bytes.NewReader(src))
+	n := 0
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return false
+		case html.StartTagToken, html.SelfClosingTagToken, html.TextToken, html.CommentToken, html.DoctypeToken:
+			if n++; n > max {
+				return true
+			}
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package sitemap
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestParser_headProbe(t *testing.T) {
+	mx := sync.Mutex{}
+	requests := map[string][]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mx.Lock()
+		requests[r.URL.Path] = append(requests[r.URL.Path], r.Method)
+		mx.Unlock()
+		switch r.URL.Path {
+		case "/page.html":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html><head></head><body>page</body></html>`))
+		case "/nohead.html":
+			if r.Method == "HEAD" {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html><head></head><body>page</body></html>`))
+		case "/data.json":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{}`))
+		case "/large.html":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html><head></head><body>` + strings.Repeat("<p>large</p>", 100) + `</body></html>`))
+		}
+	}))
+	defer server.Close()
+
+	parser, err := NewParser(WithHeadProbe(), WithMaxBodyBytes(1000))
+	if err != nil {
+		t.Fatal("Unexpected NewParser() error:", err)
+	}
+	cases := []struct {
+		path     string
+		class    ErrorClass
+		requests []string
+	}{
+		{"/page.html", "", []string{"HEAD", "GET"}},
+		{"/nohead.html", "", []string{"HEAD", "GET"}},
+		{"/data.json", ErrorContentType, []string{"HEAD"}},
+		{"/large.html", ErrorBodyLimit, []string{"HEAD"}},
+	}
+	for _, c := range cases {
+		t.Run(c.path, func(t *testing.T) {
+			uri, _ := NewURI(server.URL + c.path)
+			doc, _, err := parser.fetchDocument(context.Background(), uri, nil)
+			if c.class == "" && (err != nil || doc == nil) {
+				t.Errorf("Unexpected error %v or nil document", err)
+			}
+			if c.class != "" && classify(err) != c.class {
+				t.Errorf("Expected %q error, got %v", c.class, err)
+			}
+			if !reflect.DeepEqual(requests[c.path], c.requests) {
+				t.Errorf("Expected requests %v, got %v", c.requests, requests[c.path])
+			}
+		})
+	}
+}
+
+func TestParser_fetchDocumentLimits(t *testing.T) {
+	large := `<html><head></head><body>` + strings.Repeat("<p>large</p>", 100) + `</body></html>`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/declared.html":
+			w.Write([]byte(large))
+		case "/chunked.html":
+			// size of chunked body is not declared
+			for chunk := large; chunk != ""; {
+				n := 100
+				if n > len(chunk) {
+					n = len(chunk)
+				}
+				w.Write([]byte(chunk[:n]))
+				w.(http.Flusher).Flush()
+				chunk = chunk[n:]
+			}
+		case "/small.html":
+			w.Write([]byte(`<html><head></head><body><p>small</p></body></html>`))
+		}
+	}))
+	defer server.Close()
+
+	cases := []struct {
+		name    string
+		path    string
+		options []ParserOption
+		class   ErrorClass
+	}{
+		{"no limits", "/chunked.html", nil, ""},
+		{"declared size", "/declared.html", []ParserOption{WithMaxBodyBytes(1000)}, ErrorBodyLimit},
+		{"chunked body", "/chunked.html", []ParserOption{WithMaxBodyBytes(1000)}, ErrorBodyLimit},
+		{"body within limit", "/small.html", []ParserOption{WithMaxBodyBytes(1000)}, ""},
+		{"nodes", "/declared.html", []ParserOption{WithMaxDocumentNodes(100)}, ErrorNodeLimit},
+		{"nodes within limit", "/small.html", []ParserOption{WithMaxDocumentNodes(100)}, ""},
+		{"nodes of chunked body", "/chunked.html", []ParserOption{WithMaxDocumentNodes(100)}, ErrorNodeLimit},
+		{
+			"body before nodes",
+			"/chunked.html",
+			[]ParserOption{WithMaxBodyBytes(1000), WithMaxDocumentNodes(100)},
+			ErrorBodyLimit,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			parser, err := NewParser(c.options...)
+			if err != nil {
+				t.Fatal("Unexpected NewParser() error:", err)
+			}
+			uri, _ := NewURI(server.URL + c.path)
+			doc, meta, err := parser.fetchDocument(context.Background(), uri, nil)
+			if c.class == "" && (err != nil || doc == nil || meta == nil) {
+				t.Errorf("Unexpected error %v or nil document", err)
+			}
+			if c.class != "" && (classify(err) != c.class || doc != nil || meta != nil) {
+				t.Errorf("Expected %q error without document, got %v", c.class, err)
+			}
+		})
+	}
+
+	invalid := []ParserOption{WithMaxBodyBytes(0), WithMaxDocumentNodes(-1)}
+	for i, option := range invalid {
+		if _, err := NewParser(option); err == nil {
+			t.Errorf("Expected error for invalid option #%d", i)
+		}
+	}
+}
+
+func Test_exceedsNodeLimit(t *testing.T) {
+	cases := []struct {
+		src      string
+		max      int
+		expected bool
+	}{
+		// doctype, html, head, body, 2 p and 2 text nodes
+		{`<!DOCTYPE html><html><head></head><body><p>a</p><p>b</p></body></html>`, 8, false},
+		{`<!DOCTYPE html><html><head></head><body><p>a</p><p>b</p></body></html>`, 7, true},
+		{`<p>a<br/><!-- comment --></p>`, 4, false},
+		{`<p>a<br/><!-- comment --></p>`, 3, true},
+		{`</p></div>`, 1, false},
+		{``, 1, false},
+	}
+	for _, c := range cases {
+		if actual := exceedsNodeLimit([]byte(c.src), c.max); actual != c.expected {
+			t.Errorf("%q, limit %d: expected %v, got %v", c.src, c.max, c.expected, actual)
+		}
+	}
+}
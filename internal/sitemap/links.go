@@ -0,0 +1,275 @@
+package sitemap
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// LinkSource - element attribute which contains link to another document, like `href` of `a` element.
+// If Rel is not empty, element is accepted only if its `rel` attribute contains any of given values,
+// e.g. `link` elements with rel="next" or rel="prev".
+// Sources of `href` of `link` element match links declared with Link header of response too,
+// if there are no such sources, header links are matched by DefaultHeaderLinkRel.
+type LinkSource struct {
+	Tag  string
+	Attr string
+	Rel  []string
+}
+
+// DefaultLinkSources - links are collected from `href` of `a` elements, including `a` elements of inline SVG
+// which may declare link with `xlink:href` attribute.
+var DefaultLinkSources = []LinkSource{{Tag: "a", Attr: "href"}}
+
+// DefaultHeaderLinkRel - relation types of links declared with Link header of response which are collected
+// when link sources have no `href` of `link` element.
+var DefaultHeaderLinkRel = []string{"next", "prev", "alternate", "canonical"}
+
+// ParseLinkSource - parses link source from string in "tag@attr" or "tag@attr[rel|rel...]" format,
+// e.g. "area@href", "iframe@src" or "link@href[next|prev]".
+func ParseLinkSource(s string) (LinkSource, error) {
+	source := LinkSource{}
+	s = strings.TrimSpace(s)
+	if i := strings.IndexByte(s, '['); i >= 0 {
+		if !strings.HasSuffix(s, "]") {
+			return source, fmt.Errorf("Invalid link source %q, unclosed rel filter", s)
+		}
+		for _, rel := range strings.Split(s[i+1:len(s)-1], "|") {
+			if rel = strings.TrimSpace(rel); rel != "" {
+				source.Rel = append(source.Rel, rel)
+			}
+		}
+		if len(source.Rel) == 0 {
+			return source, fmt.Errorf("Invalid link source %q, empty rel filter", s)
+		}
+		s = s[:i]
+	}
+	parts := strings.Split(s, "@")
+	if len(parts) != 2 {
+		return source, fmt.Errorf("Invalid link source %q, expected \"tag@attr\"", s)
+	}
+	source.Tag, source.Attr = strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+	if source.Tag == "" || source.Attr == "" {
+		return source, fmt.Errorf("Invalid link source %q, tag and attribute are required", s)
+	}
+	return source, nil
+}
+
+// String - returns link source in format accepted by ParseLinkSource.
+func (s LinkSource) String() string {
+	if len(s.Rel) == 0 {
+		return s.Tag + "@" + s.Attr
+	}
+	return s.Tag + "@" + s.Attr + "[" + strings.Join(s.Rel, "|") + "]"
+}
+
+// WithLinkSources - declare elements and attributes where links to other documents are searched,
+// DefaultLinkSources are replaced by given list.
+// Links of Link header are collected by DefaultHeaderLinkRel, unless list has sources of `href` of `link` element.
+func WithLinkSources(sources ...LinkSource) ParserOption {
+	if len(sources) == 0 {
+		return failedOption(fmt.Errorf("Empty list of link sources"))
+	}
+	normalized := make([]LinkSource, 0, len(sources))
+	for _, s := range sources {
+		if s.Tag == "" || s.Attr == "" {
+			return failedOption(fmt.Errorf("Invalid link source %q, tag and attribute are required", s.String()))
+		}
+		n := LinkSource{Tag: strings.ToLower(s.Tag), Attr: strings.ToLower(s.Attr)}
+		for _, rel := range s.Rel {
+			n.Rel = append(n.Rel, strings.ToLower(rel))
+		}
+		normalized = append(normalized, n)
+	}
+	return func(p *Parser) error {
+		p.links = normalized
+		return nil
+	}
+}
+
+// match - checks element is the source of link.
+func (s LinkSource) match(n *html.Node) bool {
+	if n.Type != html.ElementNode || n.Data != s.Tag {
+		return false
+	}
+	return s.matchRel(attribute("rel", n))
+}
+
+// matchRel - checks space-separated relation types contain any of rel filter values.
+func (s LinkSource) matchRel(rels string) bool {
+	if len(s.Rel) == 0 {
+		return true
+	}
+	for _, rel := range strings.Fields(strings.ToLower(rels)) {
+		for _, r := range s.Rel {
+			if rel == r {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// value - returns value of source attribute of element.
+// Namespaced attributes of foreign elements, like `xlink:href` of SVG, are matched by full or local name.
+func (s LinkSource) value(n *html.Node) string {
+	for _, a := range n.Attr {
+		if a.Key == s.Attr || a.Namespace != "" && a.Namespace+":"+a.Key == s.Attr {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// collectLinks - parses elements tree and collects values of all link sources in document order.
+// You can pass nil for values, but always check length of results.
+func collectLinks(sources []LinkSource, tree *html.Node, values []string) []string {
+	if tree == nil {
+		return values
+	}
+	for _, s := range sources {
+		if !s.match(tree) {
+			continue
+		}
+		if v := strings.TrimSpace(s.value(tree)); v != "" {
+			values = append(values, v)
+			break
+		}
+	}
+	for n := tree.FirstChild; n != nil; n = n.NextSibling {
+		values = collectLinks(sources, n, values)
+	}
+	return values
+}
+
+// ignoredSchemes - schemes of links which never refer to documents of site.
+var ignoredSchemes = []string{"javascript", "mailto", "tel", "data"}
+
+// baseURL - returns URL against which relative links of document are resolved.
+// As HTML specification requires, it is `href` of the first `base` element resolved against document URL,
+// or document URL itself if there is no such element or its value is invalid.
+func baseURL(docURL *URI, doc *html.Node) *url.URL {
+	for _, base := range collectNodes("base", doc, nil) {
+		for _, a := range base.Attr {
+			if a.Namespace != "" || a.Key != "href" {
+				continue
+			}
+			u, err := docURL.Parse(stripURLWhitespace(a.Val))
+			if err != nil || u.Scheme != "http" && u.Scheme != "https" {
+				return docURL.URL
+			}
+			return u
+		}
+	}
+	return docURL.URL
+}
+
+// ignoredLink - checks link has one of ignoredSchemes.
+func ignoredLink(href string) bool {
+	i := strings.IndexByte(href, ':')
+	if i < 0 {
+		return false
+	}
+	scheme := strings.ToLower(href[:i])
+	for _, s := range ignoredSchemes {
+		if scheme == s {
+			return true
+		}
+	}
+	return false
+}
+
+// stripURLWhitespace - removes leading and trailing spaces and all tabs and newlines from URL,
+// as browsers do before URL is parsed.
+func stripURLWhitespace(raw string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '\t' || r == '\n' || r == '\r' {
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(raw))
+}
+
+// headerLink - link declared with Link header of response, see RFC 8288.
+type headerLink struct {
+	target string
+	rel    string
+}
+
+// parseLinkHeader - parses values of Link header, like `<https://example.com/2>; rel="next"`.
+// Malformed links are skipped.
+func parseLinkHeader(values ...string) []headerLink {
+	var links []headerLink
+	for _, v := range values {
+		for v != "" {
+			v = strings.TrimLeft(v, " \t,")
+			if !strings.HasPrefix(v, "<") {
+				// skip malformed link up to next one
+				if i := strings.IndexByte(v, ','); i >= 0 {
+					v = v[i+1:]
+					continue
+				}
+				break
+			}
+			end := strings.IndexByte(v, '>')
+			if end < 0 {
+				break
+			}
+			link := headerLink{target: strings.TrimSpace(v[1:end])}
+			v = v[end+1:]
+			var params string
+			params, v = splitLinkParams(v)
+			for _, param := range strings.Split(params, ";") {
+				parts := strings.SplitN(param, "=", 2)
+				if len(parts) == 2 && strings.EqualFold(strings.TrimSpace(parts[0]), "rel") {
+					link.rel = strings.Trim(strings.TrimSpace(parts[1]), `"`)
+					break
+				}
+			}
+			links = append(links, link)
+		}
+	}
+	return links
+}
+
+// splitLinkParams - returns parameters of the current link and rest of Link header value,
+// commas inside quoted parameter values do not separate links.
+func splitLinkParams(v string) (params, rest string) {
+	quoted := false
+	for i := 0; i < len(v); i++ {
+		switch {
+		case v[i] == '\\' && quoted:
+			i++
+		case v[i] == '"':
+			quoted = !quoted
+		case v[i] == ',' && !quoted:
+			return v[:i], v[i+1:]
+		}
+	}
+	return v, ""
+}
+
+// collectHeaderLinks - collects targets of header links, which are treated as `link` elements of document,
+// so they are matched by sources with `link` tag and `href` attribute, or by DefaultHeaderLinkRel without such sources.
+func collectHeaderLinks(sources []LinkSource, links []headerLink, values []string) []string {
+	matching := []LinkSource{}
+	for _, s := range sources {
+		if s.Tag == "link" && s.Attr == "href" {
+			matching = append(matching, s)
+		}
+	}
+	if len(matching) == 0 {
+		matching = append(matching, LinkSource{Tag: "link", Attr: "href", Rel: DefaultHeaderLinkRel})
+	}
+	for _, l := range links {
+		for _, s := range matching {
+			if s.matchRel(l.rel) && l.target != "" {
+				values = append(values, l.target)
+				break
+			}
+		}
+	}
+	return values
+}
@@ -0,0 +1,275 @@
+package sitemap
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestParseLinkSource(t *testing.T) {
+	cases := []struct {
+		source   string
+		expected LinkSource
+		isErr    bool
+	}{
+		{"a@href", LinkSource{Tag: "a", Attr: "href"}, false},
+		{" iframe@src ", LinkSource{Tag: "iframe", Attr: "src"}, false},
+		{"a@xlink:href", LinkSource{Tag: "a", Attr: "xlink:href"}, false},
+		{"link@href[next|prev]", LinkSource{Tag: "link", Attr: "href", Rel: []string{"next", "prev"}}, false},
+		{"link@href[]", LinkSource{}, true},
+		{"link@href[next", LinkSource{}, true},
+		{"a", LinkSource{}, true},
+		{"a@href@src", LinkSource{}, true},
+		{"@href", LinkSource{}, true},
+	}
+	for _, c := range cases {
+		actual, err := ParseLinkSource(c.source)
+		if c.isErr != (err != nil) {
+			t.Errorf("Unexpected error for %q: %v", c.source, err)
+			continue
+		}
+		if !c.isErr && !reflect.DeepEqual(actual, c.expected) {
+			t.Errorf("Expected %+v for %q, got %+v", c.expected, c.source, actual)
+		}
+		if !c.isErr && actual.String() != strings.TrimSpace(c.source) {
+			t.Errorf("Expected %q, got %q", strings.TrimSpace(c.source), actual.String())
+		}
+	}
+
+	invalid := []ParserOption{
+		WithLinkSources(),
+		WithLinkSources(LinkSource{Tag: "a"}),
+		WithLinkSources(LinkSource{Attr: "href"}),
+	}
+	for i, option := range invalid {
+		if _, err := NewParser(option); err == nil {
+			t.Errorf("Expected error for invalid option #%d", i)
+		}
+	}
+}
+
+func Test_documentLinks(t *testing.T) {
+	doc, err := html.Parse(strings.NewReader(`<html><head>
+<link rel="stylesheet" href="/style.css">
+<link rel="Next" href="/page/2.html">
+<link rel="alternate prev" href="/page/0.html">
+</head><body>
+<a href="/a.html#top">a</a>
+<map><area href="/area.html"></map>
+<iframe src="/frame.html"></iframe>
+<svg><a xlink:href="/svg-xlink.html"><text>x</text></a><a href="/svg.html"><text>y</text></a></svg>
+</body></html>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, _ := NewURI("http://localhost/")
+	cases := []struct {
+		name     string
+		sources  []LinkSource
+		expected []string
+	}{
+		{
+			"default",
+			DefaultLinkSources,
+			[]string{"http://localhost/a.html", "http://localhost/svg-xlink.html", "http://localhost/svg.html"},
+		},
+		{
+			"pagination",
+			[]LinkSource{{Tag: "link", Attr: "href", Rel: []string{"next", "prev"}}},
+			[]string{"http://localhost/page/2.html", "http://localhost/page/0.html"},
+		},
+		{
+			"area and iframe",
+			[]LinkSource{{Tag: "area", Attr: "href"}, {Tag: "iframe", Attr: "src"}},
+			[]string{"http://localhost/area.html", "http://localhost/frame.html"},
+		},
+		{
+			"namespaced attribute",
+			[]LinkSource{{Tag: "a", Attr: "xlink:href"}},
+			[]string{"http://localhost/svg-xlink.html"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			actual := documentLinks(root, doc, nil, c.sources)
+			if !reflect.DeepEqual(actual, c.expected) {
+				t.Errorf("Expected %v, got %v", c.expected, actual)
+			}
+		})
+	}
+}
+
+func Test_documentLinksResolution(t *testing.T) {
+	cases := []struct {
+		name     string
+		docURL   string
+		head     string
+		body     string
+		expected []string
+	}{
+		{
+			"relative to document",
+			"http://localhost/blog/post.html",
+			"",
+			`<a href="next.html">a</a><a href="../about.html">b</a><a href="?page=2">c</a><a href="#top">d</a>`,
+			[]string{
+				"http://localhost/blog/next.html",
+				"http://localhost/about.html",
+				"http://localhost/blog/post.html?page=2",
+				"http://localhost/blog/post.html",
+			},
+		},
+		{
+			"absolute base",
+			"http://localhost/blog/post.html",
+			`<base href="http://localhost/docs/">`,
+			`<a href="intro.html">a</a><a href="/root.html">b</a>`,
+			[]string{"http://localhost/docs/intro.html", "http://localhost/root.html"},
+		},
+		{
+			"relative base",
+			"http://localhost/blog/post.html",
+			`<base href="../docs/">`,
+			`<a href="intro.html">a</a>`,
+			[]string{"http://localhost/docs/intro.html"},
+		},
+		{
+			"first base with href",
+			"http://localhost/blog/post.html",
+			`<base target="_blank"><base href="/docs/"><base href="/other/">`,
+			`<a href="intro.html">a</a>`,
+			[]string{"http://localhost/docs/intro.html"},
+		},
+		{
+			"invalid base",
+			"http://localhost/blog/post.html",
+			`<base href="mailto:admin@localhost">`,
+			`<a href="next.html">a</a>`,
+			[]string{"http://localhost/blog/next.html"},
+		},
+		{
+			"protocol relative",
+			"https://localhost/blog/",
+			"",
+			`<a href="//example.com/page.html">a</a>`,
+			[]string{"https://example.com/page.html"},
+		},
+		{
+			"ignored schemes",
+			"http://localhost/",
+			"",
+			`<a href="javascript:void(0)">a</a><a href=" JavaScript:go()">b</a><a href="java` + "\t" + `script:go()">c</a>` +
+				`<a href="mailto:admin@localhost">d</a><a href="tel:+100">e</a><a href="data:text/html,hi">f</a>` +
+				`<a href="ftp://localhost/file">g</a><a href="/page` + "\n" + `.html">h</a>`,
+			[]string{"http://localhost/page.html"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			doc, err := html.Parse(strings.NewReader(
+				"<html><head>" + c.head + "</head><body>" + c.body + "</body></html>",
+			))
+			if err != nil {
+				t.Fatal(err)
+			}
+			docURL, _ := NewURI(c.docURL)
+			actual := documentLinks(docURL, doc, nil, DefaultLinkSources)
+			if !reflect.DeepEqual(actual, c.expected) {
+				t.Errorf("Expected %v, got %v", c.expected, actual)
+			}
+		})
+	}
+}
+
+func Test_parseLinkHeader(t *testing.T) {
+	cases := []struct {
+		values   []string
+		expected []headerLink
+	}{
+		{nil, nil},
+		{
+			[]string{`<https://localhost/page/2>; rel="next"`},
+			[]headerLink{{"https://localhost/page/2", "next"}},
+		},
+		{
+			[]string{`</>; rel=canonical, </en/>; rel="alternate"; hreflang="en"; title="a, b"`, `<style.css>;rel=stylesheet`},
+			[]headerLink{{"/", "canonical"}, {"/en/", "alternate"}, {"style.css", "stylesheet"}},
+		},
+		{
+			[]string{`</a,b>; title="say \"hi, all\""; rel="prev next"`},
+			[]headerLink{{"/a,b", "prev next"}},
+		},
+		{
+			[]string{`malformed; rel=next, </ok>; REL=next, </no-rel>, <unclosed`},
+			[]headerLink{{"/ok", "next"}, {"/no-rel", ""}},
+		},
+	}
+	for _, c := range cases {
+		actual := parseLinkHeader(c.values...)
+		if !reflect.DeepEqual(actual, c.expected) {
+			t.Errorf("Expected %+v for %q, got %+v", c.expected, c.values, actual)
+		}
+	}
+}
+
+func TestParser_headerLinks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Add("Link", `<https://example.com/canonical.html>; rel="canonical"`)
+		w.Header().Add("Link", `<2.html>; rel="next", </style.css>; rel="stylesheet"`)
+		w.Write([]byte(`<html><head><base href="/other/"></head><body><a href="a.html">a</a></body></html>`))
+	}))
+	defer server.Close()
+
+	cases := []struct {
+		name     string
+		sources  []LinkSource
+		expected []string
+	}{
+		{
+			"default",
+			nil,
+			[]string{server.URL + "/other/a.html", "https://example.com/canonical.html", server.URL + "/page/2.html"},
+		},
+		{
+			"anchors",
+			[]LinkSource{{Tag: "a", Attr: "href"}},
+			[]string{server.URL + "/other/a.html", "https://example.com/canonical.html", server.URL + "/page/2.html"},
+		},
+		{
+			"stylesheet",
+			[]LinkSource{{Tag: "a", Attr: "href"}, {Tag: "link", Attr: "href", Rel: []string{"stylesheet"}}},
+			[]string{server.URL + "/other/a.html", server.URL + "/style.css"},
+		},
+		{
+			"pagination",
+			[]LinkSource{{Tag: "a", Attr: "href"}, {Tag: "link", Attr: "href", Rel: []string{"next", "canonical"}}},
+			[]string{server.URL + "/other/a.html", "https://example.com/canonical.html", server.URL + "/page/2.html"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			links := []string{}
+			options := []ParserOption{WithHooks(Hooks{OnLinks: func(e LinkEvent) { links = e.Links }})}
+			if c.sources != nil {
+				options = append(options, WithLinkSources(c.sources...))
+			}
+			parser, err := NewParser(options...)
+			if err != nil {
+				t.Fatal("Unexpected NewParser() error:", err)
+			}
+			page, _ := NewURI(server.URL + "/page/1.html")
+			if result := parser.worker(context.Background(), page, 0, Target{page, 0}); result.err != nil {
+				t.Fatal("Unexpected error:", result.err)
+			}
+			if !reflect.DeepEqual(links, c.expected) {
+				t.Errorf("Expected %v, got %v", c.expected, links)
+			}
+		})
+	}
+}
@@ -0,0 +1,2 @@
+// Package load reads published site maps and site map indexes from local files or URLs, plain or gzipped.
+package load
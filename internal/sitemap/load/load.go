@@ -0,0 +1,170 @@
+package load
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/wtask/sitemap/internal/sitemap"
+)
+
+// maxSize - max size of loaded data, a bit more than the protocol allows for uncompressed file.
+const maxSize = 64 * 1024 * 1024
+
+// dateLayouts - W3C Datetime formats of `lastmod`.
+var dateLayouts = []string{
+	"2006-01-02T15:04:05Z07:00",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02",
+	"2006-01",
+	"2006",
+}
+
+// document - site map or site map index.
+type document struct {
+	XMLName xml.Name
+	URLs    []entry `xml:"url"`
+	Maps    []entry `xml:"sitemap"`
+}
+
+// entry - `url` or `sitemap` element.
+type entry struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod"`
+}
+
+// Sitemap - reads site map or site map index from local file or http(s) URL and returns all its URLs.
+// Maps listed in index are loaded too. If index is a local file, map file with the same name next to index
+// is preferred over its URL. Gzipped content is detected automatically.
+// Entries with invalid URLs are skipped, unparsable lastmod is ignored.
+func Sitemap(ctx context.Context, source string) ([]sitemap.MapItem, error) {
+	doc, err := load(ctx, source)
+	if err != nil {
+		return nil, err
+	}
+	switch doc.XMLName.Local {
+	case "urlset":
+		return items(doc.URLs), nil
+	case "sitemapindex":
+		result := []sitemap.MapItem{}
+		for _, m := range doc.Maps {
+			child, err := load(ctx, locate(source, strings.TrimSpace(m.Loc)))
+			if err != nil {
+				return nil, err
+			}
+			if child.XMLName.Local != "urlset" {
+				return nil, fmt.Errorf("load.Sitemap: %s, unexpected root element <%s> of map listed in index", m.Loc, child.XMLName.Local)
+			}
+			result = append(result, items(child.URLs)...)
+		}
+		return result, nil
+	default:
+		return nil, fmt.Errorf("load.Sitemap: %s, unexpected root element <%s>", source, doc.XMLName.Local)
+	}
+}
+
+// locate - returns local file for map listed in local index if such file exists, otherwise returns `loc` as is.
+func locate(index, loc string) string {
+	if isURL(index) {
+		return loc
+	}
+	u, err := url.Parse(loc)
+	if err != nil || path.Base(u.Path) == "/" || path.Base(u.Path) == "." {
+		return loc
+	}
+	local := filepath.Join(filepath.Dir(index), path.Base(u.Path))
+	if _, err := os.Stat(local); err == nil {
+		return local
+	}
+	return loc
+}
+
+// load - reads and decodes single file.
+func load(ctx context.Context, source string) (*document, error) {
+	data, err := read(ctx, source)
+	if err != nil {
+		return nil, fmt.Errorf("load.Sitemap: %s", err)
+	}
+	if len(data) >= 2 && data[0] == 0x1f && data[1] == 0x8b {
+		gr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("load.Sitemap: %s, cannot prepare gzip reader: %s", source, err)
+		}
+		// small gzipped file can hide huge content, so it is never unpacked beyond the limit
+		data, err = ioutil.ReadAll(io.LimitReader(gr, maxSize+1))
+		gr.Close()
+		if err != nil {
+			return nil, fmt.Errorf("load.Sitemap: %s, cannot read gzip data: %s", source, err)
+		}
+		if len(data) > maxSize {
+			return nil, fmt.Errorf("load.Sitemap: %s, uncompressed size exceeds limit of %d bytes", source, maxSize)
+		}
+	}
+	doc := &document{}
+	if err := xml.Unmarshal(data, doc); err != nil {
+		return nil, fmt.Errorf("load.Sitemap: %s, unable to decode: %s", source, err)
+	}
+	return doc, nil
+}
+
+// read - reads content of local file or http(s) URL.
+func read(ctx context.Context, source string) ([]byte, error) {
+	if !isURL(source) {
+		f, err := os.Open(source)
+		if err != nil {
+			return nil, fmt.Errorf("cannot open file: %s", err)
+		}
+		defer f.Close()
+		return ioutil.ReadAll(io.LimitReader(f, maxSize))
+	}
+	req, err := http.NewRequest("GET", source, nil)
+	if err != nil {
+		return nil, fmt.Errorf("preparing request to %q failed: %s", source, err)
+	}
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("request to %s failed: %s", source, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("cannot fetch %s, status code: %d", source, resp.StatusCode)
+	}
+	return ioutil.ReadAll(io.LimitReader(resp.Body, maxSize))
+}
+
+// isURL - checks source is http(s) URL rather than local file.
+func isURL(source string) bool {
+	return strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://")
+}
+
+// items - converts entries into map items.
+func items(entries []entry) []sitemap.MapItem {
+	result := make([]sitemap.MapItem, 0, len(entries))
+	for _, e := range entries {
+		uri, err := sitemap.NewURI(strings.TrimSpace(e.Loc))
+		if err != nil {
+			continue
+		}
+		meta := &sitemap.DocumentMeta{}
+		lastmod := strings.TrimSpace(e.LastMod)
+		for _, layout := range dateLayouts {
+			if t, err := time.Parse(layout, lastmod); err == nil {
+				meta.Modified = t
+				break
+			}
+		}
+		result = append(result, sitemap.MapItem{URI: uri, DocumentMeta: meta})
+	}
+	return result
+}
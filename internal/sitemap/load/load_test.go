@@ -0,0 +1,159 @@
+package load
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wtask/sitemap/internal/compression"
+	"github.com/wtask/sitemap/internal/sitemap"
+)
+
+const (
+	testMap1 = `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+	<url><loc>http://localhost/</loc><lastmod>2019-05-21T23:26:00Z</lastmod></url>
+	<url><loc> http://localhost/faq.html </loc><lastmod>2019-05-21</lastmod></url>
+	<url><loc>/relative.html</loc></url>
+</urlset>`
+	testMap2 = `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+	<url><loc>http://localhost/terms.html</loc><lastmod>21.05.2019</lastmod></url>
+</urlset>`
+	testIndex = `<?xml version="1.0" encoding="UTF-8"?>
+<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+	<sitemap><loc>{{base}}/sitemap1.xml</loc></sitemap>
+	<sitemap><loc>{{base}}/sitemap2.xml.gzip</loc></sitemap>
+</sitemapindex>`
+)
+
+// gzipped - compresses given data.
+func gzipped(t *testing.T, data string) []byte {
+	buf := bytes.Buffer{}
+	if err := compression.Gzip(strings.NewReader(data), &buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// expectedItems - returns URIs of all test maps.
+func expectedItems() []sitemap.MapItem {
+	uri := func(s string) *sitemap.URI {
+		u, _ := sitemap.NewURI(s)
+		return u
+	}
+	return []sitemap.MapItem{
+		{URI: uri("http://localhost/"), DocumentMeta: &sitemap.DocumentMeta{
+			Modified: time.Date(2019, 5, 21, 23, 26, 0, 0, time.UTC),
+		}},
+		{URI: uri("http://localhost/faq.html"), DocumentMeta: &sitemap.DocumentMeta{
+			Modified: time.Date(2019, 5, 21, 0, 0, 0, 0, time.UTC),
+		}},
+		{URI: uri("http://localhost/terms.html"), DocumentMeta: &sitemap.DocumentMeta{}},
+	}
+}
+
+func TestSitemap_URL(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/sitemap_index.xml":
+			w.Write([]byte(strings.Replace(testIndex, "{{base}}", server.URL, -1)))
+		case "/sitemap1.xml":
+			w.Write([]byte(testMap1))
+		case "/sitemap2.xml.gzip":
+			w.Write(gzipped(t, testMap2))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	items, err := Sitemap(context.Background(), server.URL+"/sitemap_index.xml")
+	if err != nil {
+		t.Fatal("Unexpected Sitemap() error:", err)
+	}
+	if !reflect.DeepEqual(items, expectedItems()) {
+		t.Errorf("Unexpected items: %v", items)
+	}
+
+	if _, err := Sitemap(context.Background(), server.URL+"/notfound.xml"); err == nil {
+		t.Error("Expected error for not found site map")
+	}
+}
+
+func TestSitemap_file(t *testing.T) {
+	dir, err := ioutil.TempDir("", "load")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// index refers to unavailable host, so local files must be used
+	files := map[string][]byte{
+		"sitemap_index.xml":  []byte(strings.Replace(testIndex, "{{base}}", "http://localhost:1", -1)),
+		"sitemap1.xml":       []byte(testMap1),
+		"sitemap2.xml.gzip":  gzipped(t, testMap2),
+		"nested_index.xml":   []byte(strings.Replace(testIndex, "{{base}}/sitemap1.xml", "sitemap_index.xml", -1)),
+		"invalid.xml":        []byte("<urlset><url>"),
+		"unexpected_doc.xml": []byte("<html></html>"),
+	}
+	for name, data := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	items, err := Sitemap(context.Background(), filepath.Join(dir, "sitemap_index.xml"))
+	if err != nil {
+		t.Fatal("Unexpected Sitemap() error:", err)
+	}
+	if !reflect.DeepEqual(items, expectedItems()) {
+		t.Errorf("Unexpected items: %v", items)
+	}
+
+	items, err = Sitemap(context.Background(), filepath.Join(dir, "sitemap2.xml.gzip"))
+	if err != nil || len(items) != 1 {
+		t.Errorf("Unexpected result for gzipped map: %v, %v", items, err)
+	}
+
+	// few kilobytes of gzip are unpacked into content larger than the limit
+	bomb := bytes.Buffer{}
+	content := io.MultiReader(strings.NewReader("<urlset><!--"), io.LimitReader(zeros{}, maxSize))
+	if err := compression.Gzip(content, &bomb, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "bomb.xml.gz"), bomb.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Sitemap(context.Background(), filepath.Join(dir, "bomb.xml.gz")); err == nil ||
+		!strings.Contains(err.Error(), "uncompressed size exceeds limit") {
+		t.Error("Expected error of uncompressed size, got", err)
+	}
+
+	for _, name := range []string{"nested_index.xml", "invalid.xml", "unexpected_doc.xml", "nonexistent.xml"} {
+		if _, err := Sitemap(context.Background(), filepath.Join(dir, name)); err == nil {
+			t.Errorf("Expected error for %s", name)
+		}
+	}
+}
+
+// zeros - endless reader of zero bytes.
+type zeros struct{}
+
+func (zeros) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+	return len(p), nil
+}
@@ -0,0 +1,79 @@
+package sitemap
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// FormLogin - login form which is submitted before crawl to obtain session cookies.
+type FormLogin struct {
+	// URL - address where form is posted
+	URL *URI
+	// Fields - form fields, like username and password
+	Fields url.Values
+	// Page - address of login page, crawl is aborted when document is redirected to it;
+	// URL is used if Page is nil
+	Page *URI
+}
+
+// WithFormLogin - submit login form before crawl and keep session cookies in jar used to fetch documents.
+// If cookie jar is not declared with WithCookieJar, in-memory jar is used.
+// Form is submitted every time crawl is started or resumed. When crawled document is redirected to login page,
+// session is considered lost and crawl is aborted with error of ErrorLogin class.
+func WithFormLogin(login FormLogin) ParserOption {
+	if login.URL == nil {
+		return failedOption(fmt.Errorf("Invalid login URL (nil)"))
+	}
+	if len(login.Fields) == 0 {
+		return failedOption(fmt.Errorf("Empty list of login form fields"))
+	}
+	if login.Page == nil {
+		login.Page = login.URL
+	}
+	return func(p *Parser) error {
+		p.login = &login
+		return nil
+	}
+}
+
+// isPage - checks given URL refers to login page, query is ignored.
+func (login *FormLogin) isPage(u *url.URL) bool {
+	return u.Scheme == login.Page.Scheme && u.Host == login.Page.Host && u.Path == login.Page.Path
+}
+
+// submitLogin - posts login form and checks session cookies are received.
+func (p *Parser) submitLogin(ctx context.Context) error {
+	if p.requestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.requestTimeout)
+		defer cancel()
+	}
+	uri := p.login.URL.String()
+	req, err := http.NewRequest("POST", uri, strings.NewReader(p.login.Fields.Encode()))
+	if err != nil {
+		return &CrawlError{uri, ErrorLogin, fmt.Errorf("preparing login request to %q failed: %s", uri, err)}
+	}
+	for name, values := range p.headers {
+		req.Header[name] = values
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	redirects := []string{}
+	resp, err := p.httpClient(&redirects).Do(req.WithContext(ctx))
+	if err != nil {
+		return &CrawlError{uri, ErrorLogin, fmt.Errorf("login request to %s failed: %s", uri, err)}
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode >= http.StatusBadRequest {
+		return &CrawlError{uri, ErrorLogin, fmt.Errorf("login to %s failed, status code: %d", uri, resp.StatusCode)}
+	}
+	if len(p.jar.Cookies(p.login.URL.URL)) == 0 && len(p.jar.Cookies(resp.Request.URL)) == 0 {
+		return &CrawlError{uri, ErrorLogin, fmt.Errorf("login to %s failed, session cookie is not received", uri)}
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package sitemap
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestParser_formLogin(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html><head></head><body><form method="post"></form></body></html>`))
+			return
+		}
+		if r.PostFormValue("username") != "user" || r.PostFormValue("password") != "secret" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "ok", Path: "/"})
+		http.Redirect(w, r, "/members/index.html", http.StatusSeeOther)
+	})
+	pages := map[string]string{
+		"/members/index.html": `<a href="/members/a.html">a</a>`,
+		"/members/a.html":     `<a href="/members/index.html">index</a>`,
+		"/members/lost.html":  `<a href="/members/expired.html">expired</a>`,
+	}
+	mux.HandleFunc("/members/", func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie("session")
+		if err != nil || cookie.Value != "ok" || r.URL.Path == "/members/expired.html" {
+			http.Redirect(w, r, "/login?next="+url.QueryEscape(r.URL.Path), http.StatusFound)
+			return
+		}
+		body, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head></head><body>` + body + `</body></html>`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	loginURL, _ := NewURI(server.URL + "/login")
+	cases := []struct {
+		name     string
+		start    string
+		password string
+		expected []string
+		errors   int64
+	}{
+		{
+			"logged in",
+			"/members/index.html",
+			"secret",
+			[]string{server.URL + "/members/a.html", server.URL + "/members/index.html"},
+			0,
+		},
+		{"rejected", "/members/index.html", "wrong", []string{}, 1},
+		{"session lost", "/members/lost.html", "secret", []string{server.URL + "/members/lost.html"}, 1},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			parser, err := NewParser(WithFormLogin(FormLogin{
+				URL:    loginURL,
+				Fields: url.Values{"username": {"user"}, "password": {c.password}},
+			}))
+			if err != nil {
+				t.Fatal("Unexpected NewParser() error:", err)
+			}
+			root, _ := NewURI(server.URL + c.start)
+			found := []string{}
+			for _, item := range parser.Parse(root, 1, 1) {
+				if item.DocumentMeta != nil {
+					found = append(found, item.URI.String())
+				}
+			}
+			sort.Strings(found)
+			if !reflect.DeepEqual(found, c.expected) {
+				t.Errorf("Expected %v, got %v", c.expected, found)
+			}
+			if errors := parser.Stats().Errors[ErrorLogin]; errors != c.errors {
+				t.Errorf("Expected %d login errors, got %d", c.errors, errors)
+			}
+		})
+	}
+
+	invalid := []FormLogin{
+		{Fields: url.Values{"username": {"user"}}},
+		{URL: loginURL},
+	}
+	for _, login := range invalid {
+		if _, err := NewParser(WithFormLogin(login)); err == nil {
+			t.Errorf("Expected error for %+v", login)
+		}
+	}
+}
@@ -1,41 +1,70 @@
 package sitemap
 
 import (
+	"context"
 	"fmt"
+	"net/http"
+	"net/http/cookiejar"
 	"net/url"
 	"path"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
+
+	"golang.org/x/net/html"
 )
 
 // Parser - represent type to explore and build site map.
 type Parser struct {
 	errorHandler   ErrorHandler  // async
 	requestTimeout time.Duration // optional
-	queueCap       uint
+	frontier       Frontier      // optional
+	visited        VisitedStore  // optional
+	state          *CrawlState   // optional
+	volatile       selector      // optional
+	modified       []ModifiedSource
+
+	checkpointHandler  CheckpointHandler // optional
+	checkpointInterval time.Duration
+	resultHandler      ResultHandler  // optional
+	hooks              []Hooks        // optional
+	pool               *WorkerPool    // optional
+	seeds              []*URI         // optional
+	client             *http.Client   // optional
+	jar                http.CookieJar // optional
+	headers            http.Header    // optional
+	login              *FormLogin     // optional
+	links              []LinkSource
+	accepted           []string
+	resources          []string // optional
+	headProbe          bool
+	maxBodyBytes       int64 // optional
+	maxNodes           int   // optional
+
+	mx    sync.Mutex  // protects stats
+	stats *crawlStats // stats of the last crawl
 }
 
-const (
-	// DefaultNumWorkers - default num of goroutines which fetches and parses html documents.
-	DefaultNumWorkers uint = 4
-	// DefaultQueueCap - default capacity of internal queue.
-	DefaultQueueCap uint = 1000
-)
+// DefaultNumWorkers - default num of goroutines which fetches and parses html documents.
+const DefaultNumWorkers uint = 4
 
 // ErrorHandler - func which should handle parsing error.
 type ErrorHandler func(error)
 
-type parserOption func(*Parser) error
+// ResultHandler - func which receives site map items as they are discovered.
+type ResultHandler func(MapItem)
 
-func failedOption(err error) parserOption {
+// ParserOption - optional feature of Parser, see With... functions.
+type ParserOption func(*Parser) error
+
+func failedOption(err error) ParserOption {
 	return func(p *Parser) error {
 		return err
 	}
 }
 
-func (p *Parser) setup(options ...parserOption) error {
+func (p *Parser) setup(options ...ParserOption) error {
 	if p == nil {
 		return nil
 	}
@@ -54,15 +83,26 @@ func (p *Parser) setup(options ...parserOption) error {
 // By default, all occurred errors are ignored.
 // Note: error handler will start inside own goroutine, which will silently recovered in case of panic.
 // Also the Parse method will wait for handlers are completed before returning results.
-func WithErrorHandler(h ErrorHandler) parserOption {
+func WithErrorHandler(h ErrorHandler) ParserOption {
 	return func(p *Parser) error {
 		p.errorHandler = h
 		return nil
 	}
 }
 
+// WithResultHandler - specify handler which receives every site map item as soon as it is discovered,
+// so results can be processed before crawl is completed.
+// Note: handler is called synchronously from worker goroutines, so it must be safe for concurrent use
+// and should return quickly.
+func WithResultHandler(h ResultHandler) ParserOption {
+	return func(p *Parser) error {
+		p.resultHandler = h
+		return nil
+	}
+}
+
 // WithRequestTimeout - declare timeout for any requests made with Parser.
-func WithRequestTimeout(timeout time.Duration) parserOption {
+func WithRequestTimeout(timeout time.Duration) ParserOption {
 	if timeout < 0 {
 		return failedOption(fmt.Errorf("Invalid request timeout %v", timeout))
 	}
@@ -72,124 +112,510 @@ func WithRequestTimeout(timeout time.Duration) parserOption {
 	}
 }
 
+// WithCrawlState - use state of previous crawl to make conditional requests.
+// Documents which were not modified since previous crawl are not downloaded again,
+// their outgoing links are taken from the state. The state is updated with results of every fetched document.
+// Also the state keeps modification time of documents stable when server does not declare Last-Modified
+// and content is not changed, see WithVolatileSelectors.
+func WithCrawlState(state *CrawlState) ParserOption {
+	if state == nil {
+		return failedOption(fmt.Errorf("Invalid crawl state (nil)"))
+	}
+	return func(p *Parser) error {
+		p.state = state
+		return nil
+	}
+}
+
+// WithVolatileSelectors - declare CSS selectors of document regions which change on every request
+// (counters, banners, CSRF-tokens, etc.) and should be ignored when content hash is calculated.
+// Supported are element names, ids, classes and attribute selectors joined with descendant combinator.
+func WithVolatileSelectors(selectors ...string) ParserOption {
+	volatile := selector{}
+	for _, s := range selectors {
+		sel, err := parseSelector(s)
+		if err != nil {
+			return failedOption(err)
+		}
+		volatile = append(volatile, sel...)
+	}
+	return func(p *Parser) error {
+		p.volatile = volatile
+		return nil
+	}
+}
+
+// WithModifiedSources - declare precedence of sources to resolve document modification time.
+// By default the Last-Modified header takes precedence over metadata declared inside document (DefaultModifiedSources).
+// If neither of sources declares modification time, the Date header is used.
+func WithModifiedSources(sources ...ModifiedSource) ParserOption {
+	if len(sources) == 0 {
+		return failedOption(fmt.Errorf("Empty list of modification time sources"))
+	}
+	seen := map[ModifiedSource]bool{}
+	for _, s := range sources {
+		if s != ModifiedHeader && s != ModifiedDocument {
+			return failedOption(fmt.Errorf("Unknown modification time source %q", s))
+		}
+		if seen[s] {
+			return failedOption(fmt.Errorf("Duplicate modification time source %q", s))
+		}
+		seen[s] = true
+	}
+	return func(p *Parser) error {
+		p.modified = sources
+		return nil
+	}
+}
+
+// WithCheckpoint - periodically take snapshot of crawl progress and pass it to handler,
+// so the crawl can be continued with Resume method if it was interrupted.
+// Handler is called from background goroutine and calls never overlap, crawl is not paused while handler is running.
+// Workers are paused only while snapshot is taken, it does not copy frontier and visited store
+// if they support snapshots, as default in-memory ones do.
+func WithCheckpoint(interval time.Duration, h CheckpointHandler) ParserOption {
+	if interval <= 0 {
+		return failedOption(fmt.Errorf("Invalid checkpoint interval %v", interval))
+	}
+	if h == nil {
+		return failedOption(fmt.Errorf("Invalid checkpoint handler (nil)"))
+	}
+	return func(p *Parser) error {
+		p.checkpointInterval = interval
+		p.checkpointHandler = h
+		return nil
+	}
+}
+
+// WithFrontier - use given queue of targets instead of default in-memory one.
+// Frontier should be empty and should not be shared between concurrent crawls.
+func WithFrontier(f Frontier) ParserOption {
+	if f == nil {
+		return failedOption(fmt.Errorf("Invalid frontier (nil)"))
+	}
+	return func(p *Parser) error {
+		p.frontier = f
+		return nil
+	}
+}
+
+// WithVisitedStore - use given storage of processed targets instead of default in-memory one.
+// Store should be empty and should not be shared between concurrent crawls.
+func WithVisitedStore(s VisitedStore) ParserOption {
+	if s == nil {
+		return failedOption(fmt.Errorf("Invalid visited store (nil)"))
+	}
+	return func(p *Parser) error {
+		p.visited = s
+		return nil
+	}
+}
+
+// WithSeeds - declare additional start URIs, which are crawled together with root URI of Stream or Parse.
+// Every seed starts at level 0 and extends scope of crawl: link is followed if it is in scope of root or of any seed.
+// Use it to crawl sections of site which are not linked from root document.
+func WithSeeds(seeds ...*URI) ParserOption {
+	for _, s := range seeds {
+		if s == nil {
+			return failedOption(fmt.Errorf("Invalid seed (nil)"))
+		}
+	}
+	return func(p *Parser) error {
+		p.seeds = append(p.seeds, seeds...)
+		return nil
+	}
+}
+
 // NewParser - create Parser instance with optional features.
-func NewParser(options ...parserOption) (*Parser, error) {
-	p := &Parser{queueCap: DefaultQueueCap}
+func NewParser(options ...ParserOption) (*Parser, error) {
+	p := &Parser{modified: DefaultModifiedSources, links: DefaultLinkSources, accepted: DefaultAcceptedTypes}
 	if err := p.setup(options...); err != nil {
 		return nil, err
 	}
+	if p.login != nil && p.jar == nil {
+		p.jar, _ = cookiejar.New(nil)
+	}
 	return p, nil
 }
 
-// Parse - takes root URI and max depth to find all links inside html documents available from root.
-// TODO Add support to cancel parsing with help of context.Context
+// Parse - takes root URI and max depth to find all links inside html documents available from root
+// and from seeds declared with WithSeeds.
+// Use Stream method to cancel crawl or to get results before crawl is completed.
 func (p *Parser) Parse(root *URI, depth, workers uint) []MapItem {
+	found := []MapItem{}
+	for item := range p.Stream(context.Background(), root, depth, workers) {
+		found = append(found, item)
+	}
+	return found
+}
+
+// Stream - takes root URI and max depth to find all links inside html documents available from root
+// and from seeds declared with WithSeeds.
+// Every site map item is sent into returned channel as soon as it is discovered.
+// The channel is closed when crawl is completed or context is canceled.
+// Consumer must read the channel until it is closed, otherwise crawl is blocked.
+func (p *Parser) Stream(ctx context.Context, root *URI, depth, workers uint) <-chan MapItem {
+	targets := []Target{{root, 0}}
+	for _, s := range p.seeds {
+		targets = append(targets, Target{s, 0})
+	}
+	return p.stream(ctx, root, depth, workers, targets, nil, false)
+}
+
+// Stats - returns statistics of the current or the last completed crawl.
+// Zero Stats is returned if parser has not been started yet.
+func (p *Parser) Stats() Stats {
+	p.mx.Lock()
+	stats := p.stats
+	p.mx.Unlock()
+	if stats == nil {
+		return Stats{Errors: map[ErrorClass]int64{}}
+	}
+	return stats.snapshot()
+}
+
+// Resume - continues interrupted crawl from given checkpoint in the same way as Stream.
+// Root URI and max depth are restored from checkpoint,
+// items visited before checkpoint was taken are sent into channel too.
+// If checkpoint refers to files of disk stores, parser must use stores opened from these files,
+// see OpenDiskFrontier and OpenDiskVisitedStore.
+func (p *Parser) Resume(ctx context.Context, cp *Checkpoint, workers uint) (<-chan MapItem, error) {
+	if cp == nil {
+		return nil, fmt.Errorf("sitemap.Parser.Resume: checkpoint is nil")
+	}
+	root, err := NewURI(cp.Root)
+	if err != nil {
+		return nil, fmt.Errorf("sitemap.Parser.Resume: %s", err)
+	}
+	if cp.FrontierLog != nil && !openedFrom(p.frontier, cp.FrontierLog) {
+		return nil, fmt.Errorf("sitemap.Parser.Resume: frontier is not opened from %s", cp.FrontierLog.File)
+	}
+	if cp.VisitedLog != nil && !openedFrom(p.visited, cp.VisitedLog) {
+		return nil, fmt.Errorf("sitemap.Parser.Resume: visited store is not opened from %s", cp.VisitedLog.File)
+	}
+	return p.stream(ctx, root, cp.Depth, workers, cp.targets(), cp.items(), cp.VisitedLog != nil), nil
+}
+
+// stream - runs crawl in background and sends results into returned channel.
+func (p *Parser) stream(
+	ctx context.Context,
+	root *URI,
+	depth, workers uint,
+	seeds []Target,
+	visited []MapItem,
+	restored bool,
+) <-chan MapItem {
+	items := make(chan MapItem, workers)
+	go func() {
+		defer close(items)
+		p.crawl(ctx, root, depth, workers, seeds, visited, restored, func(item MapItem) {
+			if p.resultHandler != nil {
+				p.resultHandler(item)
+			}
+			// item is always sent, consumer must drain the channel even if context is canceled
+			items <- item
+		})
+	}()
+	return items
+}
+
+// crawl - explores documents starting from `seeds` targets and passes every new site map item to `emit`.
+// Argument `visited` contains items which are already processed and should not be fetched again,
+// `restored` means visited store is opened from checkpoint and its items are passed to `emit` at first.
+// When context is canceled, crawl waits for running workers and takes final checkpoint if it is enabled.
+func (p *Parser) crawl(
+	ctx context.Context,
+	root *URI,
+	depth, workers uint,
+	seeds []Target,
+	visited []MapItem,
+	restored bool,
+	emit func(MapItem),
+) {
 	// TODO resolve p == nil case
 	if workers == 0 {
 		// or panic?
 		workers = DefaultNumWorkers
 	}
-	if p.queueCap == 0 {
-		p.queueCap = DefaultQueueCap
+	frontier, store := p.frontier, p.visited
+	if frontier == nil {
+		frontier = NewMemoryFrontier()
 	}
-	queue := make(chan Target, p.queueCap)
-	pending := make(chan (<-chan Target), workers)
-	results := sync.Map{}
+	if store == nil {
+		store = NewMemoryVisitedStore()
+	}
+
+	busy := int64(0) // num of running workers
+	stats := newCrawlStats(frontier, &busy, workers)
+	p.mx.Lock()
+	p.stats = stats
+	p.mx.Unlock()
+	defer stats.finish()
 
-	num := struct{ workers, fillers int64 }{0, 0} // goroutines counters
 	eh := sync.WaitGroup{}
+	report := func(err error) {
+		if err == nil {
+			return
+		}
+		stats.failed(err)
+		if p.errorHandler == nil {
+			return
+		}
+		eh.Add(1)
+		go func() {
+			defer func() {
+				recover() // protect parser from handler panic
+				eh.Done()
+			}()
+			p.errorHandler(err)
+		}()
+	}
 
-	ensureWorkers := func() {
-		for i := atomic.LoadInt64(&num.workers); i < int64(workers); i++ {
-			select {
-			case target := <-queue:
-				atomic.AddInt64(&num.workers, 1)
-				go func() {
-					defer func() {
-						atomic.AddInt64(&num.workers, -1)
-					}()
-					if _, ok := results.Load(target.URI.String()); ok {
-						// already have target, drop it
-						return
-					}
-					completed := p.worker(root, depth, target)
-					if completed.err != nil && p.errorHandler != nil {
-						eh.Add(1)
-						go func() {
-							defer func() {
-								recover() // protect parser from handler panic
-								eh.Done()
-							}()
-							p.errorHandler(completed.err)
-						}()
-					}
-					// Do not check "loaded" result here,
-					// always send completed.targets into pending chan to prevent leak of background goroutines.
-					results.LoadOrStore(
-						completed.Target.URI.String(),
-						MapItem{completed.Target.URI, completed.meta},
-					)
-					if completed.targets != nil {
-						pending <- completed.targets
-					}
-				}()
-			default:
-				return
-			}
+	// crawl is aborted when session of login form is lost
+	ctx, abort := context.WithCancel(ctx)
+	defer abort()
+	if p.login != nil {
+		if err := p.submitLogin(ctx); err != nil {
+			report(err)
+			eh.Wait()
+			return
 		}
 	}
 
-	ensureFillers := func() {
+	if restored {
+		if err := store.Range(func(item MapItem) bool {
+			emit(item)
+			return true
+		}); err != nil {
+			report(&CrawlError{"", ErrorStore, err})
+		}
+	}
+	for _, item := range visited {
+		added, err := store.Add(item)
+		if err != nil {
+			report(&CrawlError{item.URI.String(), ErrorStore, err})
+		}
+		if added {
+			emit(item)
+		}
+	}
+	for _, t := range seeds {
+		if err := frontier.Push(t); err != nil {
+			report(&CrawlError{t.URI.String(), ErrorStore, err})
+		}
+	}
+
+	// Workers hold read lock while they change frontier, store and in-flight targets,
+	// checkpoint holds write lock to take consistent snapshot.
+	mx := sync.RWMutex{}
+	inflight := struct {
+		sync.Mutex
+		targets map[string]Target
+	}{targets: map[string]Target{}}
+
+	checkpoint := func() *Checkpoint {
+		mx.Lock()
+		cp := &Checkpoint{
+			Root:     root.String(),
+			Depth:    depth,
+			Created:  time.Now().UTC(),
+			Frontier: []CheckpointTarget{},
+			Visited:  []CheckpointItem{},
+		}
+		// targets which are in progress will be fetched again after resume
+		for key, t := range inflight.targets {
+			cp.Frontier = append(cp.Frontier, CheckpointTarget{key, t.Level})
+		}
+		queued, err := snapshotFrontier(frontier)
+		if err != nil {
+			report(&CrawlError{"", ErrorStore, err})
+		}
+		stored, err := snapshotVisited(store)
+		if err != nil {
+			report(&CrawlError{"", ErrorStore, err})
+		}
+		mx.Unlock()
+		// snapshots are immutable, so they are serialized while workers are running
+		cp.FrontierLog, cp.VisitedLog = queued.log, stored.log
+		for _, t := range queued.targets {
+			cp.Frontier = append(cp.Frontier, CheckpointTarget{t.URI.String(), t.Level})
+		}
+		for _, item := range stored.items {
+			cp.Visited = append(cp.Visited, CheckpointItem{item.URI.String(), item.DocumentMeta})
+		}
+		return cp
+	}
+	lastCheckpoint := time.Now()
+	checkpointing := int32(0) // 1 while periodic checkpoint is running
+	cw := sync.WaitGroup{}
+
+	// main loop sleeps until worker or checkpoint is completed instead of spinning
+	wake := make(chan struct{}, 1)
+	notify := func() {
 		select {
-		// fill the queue when there are pending targets
-		case targets := <-pending:
-			atomic.AddInt64(&num.fillers, 1)
-			go func() {
-				defer func() {
-					atomic.AddInt64(&num.fillers, -1)
-				}()
-				for t := range targets {
-					queue <- t
-				}
-			}()
+		case wake <- struct{}{}:
 		default:
 		}
 	}
+	// idle - waits for notification, interruption or time of next checkpoint.
+	idle := func() {
+		var due <-chan time.Time
+		if p.checkpointHandler != nil && ctx.Err() == nil && atomic.LoadInt32(&checkpointing) == 0 {
+			timer := time.NewTimer(p.checkpointInterval - time.Since(lastCheckpoint))
+			defer timer.Stop()
+			due = timer.C
+		}
+		done := ctx.Done()
+		if ctx.Err() != nil {
+			// running workers are waited after interruption
+			done = nil
+		}
+		select {
+		case <-wake:
+		case <-done:
+		case <-due:
+		}
+	}
+
+	// claim - pops next target from frontier and marks it as in-flight.
+	// Targets which are already visited or in-flight are dropped.
+	claim := func() (Target, bool, error) {
+		mx.RLock()
+		defer mx.RUnlock()
+		for {
+			target, ok, err := frontier.Pop()
+			if err != nil || !ok {
+				return target, false, err
+			}
+			key := target.URI.String()
+			// worker stores item before it releases target, so in-flight targets are checked at first
+			inflight.Lock()
+			_, running := inflight.targets[key]
+			drop := running || store.Has(key)
+			if !drop {
+				inflight.targets[key] = target
+			}
+			inflight.Unlock()
+			if !drop {
+				return target, true, nil
+			}
+		}
+	}
 
-	queue <- Target{root, 0}
 	for {
 		// main loop
-		ensureWorkers()
-		ensureFillers()
-
-		if len(queue) == 0 &&
-			len(pending) == 0 &&
-			atomic.LoadInt64(&num.workers) == 0 &&
-			atomic.LoadInt64(&num.fillers) == 0 {
-			// all done
-			break
+		if ctx.Err() != nil {
+			if atomic.LoadInt64(&busy) == 0 {
+				if p.checkpointHandler != nil {
+					cw.Wait()
+					p.checkpointHandler(checkpoint())
+				}
+				break
+			}
+			idle()
+			continue
+		}
+		if p.checkpointHandler != nil &&
+			time.Since(lastCheckpoint) >= p.checkpointInterval &&
+			atomic.CompareAndSwapInt32(&checkpointing, 0, 1) {
+			lastCheckpoint = time.Now()
+			cw.Add(1)
+			go func() {
+				defer cw.Done()
+				defer notify()
+				defer atomic.StoreInt32(&checkpointing, 0)
+				p.checkpointHandler(checkpoint())
+			}()
 		}
-	}
 
-	found := []MapItem{}
-	results.Range(func(_ interface{}, value interface{}) bool {
-		if item, ok := value.(MapItem); ok {
-			found = append(found, item)
+		if atomic.LoadInt64(&busy) >= int64(workers) {
+			idle()
+			continue
+		}
+		if !p.pool.acquire(ctx.Done()) {
+			continue
+		}
+		target, ok, err := claim()
+		if err != nil {
+			// frontier is broken, crawl is stopped as interrupted one
+			p.pool.release()
+			report(&CrawlError{"", ErrorStore, err})
+			abort()
+			continue
+		}
+		if !ok {
+			p.pool.release()
+			// Counter must be checked before frontier: worker pushes targets before it is stopped.
+			if atomic.LoadInt64(&busy) == 0 && frontier.Len() == 0 {
+				// all done
+				break
+			}
+			idle()
+			continue
 		}
-		return true
-	})
 
-	eh.Wait()
+		atomic.AddInt64(&busy, 1)
+		go func() {
+			// deferred calls are run in reverse order, main loop is notified when slot is released
+			defer notify()
+			defer p.pool.release()
+			defer atomic.AddInt64(&busy, -1)
+			completed := p.worker(ctx, root, depth, target)
+			stats.completed(completed)
+			report(completed.err)
+			if classify(completed.err) == ErrorLogin {
+				abort()
+			}
+			for _, w := range completed.warnings {
+				report(w)
+			}
 
-	return found
+			if ctx.Err() != nil && completed.meta == nil {
+				// target was interrupted, it stays in-flight to be included into final checkpoint
+				return
+			}
+			item := MapItem{completed.Target.URI, completed.meta}
+			added := false
+			func() {
+				mx.RLock()
+				defer mx.RUnlock()
+				var err error
+				if added, err = store.Add(item); err != nil {
+					report(&CrawlError{item.URI.String(), ErrorStore, err})
+				}
+				for _, t := range completed.targets {
+					if store.Has(t.URI.String()) {
+						continue
+					}
+					if err := frontier.Push(t); err != nil {
+						report(&CrawlError{t.URI.String(), ErrorStore, err})
+					}
+				}
+				inflight.Lock()
+				delete(inflight.targets, target.URI.String())
+				inflight.Unlock()
+			}()
+			if added {
+				emit(item)
+			}
+		}()
+	}
+
+	cw.Wait()
+	eh.Wait()
 }
 
 // worker - fetches and parses target document.
-// Arguments `root` and `depth` are required to build absolute URI properly.
-func (p *Parser) worker(root *URI, depth uint, t Target) completedTarget {
+// Arguments `root` and `depth` are required to select links to follow,
+// relative links are resolved against final URL of document.
+func (p *Parser) worker(ctx context.Context, root *URI, depth uint, t Target) completedTarget {
+	var cached *PageState
+	if page, ok := p.state.Get(t.URI.String()); ok {
+		cached = &page
+	}
 	// if an error occurred, the doc could still be partially exists,
 	// below we will check doc body
-	doc, meta, err := fetchDocument(t.URI, p.requestTimeout)
+	doc, meta, err := p.fetchDocument(ctx, t.URI, cached)
 
 	result := completedTarget{
 		Target:  t,
@@ -198,51 +624,136 @@ func (p *Parser) worker(root *URI, depth uint, t Target) completedTarget {
 		targets: nil,
 	}
 
+	var links []string
+	if meta != nil && meta.NotModified {
+		links = cached.Links
+	} else {
+		docURL := t.URI
+		if meta != nil && meta.FinalURL != "" {
+			if final, err := NewURI(meta.FinalURL); err == nil {
+				docURL = final
+			}
+		}
+		var header []headerLink
+		if meta != nil {
+			header = meta.headerLinks
+		}
+		links = documentLinks(docURL, doc, header, p.links)
+		if meta != nil {
+			meta.Hash = contentHash(doc, p.volatile)
+			meta.NoIndex = meta.NoIndex || documentNoIndex(doc)
+			var warnings []error
+			meta.Modified, warnings = resolveModified(p.modified, doc, meta, cached)
+			for _, w := range warnings {
+				result.warnings = append(result.warnings, &CrawlError{
+					t.URI.String(),
+					ErrorMetadata,
+					fmt.Errorf("%s, %s", t.URI.String(), w),
+				})
+			}
+		}
+	}
+
+	if err == nil && meta != nil {
+		p.state.Set(t.URI.String(), PageState{
+			ETag:         meta.ETag,
+			LastModified: meta.LastModified,
+			Modified:     meta.Modified,
+			Hash:         meta.Hash,
+			Links:        links,
+			NoIndex:      meta.NoIndex,
+		})
+	}
+
+	if meta != nil && len(p.hooks) > 0 {
+		event := LinkEvent{Source: t.URI.String(), Level: t.Level, Links: links, Followed: t.Level < depth}
+		for _, l := range links {
+			if link, err := NewURI(l); err == nil && p.inScope(root, link) {
+				event.InScope = append(event.InScope, l)
+			}
+		}
+		p.onLinks(event)
+	}
+
 	if t.Level >= depth {
 		// stop parsing
 		return result
 	}
 
-	targets := make(chan Target)
-	result.targets = targets
+	result.targets = []Target{}
+	for _, l := range links {
+		link, err := NewURI(l)
+		if err != nil || !p.inScope(root, link) {
+			continue
+		}
+		result.targets = append(result.targets, Target{link, t.Level + 1})
+	}
 
-	go func() {
-		defer close(targets)
-		body := firstNode("body", doc)
-		if body == nil {
-			return
+	return result
+}
+
+// inScope - checks link is allowed to crawl starting from `root` or from any of seeds.
+func (p *Parser) inScope(root, link *URI) bool {
+	if underPrefix(root, link) {
+		return true
+	}
+	for _, s := range p.seeds {
+		if underPrefix(s, link) {
+			return true
 		}
-		base, _ := NewURI(
-			attribute("href", firstNode("base", firstNode("head", doc))),
+	}
+	return false
+}
+
+// underPrefix - checks link belongs to the same host as `prefix` and its path is nested into directory of `prefix`.
+func underPrefix(prefix, link *URI) bool {
+	// TODO Make more reliable verification for nested targets
+	// Add method to URI
+	return prefix.Scheme == link.Scheme &&
+		prefix.Hostname() == link.Hostname() &&
+		strings.HasPrefix(
+			path.Dir(link.EscapedPath()),
+			path.Dir(prefix.EscapedPath()),
 		)
-		for _, href := range collectAttributes("a", "href", body, nil) {
-			var url *url.URL
-			if base != nil {
-				url, _ = base.Parse(href)
-			} else {
-				url, _ = root.Parse(href)
-			}
-			if url == nil {
-				continue
-			}
-			url.Fragment = "" // always drop fragment
-			link, err := NewURI(url.String())
-			if err != nil {
-				continue
-			}
-			if root.Scheme != link.Scheme ||
-				root.Hostname() != link.Hostname() ||
-				!strings.HasPrefix(
-					path.Dir(link.EscapedPath()),
-					path.Dir(root.EscapedPath()),
-				) {
-				// TODO Make more reliable verification for nested targets
-				// Add method to URI
-				continue
-			}
-			targets <- Target{link, t.Level + 1}
+}
+
+// documentLinks - collects absolute links from given sources of document and from links of response header.
+// Relative links of document are resolved against document base URL (see baseURL),
+// links of header are resolved against URL of document.
+func documentLinks(docURL *URI, doc *html.Node, header []headerLink, sources []LinkSource) []string {
+	if firstNode("body", doc) == nil {
+		return nil
+	}
+	links := []string{}
+	base := baseURL(docURL, doc)
+	for _, href := range collectLinks(sources, doc, nil) {
+		if link, ok := resolveLink(base, href); ok {
+			links = append(links, link)
 		}
-	}()
+	}
+	for _, href := range collectHeaderLinks(sources, header, nil) {
+		if link, ok := resolveLink(docURL.URL, href); ok {
+			links = append(links, link)
+		}
+	}
+	return links
+}
 
-	return result
+// resolveLink - returns absolute URI of link without fragment,
+// false is returned if link is not valid or does not refer to document.
+func resolveLink(base *url.URL, href string) (string, bool) {
+	href = stripURLWhitespace(href)
+	if ignoredLink(href) {
+		return "", false
+	}
+	url, err := base.Parse(href)
+	if err != nil {
+		return "", false
+	}
+	url.Fragment = "" // always drop fragment
+	link, err := NewURI(url.String())
+	if err != nil {
+		return "", false
+	}
+	return link.String(), true
 }
@@ -1,10 +1,12 @@
 package sitemap
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"sort"
 	"strings"
 	"testing"
 )
@@ -21,7 +23,7 @@ func TestParser_worker(t *testing.T) {
 	}
 
 	// fetch 0-level page and parse it links
-	c := parser.worker(root, 1, Target{root, 0})
+	c := parser.worker(context.Background(), root, 1, Target{root, 0})
 
 	if c.targets == nil {
 		t.Fatal("Unexpected nil targets")
@@ -39,7 +41,7 @@ func TestParser_worker(t *testing.T) {
 		server.URL + "/homepage.html",
 	}
 	actual := []string{}
-	for target := range c.targets {
+	for _, target := range c.targets {
 		actual = append(actual, target.URI.String())
 		if target.Level != 1 {
 			t.Error("Unexpected level:", target.Level, "for URI:", target.URI.String())
@@ -50,6 +52,200 @@ func TestParser_worker(t *testing.T) {
 	}
 }
 
+func TestParser_workerRedirected(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle("/old.html", http.RedirectHandler("/blog/post.html", http.StatusMovedPermanently))
+	mux.HandleFunc("/blog/post.html", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head></head><body><a href="next.html">next</a></body></html>`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	parser, err := NewParser()
+	if err != nil {
+		t.Fatal("Unexpected NewParser() error:", err)
+	}
+	root, _ := NewURI(server.URL + "/")
+	old, _ := NewURI(server.URL + "/old.html")
+	c := parser.worker(context.Background(), root, 2, Target{old, 1})
+	if c.meta == nil || c.meta.FinalURL != server.URL+"/blog/post.html" {
+		t.Fatalf("Unexpected meta: %+v", c.meta)
+	}
+	// relative link is resolved against final URL of document
+	if len(c.targets) != 1 || c.targets[0].URI.String() != server.URL+"/blog/next.html" {
+		t.Errorf("Unexpected targets: %v", c.targets)
+	}
+}
+
+func TestParser_workerNotModified(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("ETag", `"v2"`)
+		fmt.Fprint(w, `<html><body><a href="/changed.html">changed</a></body></html>`)
+	}))
+	defer server.Close()
+
+	root, _ := NewURI(server.URL + "/")
+	state := NewCrawlState()
+	state.Set(root.String(), PageState{
+		ETag:  `"v1"`,
+		Hash:  "cached",
+		Links: []string{server.URL + "/cached.html"},
+	})
+	parser, err := NewParser(WithCrawlState(state))
+	if err != nil {
+		t.Fatal("Unexpected NewParser() error:", err)
+	}
+
+	c := parser.worker(context.Background(), root, 1, Target{root, 0})
+	if c.err != nil {
+		t.Fatal("Unexpected error:", c.err)
+	}
+	if c.meta == nil || !c.meta.NotModified {
+		t.Fatal("Expected not modified metadata, got:", c.meta)
+	}
+	actual := []string{}
+	for _, target := range c.targets {
+		actual = append(actual, target.URI.String())
+	}
+	expected := []string{server.URL + "/cached.html"}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Error("Expected:", expected, "actual:", actual)
+	}
+
+	// server has changed document
+	state.Set(root.String(), PageState{ETag: `"v0"`})
+	c = parser.worker(context.Background(), root, 1, Target{root, 0})
+	actual = []string{}
+	for _, target := range c.targets {
+		actual = append(actual, target.URI.String())
+	}
+	expected = []string{server.URL + "/changed.html"}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Error("Expected:", expected, "actual:", actual)
+	}
+	page, _ := state.Get(root.String())
+	if page.ETag != `"v2"` || !reflect.DeepEqual(expected, page.Links) {
+		t.Error("State was not updated:", page)
+	}
+}
+
+func TestParser_Stream(t *testing.T) {
+	server := httptest.NewServer(http.FileServer(http.Dir("testdata/simplesite")))
+	defer server.Close()
+
+	handled := make(chan string, 10)
+	parser, err := NewParser(WithResultHandler(func(item MapItem) {
+		handled <- item.URI.String()
+	}))
+	if err != nil {
+		t.Fatal("Unexpected NewParser() error:", err)
+	}
+	root, _ := NewURI(server.URL + "/homepage.html")
+	streamed := []string{}
+	for item := range parser.Stream(context.Background(), root, 1, 2) {
+		streamed = append(streamed, item.URI.String())
+	}
+	close(handled)
+	fromHandler := []string{}
+	for uri := range handled {
+		fromHandler = append(fromHandler, uri)
+	}
+	sort.Strings(streamed)
+	sort.Strings(fromHandler)
+	expected := []string{
+		server.URL + "/faq.html",
+		server.URL + "/homepage.html",
+		server.URL + "/protocol.html",
+		server.URL + "/terms.html",
+	}
+	if !reflect.DeepEqual(expected, streamed) {
+		t.Error("Expected:", expected, "streamed:", streamed)
+	}
+	if !reflect.DeepEqual(expected, fromHandler) {
+		t.Error("Expected:", expected, "handled:", fromHandler)
+	}
+}
+
+func TestParser_StreamSeeds(t *testing.T) {
+	pages := map[string]string{
+		"/blog/index.html": `<a href="/blog/post.html">post</a>`,
+		"/blog/post.html":  `<a href="/blog/index.html">blog</a>`,
+		"/docs/index.html": `<a href="/docs/guide.html">guide</a><a href="/other/page.html">other</a>`,
+		"/docs/guide.html": `<a href="/docs/index.html">docs</a>`,
+		"/other/page.html": `<a href="/blog/index.html">blog</a>`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprintf(w, "<html><head></head><body>%s</body></html>", body)
+	}))
+	defer server.Close()
+
+	if _, err := NewParser(WithSeeds(nil)); err == nil {
+		t.Error("Expected error for nil seed")
+	}
+	root, _ := NewURI(server.URL + "/blog/index.html")
+	seed, _ := NewURI(server.URL + "/docs/index.html")
+	cases := []struct {
+		seeds    []*URI
+		expected []string
+	}{
+		{
+			nil,
+			[]string{server.URL + "/blog/index.html", server.URL + "/blog/post.html"},
+		},
+		{
+			[]*URI{seed, root},
+			[]string{
+				server.URL + "/blog/index.html",
+				server.URL + "/blog/post.html",
+				server.URL + "/docs/guide.html",
+				server.URL + "/docs/index.html",
+			},
+		},
+	}
+	for _, c := range cases {
+		parser, err := NewParser(WithSeeds(c.seeds...))
+		if err != nil {
+			t.Fatal("Unexpected NewParser() error:", err)
+		}
+		found := []string{}
+		for item := range parser.Stream(context.Background(), root, 1, 2) {
+			found = append(found, item.URI.String())
+		}
+		sort.Strings(found)
+		if !reflect.DeepEqual(c.expected, found) {
+			t.Error("Expected:", c.expected, "found:", found)
+		}
+	}
+}
+
+func TestParser_StreamCanceled(t *testing.T) {
+	server := httptest.NewServer(http.FileServer(http.Dir("testdata/simplesite")))
+	defer server.Close()
+
+	parser, err := NewParser()
+	if err != nil {
+		t.Fatal("Unexpected NewParser() error:", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	root, _ := NewURI(server.URL + "/homepage.html")
+	for item := range parser.Stream(ctx, root, 1, 2) {
+		t.Error("Unexpected item for canceled context:", item.URI.String())
+	}
+}
+
 func ExampleParser_Parse() {
 	// This test example allows you not to sort the results.
 	// Otherwise we need to prepend server.URL into expected results
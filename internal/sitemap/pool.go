@@ -0,0 +1,69 @@
+package sitemap
+
+import (
+	"fmt"
+)
+
+// WorkerPool - budget of workers shared between concurrent crawls of several parsers.
+// Every crawl is still limited by own num of workers, but in total no more than pool size workers are running.
+type WorkerPool struct {
+	slots chan struct{}
+}
+
+// NewWorkerPool - builds pool of given size, DefaultNumWorkers is used if size is 0.
+func NewWorkerPool(size uint) *WorkerPool {
+	if size == 0 {
+		size = DefaultNumWorkers
+	}
+	return &WorkerPool{slots: make(chan struct{}, size)}
+}
+
+// Size - returns max num of workers running at the same time.
+func (wp *WorkerPool) Size() uint {
+	if wp == nil {
+		return 0
+	}
+	return uint(cap(wp.slots))
+}
+
+// Busy - returns num of running workers.
+func (wp *WorkerPool) Busy() uint {
+	if wp == nil {
+		return 0
+	}
+	return uint(len(wp.slots))
+}
+
+// acquire - waits for free slot and takes it, nil pool has unlimited slots.
+// Returns false if `done` is closed before slot is taken.
+func (wp *WorkerPool) acquire(done <-chan struct{}) bool {
+	if wp == nil {
+		return true
+	}
+	select {
+	case wp.slots <- struct{}{}:
+		return true
+	case <-done:
+		return false
+	}
+}
+
+// release - returns slot taken with acquire.
+func (wp *WorkerPool) release() {
+	if wp == nil {
+		return
+	}
+	<-wp.slots
+}
+
+// WithWorkerPool - share given pool of workers with other parsers,
+// so concurrent crawls of several sites do not exceed total budget of workers.
+func WithWorkerPool(wp *WorkerPool) ParserOption {
+	if wp == nil {
+		return failedOption(fmt.Errorf("Invalid worker pool (nil)"))
+	}
+	return func(p *Parser) error {
+		p.pool = wp
+		return nil
+	}
+}
@@ -0,0 +1,105 @@
+package sitemap
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWithWorkerPool(t *testing.T) {
+	if _, err := NewParser(WithWorkerPool(nil)); err == nil {
+		t.Error("Expected error for nil pool")
+	}
+	if size := NewWorkerPool(0).Size(); size != DefaultNumWorkers {
+		t.Errorf("Expected default size %d, got %d", DefaultNumWorkers, size)
+	}
+}
+
+func TestWorkerPool_acquire(t *testing.T) {
+	var unlimited *WorkerPool
+	if !unlimited.acquire(nil) {
+		t.Error("Expected nil pool has free slot")
+	}
+
+	pool := NewWorkerPool(1)
+	if !pool.acquire(nil) {
+		t.Fatal("Expected free slot is taken")
+	}
+	done := make(chan struct{})
+	close(done)
+	if pool.acquire(done) {
+		t.Error("Expected slot is not taken from full pool when waiting is cancelled")
+	}
+
+	acquired := make(chan bool)
+	go func() {
+		acquired <- pool.acquire(nil)
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("Expected acquire waits for released slot")
+	case <-time.After(20 * time.Millisecond):
+	}
+	pool.release()
+	select {
+	case ok := <-acquired:
+		if !ok {
+			t.Error("Expected released slot is taken")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected acquire returns after slot is released")
+	}
+	if busy := pool.Busy(); busy != 1 {
+		t.Errorf("Expected 1 busy slot, got %d", busy)
+	}
+}
+
+func TestParser_workerPool(t *testing.T) {
+	running, max := int64(0), int64(0)
+	files := http.FileServer(http.Dir("testdata/simplesite"))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt64(&running, 1)
+		defer atomic.AddInt64(&running, -1)
+		for {
+			m := atomic.LoadInt64(&max)
+			if n <= m || atomic.CompareAndSwapInt64(&max, m, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		files.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	pool := NewWorkerPool(2)
+	wg := sync.WaitGroup{}
+	found := make([]int, 3)
+	for i := range found {
+		parser, err := NewParser(WithWorkerPool(pool))
+		if err != nil {
+			t.Fatal("Unexpected NewParser() error:", err)
+		}
+		root, _ := NewURI(server.URL + "/homepage.html")
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			found[i] = len(parser.Parse(root, 1, 4))
+		}(i)
+	}
+	wg.Wait()
+
+	if max > 2 {
+		t.Errorf("Expected no more than 2 concurrent requests, got %d", max)
+	}
+	for i, n := range found {
+		if n != found[0] || n == 0 {
+			t.Errorf("Unexpected num of items found by parser #%d: %d", i, n)
+		}
+	}
+	if busy := pool.Busy(); busy != 0 {
+		t.Errorf("Expected all slots are released, got %d busy", busy)
+	}
+}
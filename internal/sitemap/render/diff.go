@@ -0,0 +1,95 @@
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/wtask/sitemap/internal/sitemap"
+)
+
+// lastmodFormat - format of modification time in diff output.
+const lastmodFormat = "2006-01-02T15:04:05Z07:00"
+
+// diffItem - site map item prepared to JSON output.
+type diffItem struct {
+	URI     string `json:"uri"`
+	LastMod string `json:"lastmod,omitempty"`
+}
+
+// diffChange - changed item prepared to JSON output.
+type diffChange struct {
+	URI        string `json:"uri"`
+	OldLastMod string `json:"old_lastmod,omitempty"`
+	NewLastMod string `json:"new_lastmod,omitempty"`
+}
+
+// formatLastmod - formats modification time, zero time is formatted as empty string.
+func formatLastmod(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(lastmodFormat)
+}
+
+// itemLastmod - returns formatted modification time of site map item.
+func itemLastmod(item sitemap.MapItem) string {
+	if item.DocumentMeta == nil {
+		return ""
+	}
+	return formatLastmod(item.Modified)
+}
+
+// DiffText - writes difference between site maps as plain text:
+// added URIs are prefixed with "+", removed ones with "-" and URIs with changed lastmod with "~".
+// The last line contains totals.
+func DiffText(writer io.Writer, d sitemap.MapDiff) error {
+	items := func(prefix string, m []sitemap.MapItem) error {
+		for _, item := range m {
+			line := prefix + " " + item.URI.String()
+			if lastmod := itemLastmod(item); lastmod != "" {
+				line += " " + lastmod
+			}
+			if _, err := fmt.Fprintln(writer, line); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := items("+", d.Added); err != nil {
+		return err
+	}
+	if err := items("-", d.Removed); err != nil {
+		return err
+	}
+	for _, c := range d.Changed {
+		_, err := fmt.Fprintf(writer, "~ %s %q -> %q\n", c.URI.String(), formatLastmod(c.Old), formatLastmod(c.New))
+		if err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(writer, "Added: %d, removed: %d, changed: %d\n", len(d.Added), len(d.Removed), len(d.Changed))
+	return err
+}
+
+// DiffJSON - writes difference between site maps in JSON format.
+func DiffJSON(writer io.Writer, d sitemap.MapDiff) error {
+	data := struct {
+		Added   []diffItem   `json:"added"`
+		Removed []diffItem   `json:"removed"`
+		Changed []diffChange `json:"changed"`
+	}{[]diffItem{}, []diffItem{}, []diffChange{}}
+	for _, item := range d.Added {
+		data.Added = append(data.Added, diffItem{item.URI.String(), itemLastmod(item)})
+	}
+	for _, item := range d.Removed {
+		data.Removed = append(data.Removed, diffItem{item.URI.String(), itemLastmod(item)})
+	}
+	for _, c := range d.Changed {
+		data.Changed = append(data.Changed, diffChange{c.URI.String(), formatLastmod(c.Old), formatLastmod(c.New)})
+	}
+	enc := json.NewEncoder(writer)
+	enc.SetIndent("", "  ")
+	return enc.Encode(data)
+}
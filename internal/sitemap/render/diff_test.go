@@ -0,0 +1,94 @@
+package render
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/wtask/sitemap/internal/sitemap"
+)
+
+func testDiff() sitemap.MapDiff {
+	uri := func(source string) *sitemap.URI {
+		u, err := sitemap.NewURI(source)
+		if err != nil {
+			panic(err)
+		}
+		return u
+	}
+	modified := time.Date(2019, 5, 21, 23, 26, 0, 0, time.UTC)
+	return sitemap.Diff(
+		[]sitemap.MapItem{
+			{URI: uri("http://localhost/"), DocumentMeta: &sitemap.DocumentMeta{Modified: modified}},
+			{URI: uri("http://localhost/faq.html"), DocumentMeta: &sitemap.DocumentMeta{Modified: modified}},
+			{URI: uri("http://localhost/terms.html"), DocumentMeta: nil},
+		},
+		[]sitemap.MapItem{
+			{URI: uri("http://localhost/"), DocumentMeta: &sitemap.DocumentMeta{Modified: modified.Add(time.Hour)}},
+			{URI: uri("http://localhost/protocol.html"), DocumentMeta: &sitemap.DocumentMeta{Modified: modified}},
+			{URI: uri("http://localhost/terms.html"), DocumentMeta: &sitemap.DocumentMeta{Modified: modified}},
+		},
+	)
+}
+
+func ExampleDiffText() {
+	err := DiffText(os.Stdout, testDiff())
+	if err != nil {
+		panic(fmt.Errorf("Unexpected error: %s", err))
+	}
+
+	// Output:
+	// + http://localhost/protocol.html 2019-05-21T23:26:00Z
+	// - http://localhost/faq.html 2019-05-21T23:26:00Z
+	// ~ http://localhost/ "2019-05-21T23:26:00Z" -> "2019-05-22T00:26:00Z"
+	// ~ http://localhost/terms.html "" -> "2019-05-21T23:26:00Z"
+	// Added: 1, removed: 1, changed: 2
+}
+
+func ExampleDiffJSON() {
+	err := DiffJSON(os.Stdout, testDiff())
+	if err != nil {
+		panic(fmt.Errorf("Unexpected error: %s", err))
+	}
+
+	// Output:
+	// {
+	//   "added": [
+	//     {
+	//       "uri": "http://localhost/protocol.html",
+	//       "lastmod": "2019-05-21T23:26:00Z"
+	//     }
+	//   ],
+	//   "removed": [
+	//     {
+	//       "uri": "http://localhost/faq.html",
+	//       "lastmod": "2019-05-21T23:26:00Z"
+	//     }
+	//   ],
+	//   "changed": [
+	//     {
+	//       "uri": "http://localhost/",
+	//       "old_lastmod": "2019-05-21T23:26:00Z",
+	//       "new_lastmod": "2019-05-22T00:26:00Z"
+	//     },
+	//     {
+	//       "uri": "http://localhost/terms.html",
+	//       "new_lastmod": "2019-05-21T23:26:00Z"
+	//     }
+	//   ]
+	// }
+}
+
+func ExampleDiffJSON_empty() {
+	err := DiffJSON(os.Stdout, sitemap.Diff(nil, nil))
+	if err != nil {
+		panic(fmt.Errorf("Unexpected error: %s", err))
+	}
+
+	// Output:
+	// {
+	//   "added": [],
+	//   "removed": [],
+	//   "changed": []
+	// }
+}
@@ -0,0 +1,185 @@
+package render
+
+import (
+	"encoding/json"
+	"io"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/wtask/sitemap/internal/sitemap"
+)
+
+const (
+	dotGraph = `digraph sitemap {
+	node [shape=box];
+{{- range $i, $c := .Clusters }}
+	{{- if $c.Name }}
+	subgraph {{ quote (printf "cluster_%d" $i) }} {
+		label={{ quote $c.Name }};
+		{{- range $c.Nodes }}
+		{{ quote .URI }};
+		{{- end }}
+	}
+	{{- else }}
+		{{- range $c.Nodes }}
+	{{ quote .URI }};
+		{{- end }}
+	{{- end }}
+{{- end }}
+{{- range .Edges }}
+	{{ quote .Source }} -> {{ quote .Target }};
+{{- end }}
+}
+`
+	graphML = `<?xml version="1.0" encoding="UTF-8"?>
+<graphml xmlns="http://graphml.graphdrawing.org/xmlns">
+	<key id="uri" for="node" attr.name="uri" attr.type="string"/>
+	<key id="level" for="node" attr.name="level" attr.type="int"/>
+	{{- if .Clustered }}
+	<key id="cluster" for="node" attr.name="cluster" attr.type="string"/>
+	{{- end }}
+	<graph id="sitemap" edgedefault="directed">
+	{{- $clustered := .Clustered }}
+	{{- range .Nodes }}
+		<node id="n{{ .ID }}">
+			<data key="uri">{{ html .URI }}</data>
+			<data key="level">{{ .Level }}</data>
+			{{- if $clustered }}
+			<data key="cluster">{{ html .Cluster }}</data>
+			{{- end }}
+		</node>
+	{{- end }}
+	{{- range .Links }}
+		<edge source="n{{ .Source }}" target="n{{ .Target }}"/>
+	{{- end }}
+	</graph>
+</graphml>
+`
+)
+
+var graph *template.Template
+
+func init() {
+	graph = template.Must(template.New("dot").Funcs(template.FuncMap{"quote": dotQuote}).Parse(dotGraph))
+	graph = template.Must(graph.New("graphml").Parse(graphML))
+}
+
+// graphNode - node of link graph prepared to render.
+type graphNode struct {
+	ID      int      `json:"-"`
+	URI     string   `json:"uri"`
+	Level   uint     `json:"level"`
+	Cluster string   `json:"cluster,omitempty"`
+	Links   []string `json:"links"`
+}
+
+// graphCluster - group of nodes with common path prefix.
+type graphCluster struct {
+	Name  string
+	Nodes []*graphNode
+}
+
+// graphData - link graph prepared to render.
+type graphData struct {
+	Clustered bool
+	Nodes     []*graphNode
+	Clusters  []graphCluster // sorted by name
+	Edges     []sitemap.GraphEdge
+	Links     []struct{ Source, Target int }
+}
+
+// buildGraphData - groups nodes into clusters and resolves edges into node identifiers.
+// Edges between unknown nodes are skipped.
+func buildGraphData(nodes []sitemap.GraphNode, edges []sitemap.GraphEdge, clusterDepth uint) graphData {
+	data := graphData{Clustered: clusterDepth > 0}
+	index := map[string]*graphNode{}
+	clusters := map[string][]*graphNode{}
+	for i, n := range nodes {
+		node := &graphNode{ID: i, URI: n.URI, Level: n.Level, Links: []string{}}
+		if data.Clustered {
+			node.Cluster = pathCluster(n.URI, clusterDepth)
+		}
+		index[n.URI] = node
+		data.Nodes = append(data.Nodes, node)
+		clusters[node.Cluster] = append(clusters[node.Cluster], node)
+	}
+	names := make([]string, 0, len(clusters))
+	for name := range clusters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		data.Clusters = append(data.Clusters, graphCluster{name, clusters[name]})
+	}
+	for _, e := range edges {
+		source, target := index[e.Source], index[e.Target]
+		if source == nil || target == nil {
+			continue
+		}
+		source.Links = append(source.Links, target.URI)
+		data.Edges = append(data.Edges, e)
+		data.Links = append(data.Links, struct{ Source, Target int }{source.ID, target.ID})
+	}
+	return data
+}
+
+// pathCluster - returns prefix of URI path which consists of no more than `depth` directories.
+// For example, "http://localhost/a/b/c.html" belongs to "/a/" cluster with depth 1 and to "/a/b/" with depth 2.
+func pathCluster(uri string, depth uint) string {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "/"
+	}
+	dir := u.Path
+	if !strings.HasSuffix(dir, "/") {
+		dir = path.Dir(dir)
+	}
+	segments := []string{}
+	for _, s := range strings.Split(dir, "/") {
+		if s == "" || s == "." {
+			continue
+		}
+		if uint(len(segments)) == depth {
+			break
+		}
+		segments = append(segments, s)
+	}
+	if len(segments) == 0 {
+		return "/"
+	}
+	return "/" + strings.Join(segments, "/") + "/"
+}
+
+// dotQuote - quotes identifier as required by DOT language.
+func dotQuote(id string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(id) + `"`
+}
+
+// GraphDOT - writes link graph in Graphviz DOT format with given writer.
+// If `clusterDepth` is greater than 0, nodes are grouped into clusters by path prefix of given num of directories.
+func GraphDOT(writer io.Writer, nodes []sitemap.GraphNode, edges []sitemap.GraphEdge, clusterDepth uint) error {
+	return graph.Lookup("dot").Execute(writer, buildGraphData(nodes, edges, clusterDepth))
+}
+
+// GraphML - writes link graph in GraphML format with given writer.
+// If `clusterDepth` is greater than 0, every node has "cluster" attribute with its path prefix.
+func GraphML(writer io.Writer, nodes []sitemap.GraphNode, edges []sitemap.GraphEdge, clusterDepth uint) error {
+	return graph.Lookup("graphml").Execute(writer, buildGraphData(nodes, edges, clusterDepth))
+}
+
+// GraphJSON - writes link graph in JSON format as adjacency lists with given writer.
+// If `clusterDepth` is greater than 0, every node has "cluster" field with its path prefix.
+func GraphJSON(writer io.Writer, nodes []sitemap.GraphNode, edges []sitemap.GraphEdge, clusterDepth uint) error {
+	data := buildGraphData(nodes, edges, clusterDepth)
+	if data.Nodes == nil {
+		data.Nodes = []*graphNode{}
+	}
+	enc := json.NewEncoder(writer)
+	enc.SetIndent("", "  ")
+	return enc.Encode(struct {
+		Nodes []*graphNode `json:"nodes"`
+	}{data.Nodes})
+}
@@ -0,0 +1,153 @@
+package render
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/wtask/sitemap/internal/sitemap"
+)
+
+var (
+	testGraphNodes = []sitemap.GraphNode{
+		{URI: "http://localhost/", Level: 0},
+		{URI: "http://localhost/docs/faq.html", Level: 1},
+		{URI: "http://localhost/docs/api/index.html", Level: 2},
+	}
+	testGraphEdges = []sitemap.GraphEdge{
+		{Source: "http://localhost/", Target: "http://localhost/docs/faq.html"},
+		{Source: "http://localhost/docs/faq.html", Target: "http://localhost/"},
+		{Source: "http://localhost/docs/faq.html", Target: "http://localhost/docs/api/index.html"},
+		// should be skipped
+		{Source: "http://localhost/", Target: "http://localhost/unknown.html"},
+	}
+)
+
+func ExampleGraphDOT() {
+	err := GraphDOT(os.Stdout, testGraphNodes, testGraphEdges, 1)
+	if err != nil {
+		panic(fmt.Errorf("Unexpected error: %s", err))
+	}
+
+	// Output:
+	// digraph sitemap {
+	// 	node [shape=box];
+	// 	subgraph "cluster_0" {
+	// 		label="/";
+	// 		"http://localhost/";
+	// 	}
+	// 	subgraph "cluster_1" {
+	// 		label="/docs/";
+	// 		"http://localhost/docs/faq.html";
+	// 		"http://localhost/docs/api/index.html";
+	// 	}
+	// 	"http://localhost/" -> "http://localhost/docs/faq.html";
+	// 	"http://localhost/docs/faq.html" -> "http://localhost/";
+	// 	"http://localhost/docs/faq.html" -> "http://localhost/docs/api/index.html";
+	// }
+}
+
+func ExampleGraphDOT_withoutClusters() {
+	err := GraphDOT(os.Stdout, testGraphNodes[:2], testGraphEdges[:1], 0)
+	if err != nil {
+		panic(fmt.Errorf("Unexpected error: %s", err))
+	}
+
+	// Output:
+	// digraph sitemap {
+	// 	node [shape=box];
+	// 	"http://localhost/";
+	// 	"http://localhost/docs/faq.html";
+	// 	"http://localhost/" -> "http://localhost/docs/faq.html";
+	// }
+}
+
+func ExampleGraphML() {
+	err := GraphML(os.Stdout, testGraphNodes[:2], testGraphEdges, 2)
+	if err != nil {
+		panic(fmt.Errorf("Unexpected error: %s", err))
+	}
+
+	// Output:
+	// <?xml version="1.0" encoding="UTF-8"?>
+	// <graphml xmlns="http://graphml.graphdrawing.org/xmlns">
+	// 	<key id="uri" for="node" attr.name="uri" attr.type="string"/>
+	// 	<key id="level" for="node" attr.name="level" attr.type="int"/>
+	// 	<key id="cluster" for="node" attr.name="cluster" attr.type="string"/>
+	// 	<graph id="sitemap" edgedefault="directed">
+	// 		<node id="n0">
+	// 			<data key="uri">http://localhost/</data>
+	// 			<data key="level">0</data>
+	// 			<data key="cluster">/</data>
+	// 		</node>
+	// 		<node id="n1">
+	// 			<data key="uri">http://localhost/docs/faq.html</data>
+	// 			<data key="level">1</data>
+	// 			<data key="cluster">/docs/</data>
+	// 		</node>
+	// 		<edge source="n0" target="n1"/>
+	// 		<edge source="n1" target="n0"/>
+	// 	</graph>
+	// </graphml>
+}
+
+func ExampleGraphJSON() {
+	err := GraphJSON(os.Stdout, testGraphNodes[:2], testGraphEdges, 0)
+	if err != nil {
+		panic(fmt.Errorf("Unexpected error: %s", err))
+	}
+
+	// Output:
+	// {
+	//   "nodes": [
+	//     {
+	//       "uri": "http://localhost/",
+	//       "level": 0,
+	//       "links": [
+	//         "http://localhost/docs/faq.html"
+	//       ]
+	//     },
+	//     {
+	//       "uri": "http://localhost/docs/faq.html",
+	//       "level": 1,
+	//       "links": [
+	//         "http://localhost/"
+	//       ]
+	//     }
+	//   ]
+	// }
+}
+
+func ExampleGraphJSON_empty() {
+	err := GraphJSON(os.Stdout, nil, nil, 1)
+	if err != nil {
+		panic(fmt.Errorf("Unexpected error: %s", err))
+	}
+
+	// Output:
+	// {
+	//   "nodes": []
+	// }
+}
+
+func Test_pathCluster(t *testing.T) {
+	cases := []struct {
+		uri      string
+		depth    uint
+		expected string
+	}{
+		{"http://localhost", 1, "/"},
+		{"http://localhost/", 1, "/"},
+		{"http://localhost/faq.html", 2, "/"},
+		{"http://localhost/a/", 1, "/a/"},
+		{"http://localhost/a/b/c.html", 1, "/a/"},
+		{"http://localhost/a/b/c.html", 2, "/a/b/"},
+		{"http://localhost/a/b/c.html", 3, "/a/b/"},
+		{"http://localhost/a/b/?q=1", 5, "/a/b/"},
+	}
+	for _, c := range cases {
+		if actual := pathCluster(c.uri, c.depth); actual != c.expected {
+			t.Errorf("pathCluster(%q, %d): expected %q, got %q", c.uri, c.depth, c.expected, actual)
+		}
+	}
+}
@@ -9,9 +9,9 @@ import (
 )
 
 const (
-	xmlMap = `<?xml version="1.0" encoding="UTF-8"?>
-<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
-{{- range . }}
+	xmlMapHeader = `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`
+	xmlMapURL = `
 	{{- if .URI }}
 	<url>
 		<loc>{{ .URI.String }}</loc>
@@ -21,8 +21,8 @@ const (
 			{{- end }}
 		{{- end }}
 	</url>
-	{{- end}}
-{{- end }}
+	{{- end }}`
+	xmlMapFooter = `
 </urlset>
 `
 	xmlIndex = `<?xml version="1.0" encoding="UTF-8"?>
@@ -45,13 +45,46 @@ const (
 var xml *template.Template
 
 func init() {
-	xml = template.Must(template.New("map").Parse(xmlMap))
+	xml = template.Must(template.New("url").Parse(xmlMapURL))
 	xml = template.Must(xml.New("index").Parse(xmlIndex))
 }
 
 // XMLMap - writes site map in XML format with given writer.
 func XMLMap(writer io.Writer, m []sitemap.MapItem) error {
-	return xml.Lookup("map").Execute(writer, m)
+	w, err := NewXMLMapWriter(writer)
+	if err != nil {
+		return err
+	}
+	for _, item := range m {
+		if err := w.Write(item); err != nil {
+			return err
+		}
+	}
+	return w.Close()
+}
+
+// XMLMapWriter - writes site map in XML format item by item, so the whole map is not kept in memory.
+type XMLMapWriter struct {
+	writer io.Writer
+}
+
+// NewXMLMapWriter - writes header of site map with given writer and returns writer of map items.
+func NewXMLMapWriter(writer io.Writer) (*XMLMapWriter, error) {
+	if _, err := io.WriteString(writer, xmlMapHeader); err != nil {
+		return nil, err
+	}
+	return &XMLMapWriter{writer}, nil
+}
+
+// Write - writes single site map item, items without URI are skipped.
+func (w *XMLMapWriter) Write(item sitemap.MapItem) error {
+	return xml.Lookup("url").Execute(w.writer, item)
+}
+
+// Close - writes footer of site map, underlying writer is not closed.
+func (w *XMLMapWriter) Close() error {
+	_, err := io.WriteString(w.writer, xmlMapFooter)
+	return err
 }
 
 // XMLIndex - writes site map index in XML format with given writer.
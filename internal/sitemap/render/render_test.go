@@ -18,7 +18,7 @@ func init() {
 	}
 }
 
-func ExampleEmptyXMLMap() {
+func ExampleXMLMap_empty() {
 	err := XMLMap(os.Stdout, []sitemap.MapItem{})
 	if err != nil {
 		panic(fmt.Errorf("Unexpected error: %s", err))
@@ -42,25 +42,25 @@ func ExampleXMLMap() {
 		os.Stdout,
 		[]sitemap.MapItem{
 			sitemap.MapItem{
-				uri("http://localhost/"),
-				&sitemap.DocumentMeta{Modified: time.Time{}},
+				URI:          uri("http://localhost/"),
+				DocumentMeta: &sitemap.DocumentMeta{Modified: time.Time{}},
 			},
 			sitemap.MapItem{
-				uri("http://localhost/homepage.html"),
-				&sitemap.DocumentMeta{Modified: time.Date(2019, 5, 21, 23, 26, 0, 0, time.UTC)},
+				URI:          uri("http://localhost/homepage.html"),
+				DocumentMeta: &sitemap.DocumentMeta{Modified: time.Date(2019, 5, 21, 23, 26, 0, 0, time.UTC)},
 			},
 			sitemap.MapItem{
-				uri("http://localhost/protocol.html"),
-				&sitemap.DocumentMeta{Modified: time.Date(2019, 5, 21, 23, 26, 0, 0, MoscowTZ)},
+				URI:          uri("http://localhost/protocol.html"),
+				DocumentMeta: &sitemap.DocumentMeta{Modified: time.Date(2019, 5, 21, 23, 26, 0, 0, MoscowTZ)},
 			},
 			sitemap.MapItem{
 				//  should be no output
-				nil,
-				&sitemap.DocumentMeta{Modified: time.Date(2019, 5, 21, 23, 26, 0, 0, time.UTC)},
+				URI:          nil,
+				DocumentMeta: &sitemap.DocumentMeta{Modified: time.Date(2019, 5, 21, 23, 26, 0, 0, time.UTC)},
 			},
 			sitemap.MapItem{
-				uri("http://localhost/faq.html"),
-				nil,
+				URI:          uri("http://localhost/faq.html"),
+				DocumentMeta: nil,
 			},
 		},
 	)
@@ -88,7 +88,34 @@ func ExampleXMLMap() {
 	// </urlset>
 }
 
-func ExampleEmptyXMLIndex() {
+func ExampleXMLMapWriter() {
+	w, err := NewXMLMapWriter(os.Stdout)
+	if err != nil {
+		panic(err)
+	}
+	for _, source := range []string{"http://localhost/", "http://localhost/faq.html"} {
+		uri, _ := sitemap.NewURI(source)
+		if err := w.Write(sitemap.MapItem{URI: uri, DocumentMeta: nil}); err != nil {
+			panic(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		panic(err)
+	}
+
+	// Output:
+	// <?xml version="1.0" encoding="UTF-8"?>
+	// <urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+	//	<url>
+	//		<loc>http://localhost/</loc>
+	//	</url>
+	//	<url>
+	//		<loc>http://localhost/faq.html</loc>
+	//	</url>
+	// </urlset>
+}
+
+func ExampleXMLIndex_empty() {
 	err := XMLIndex(os.Stdout, time.Time{}, nil)
 	if err != nil {
 		panic(err)
@@ -100,7 +127,7 @@ func ExampleEmptyXMLIndex() {
 	// </sitemapindex>
 }
 
-func ExampleXMLIndexWithoutTime() {
+func ExampleXMLIndex_withoutTime() {
 	err := XMLIndex(
 		os.Stdout,
 		time.Time{},
@@ -126,7 +153,7 @@ func ExampleXMLIndexWithoutTime() {
 	// </sitemapindex>
 }
 
-func ExampleXMLIndexWithTime() {
+func ExampleXMLIndex_withTime() {
 	err := XMLIndex(
 		os.Stdout,
 		time.Date(2019, 5, 21, 23, 26, 0, 0, MoscowTZ),
@@ -154,7 +181,7 @@ func ExampleXMLIndexWithTime() {
 	// </sitemapindex>
 }
 
-func ExampleXMLIndexWithUTCTime() {
+func ExampleXMLIndex_withUTCTime() {
 	err := XMLIndex(
 		os.Stdout,
 		time.Date(2019, 5, 21, 23, 26, 0, 0, time.UTC),
@@ -0,0 +1,3 @@
+// Package report collects link graph and responses while site is crawled
+// and builds crawl report: broken links, redirects, non-HTML responses, pages beyond max depth and inbound links.
+package report
@@ -0,0 +1,248 @@
+package report
+
+import (
+	"encoding/json"
+	"html/template"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/wtask/sitemap/internal/sitemap"
+)
+
+// Entry - single URI listed in report.
+type Entry struct {
+	// URI - requested or linked URI
+	URI string `json:"uri"`
+	// StatusCode - status code of response, 0 if response was not received or URI was not requested
+	StatusCode int `json:"status_code,omitempty"`
+	// ContentType - value of Content-Type header of response
+	ContentType string `json:"content_type,omitempty"`
+	// Error - message of error occurred while URI was fetched
+	Error string `json:"error,omitempty"`
+	// Redirects - chain of URIs to which request was redirected, the last one is final
+	Redirects []string `json:"redirects,omitempty"`
+	// Referrers - URIs of documents which link to this URI
+	Referrers []string `json:"referrers"`
+}
+
+// Inbound - num of documents which link to URI.
+type Inbound struct {
+	URI   string `json:"uri"`
+	Count int    `json:"count"`
+}
+
+// Report - results of crawl analysis.
+// Every list is sorted by URI, inbound links are sorted by count in descending order.
+type Report struct {
+	// Generated - time when report was built
+	Generated time.Time `json:"generated"`
+	// Broken - URIs which can not be fetched due to network errors or unexpected status codes
+	Broken []Entry `json:"broken_links"`
+	// Redirects - URIs which were redirected
+	Redirects []Entry `json:"redirects"`
+	// NonHTML - URIs which responded with content type other than HTML
+	NonHTML []Entry `json:"non_html"`
+	// BeyondDepth - in-scope URIs which were not crawled because they are linked only from documents at max depth
+	BeyondDepth []Entry `json:"beyond_depth"`
+	// Inbound - inbound link counts for every crawled or linked in-scope URI
+	Inbound []Inbound `json:"inbound_links"`
+}
+
+// Collector - accumulates parser events to build report.
+// Use Hooks to register collector with sitemap.WithHooks option.
+type Collector struct {
+	mx        sync.Mutex
+	fetches   map[string]sitemap.FetchEvent
+	referrers map[string]map[string]bool // target -> set of sources
+	inScope   map[string]bool            // in-scope targets
+	beyond    map[string]bool            // in-scope targets linked from documents at max depth
+}
+
+// NewCollector - builds empty collector.
+func NewCollector() *Collector {
+	return &Collector{
+		fetches:   map[string]sitemap.FetchEvent{},
+		referrers: map[string]map[string]bool{},
+		inScope:   map[string]bool{},
+		beyond:    map[string]bool{},
+	}
+}
+
+// Hooks - returns parser hooks to collect events.
+func (c *Collector) Hooks() sitemap.Hooks {
+	return sitemap.Hooks{OnFetch: c.observeFetch, OnLinks: c.observeLinks}
+}
+
+// observeFetch - remembers completed request.
+func (c *Collector) observeFetch(e sitemap.FetchEvent) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	c.fetches[e.URI] = e
+}
+
+// observeLinks - remembers outgoing links of document.
+// Links to document itself are ignored.
+func (c *Collector) observeLinks(e sitemap.LinkEvent) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	for _, l := range e.Links {
+		if l == e.Source {
+			continue
+		}
+		if c.referrers[l] == nil {
+			c.referrers[l] = map[string]bool{}
+		}
+		c.referrers[l][e.Source] = true
+	}
+	for _, l := range e.InScope {
+		c.inScope[l] = true
+		if !e.Followed {
+			c.beyond[l] = true
+		}
+	}
+	c.inScope[e.Source] = true
+}
+
+// Report - builds report from collected events.
+func (c *Collector) Report() *Report {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	r := &Report{
+		Generated:   time.Now(),
+		Broken:      []Entry{},
+		Redirects:   []Entry{},
+		NonHTML:     []Entry{},
+		BeyondDepth: []Entry{},
+		Inbound:     []Inbound{},
+	}
+	for uri, e := range c.fetches {
+		entry := Entry{
+			URI:         uri,
+			StatusCode:  e.StatusCode,
+			ContentType: e.ContentType,
+			Redirects:   e.Redirects,
+			Referrers:   c.sortedReferrers(uri),
+		}
+		if e.Err != nil {
+			entry.Error = e.Err.Error()
+		}
+		if len(e.Redirects) > 0 {
+			r.Redirects = append(r.Redirects, entry)
+		}
+		if ce, ok := e.Err.(*sitemap.CrawlError); ok {
+			switch ce.Class {
+			case sitemap.ErrorRequest, sitemap.ErrorStatus:
+				r.Broken = append(r.Broken, entry)
+			case sitemap.ErrorContentType:
+				r.NonHTML = append(r.NonHTML, entry)
+			}
+		}
+	}
+	for uri := range c.beyond {
+		if _, fetched := c.fetches[uri]; fetched {
+			continue
+		}
+		r.BeyondDepth = append(r.BeyondDepth, Entry{URI: uri, Referrers: c.sortedReferrers(uri)})
+	}
+	for uri := range c.inScope {
+		r.Inbound = append(r.Inbound, Inbound{uri, len(c.referrers[uri])})
+	}
+
+	for _, entries := range [][]Entry{r.Broken, r.Redirects, r.NonHTML, r.BeyondDepth} {
+		sort.Slice(entries, func(i, j int) bool { return entries[i].URI < entries[j].URI })
+	}
+	sort.Slice(r.Inbound, func(i, j int) bool {
+		if r.Inbound[i].Count != r.Inbound[j].Count {
+			return r.Inbound[i].Count > r.Inbound[j].Count
+		}
+		return r.Inbound[i].URI < r.Inbound[j].URI
+	})
+	return r
+}
+
+// sortedReferrers - returns sorted list of documents which link to given URI.
+func (c *Collector) sortedReferrers(uri string) []string {
+	referrers := make([]string, 0, len(c.referrers[uri]))
+	for source := range c.referrers[uri] {
+		referrers = append(referrers, source)
+	}
+	sort.Strings(referrers)
+	return referrers
+}
+
+// WriteJSON - writes report in JSON format with given writer.
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+const htmlReport = `<!doctype html>
+<html lang="en">
+<head>
+	<meta charset="utf-8">
+	<title>Crawl report</title>
+	<style>
+		body { font-family: sans-serif; }
+		table { border-collapse: collapse; margin-bottom: 2em; }
+		th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; vertical-align: top; }
+		ul { margin: 0; padding-left: 1.2em; }
+	</style>
+</head>
+<body>
+	<h1>Crawl report</h1>
+	<p>Generated: {{ .Generated.Format "2006-01-02T15:04:05Z07:00" }}</p>
+	{{ template "entries" (section "Broken links" .Broken) }}
+	{{ template "entries" (section "Redirects" .Redirects) }}
+	{{ template "entries" (section "Non-HTML responses" .NonHTML) }}
+	{{ template "entries" (section "Pages beyond max depth" .BeyondDepth) }}
+	<h2>Inbound links ({{ len .Inbound }})</h2>
+	{{- if .Inbound }}
+	<table>
+		<tr><th>URI</th><th>Count</th></tr>
+		{{- range .Inbound }}
+		<tr><td>{{ .URI }}</td><td>{{ .Count }}</td></tr>
+		{{- end }}
+	</table>
+	{{- end }}
+</body>
+</html>
+{{ define "entries" }}
+	<h2>{{ .Title }} ({{ len .Entries }})</h2>
+	{{- if .Entries }}
+	<table>
+		<tr><th>URI</th><th>Status</th><th>Details</th><th>Referrers</th></tr>
+		{{- range .Entries }}
+		<tr>
+			<td>{{ .URI }}</td>
+			<td>{{ if .StatusCode }}{{ .StatusCode }}{{ end }}</td>
+			<td>
+				{{- if .Error }}{{ .Error }}{{ else if .ContentType }}{{ .ContentType }}{{ end }}
+				{{- if .Redirects }}
+				<ul>{{ range .Redirects }}<li>{{ . }}</li>{{ end }}</ul>
+				{{- end }}
+			</td>
+			<td><ul>{{ range .Referrers }}<li>{{ . }}</li>{{ end }}</ul></td>
+		</tr>
+		{{- end }}
+	</table>
+	{{- end }}
+{{ end }}
+`
+
+var page = template.Must(template.New("report").Funcs(template.FuncMap{
+	"section": func(title string, entries []Entry) interface{} {
+		return struct {
+			Title   string
+			Entries []Entry
+		}{title, entries}
+	},
+}).Parse(htmlReport))
+
+// WriteHTML - writes report as HTML document with given writer.
+func (r *Report) WriteHTML(w io.Writer) error {
+	return page.Execute(w, r)
+}
@@ -0,0 +1,140 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/wtask/sitemap/internal/sitemap"
+)
+
+func testCollector() *Collector {
+	c := NewCollector()
+	h := c.Hooks()
+	h.OnFetch(sitemap.FetchEvent{URI: "http://localhost/", StatusCode: 200, ContentType: "text/html"})
+	h.OnFetch(sitemap.FetchEvent{
+		URI:        "http://localhost/missing.html",
+		StatusCode: 404,
+		Err:        &sitemap.CrawlError{Class: sitemap.ErrorStatus, Err: errors.New("not found")},
+	})
+	h.OnFetch(sitemap.FetchEvent{
+		URI:         "http://localhost/old.html",
+		StatusCode:  200,
+		ContentType: "text/html",
+		Redirects:   []string{"http://localhost/new.html"},
+	})
+	h.OnFetch(sitemap.FetchEvent{
+		URI:         "http://localhost/doc.pdf",
+		StatusCode:  200,
+		ContentType: "application/pdf",
+		Err:         &sitemap.CrawlError{Class: sitemap.ErrorContentType, Err: errors.New("invalid")},
+	})
+	h.OnLinks(sitemap.LinkEvent{
+		Source: "http://localhost/",
+		Level:  0,
+		Links: []string{
+			"http://localhost/",
+			"http://localhost/missing.html",
+			"http://localhost/old.html",
+			"http://localhost/doc.pdf",
+			"http://example.com/",
+		},
+		InScope: []string{
+			"http://localhost/",
+			"http://localhost/missing.html",
+			"http://localhost/old.html",
+			"http://localhost/doc.pdf",
+		},
+		Followed: true,
+	})
+	h.OnLinks(sitemap.LinkEvent{
+		Source:   "http://localhost/old.html",
+		Level:    1,
+		Links:    []string{"http://localhost/", "http://localhost/deep.html", "http://localhost/missing.html"},
+		InScope:  []string{"http://localhost/", "http://localhost/deep.html", "http://localhost/missing.html"},
+		Followed: false,
+	})
+	return c
+}
+
+func TestCollector_Report(t *testing.T) {
+	r := testCollector().Report()
+
+	expectedBroken := []Entry{{
+		URI:        "http://localhost/missing.html",
+		StatusCode: 404,
+		Error:      "not found",
+		Referrers:  []string{"http://localhost/", "http://localhost/old.html"},
+	}}
+	if !reflect.DeepEqual(r.Broken, expectedBroken) {
+		t.Errorf("Unexpected broken links: %+v", r.Broken)
+	}
+	expectedRedirects := []Entry{{
+		URI:         "http://localhost/old.html",
+		StatusCode:  200,
+		ContentType: "text/html",
+		Redirects:   []string{"http://localhost/new.html"},
+		Referrers:   []string{"http://localhost/"},
+	}}
+	if !reflect.DeepEqual(r.Redirects, expectedRedirects) {
+		t.Errorf("Unexpected redirects: %+v", r.Redirects)
+	}
+	if len(r.NonHTML) != 1 || r.NonHTML[0].URI != "http://localhost/doc.pdf" ||
+		r.NonHTML[0].ContentType != "application/pdf" {
+		t.Errorf("Unexpected non-HTML responses: %+v", r.NonHTML)
+	}
+	expectedBeyond := []Entry{{URI: "http://localhost/deep.html", Referrers: []string{"http://localhost/old.html"}}}
+	if !reflect.DeepEqual(r.BeyondDepth, expectedBeyond) {
+		t.Errorf("Unexpected pages beyond depth: %+v", r.BeyondDepth)
+	}
+	expectedInbound := []Inbound{
+		{"http://localhost/missing.html", 2},
+		{"http://localhost/", 1},
+		{"http://localhost/deep.html", 1},
+		{"http://localhost/doc.pdf", 1},
+		{"http://localhost/old.html", 1},
+	}
+	if !reflect.DeepEqual(r.Inbound, expectedInbound) {
+		t.Errorf("Unexpected inbound links: %+v", r.Inbound)
+	}
+}
+
+func TestCollector_ReportEmpty(t *testing.T) {
+	buf := bytes.Buffer{}
+	if err := NewCollector().Report().WriteJSON(&buf); err != nil {
+		t.Fatal("Unexpected WriteJSON() error:", err)
+	}
+	decoded := map[string]interface{}{}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatal("Unexpected JSON:", err)
+	}
+	for _, key := range []string{"broken_links", "redirects", "non_html", "beyond_depth", "inbound_links"} {
+		if list, ok := decoded[key].([]interface{}); !ok || len(list) != 0 {
+			t.Errorf("Expected empty list for %q, got: %v", key, decoded[key])
+		}
+	}
+}
+
+func TestReport_WriteHTML(t *testing.T) {
+	buf := bytes.Buffer{}
+	if err := testCollector().Report().WriteHTML(&buf); err != nil {
+		t.Fatal("Unexpected WriteHTML() error:", err)
+	}
+	out := buf.String()
+	for _, expected := range []string{
+		"<h2>Broken links (1)</h2>",
+		"<h2>Redirects (1)</h2>",
+		"<h2>Non-HTML responses (1)</h2>",
+		"<h2>Pages beyond max depth (1)</h2>",
+		"<h2>Inbound links (5)</h2>",
+		"<li>http://localhost/new.html</li>",
+		"<td>http://localhost/missing.html</td><td>2</td>",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("Expected %q in HTML report", expected)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package sitemap
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// WithResourceTypes - record responses of given media types, like "application/pdf", as site map items
+// without downloading and parsing their content. Type can be given with wildcard subtype, like "image/*".
+// If type of resource is expected from extension of URI path, resource is requested with HEAD method,
+// when server does not support HEAD, only the first byte of resource is requested with range GET.
+// Responses of other URIs are recognized as resources by Content-Type header, their body is not read.
+func WithResourceTypes(types ...string) ParserOption {
+	resources, err := parseMediaTypes(types)
+	if err != nil {
+		return failedOption(fmt.Errorf("Invalid resource type %s", err))
+	}
+	for _, r := range resources {
+		if r == "text/html" {
+			return failedOption(fmt.Errorf("Invalid resource type %q", r))
+		}
+	}
+	return func(p *Parser) error {
+		p.resources = resources
+		return nil
+	}
+}
+
+// isResource - checks content type of response is one of resource types.
+func (p *Parser) isResource(ctype string) bool {
+	return matchMediaType(p.resources, ctype)
+}
+
+// expectResource - checks extension of URI path refers to one of resource types.
+func (p *Parser) expectResource(uri *URI) bool {
+	ext := path.Ext(uri.Path)
+	if ext == "" || len(p.resources) == 0 {
+		return false
+	}
+	ctype := mime.TypeByExtension(ext)
+	return ctype != "" && p.isResource(ctype)
+}
+
+// accept - returns value of Accept header, resource types are accepted with lower priority than documents.
+func (p *Parser) accept() string {
+	accept := strings.Join(p.acceptedTypes(), ", ")
+	if len(p.resources) == 0 {
+		return accept
+	}
+	return accept + ", " + strings.Join(p.resources, ";q=0.9, ") + ";q=0.9"
+}
+
+// resourceMeta - builds metadata of resource from headers of response.
+func resourceMeta(headers http.Header) *DocumentMeta {
+	return &DocumentMeta{
+		Modified:     modifiedTime(headers),
+		ETag:         headers.Get("ETag"),
+		LastModified: headers.Get("Last-Modified"),
+		NoIndex:      robotsNoIndex(headers["X-Robots-Tag"]...),
+	}
+}
@@ -0,0 +1,128 @@
+package sitemap
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParser_resources(t *testing.T) {
+	modified := time.Date(2019, 5, 21, 10, 0, 0, 0, time.UTC)
+	mx := sync.Mutex{}
+	requests := map[string][]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mx.Lock()
+		requests[r.URL.Path] = append(requests[r.URL.Path], r.Method+" "+r.Header.Get("Range"))
+		mx.Unlock()
+		w.Header().Set("Last-Modified", modified.Format(http.TimeFormat))
+		switch r.URL.Path {
+		case "/index.html":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html><head></head><body>` +
+				`<a href="/paper.pdf">a</a><a href="/nohead.pdf">b</a><a href="/download">c</a>` +
+				`<a href="/page.pdf">d</a><a href="/image.png">e</a>` +
+				`</body></html>`))
+		case "/paper.pdf", "/download":
+			w.Header().Set("Content-Type", "application/pdf")
+			w.Write([]byte(strings.Repeat("%PDF", 1<<16)))
+		case "/nohead.pdf":
+			if r.Method == "HEAD" {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.Header().Set("Content-Type", "application/pdf")
+			w.Header().Set("Content-Range", "bytes 0-0/4")
+			w.WriteHeader(http.StatusPartialContent)
+			w.Write([]byte("%"))
+		case "/page.pdf":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html><head></head><body><a href="/index.html">index</a></body></html>`))
+		case "/image.png":
+			w.Header().Set("Content-Type", "image/png")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	parser, err := NewParser(WithResourceTypes("application/pdf", "application/msword"))
+	if err != nil {
+		t.Fatal("Unexpected NewParser() error:", err)
+	}
+	root, _ := NewURI(server.URL + "/index.html")
+	found := []string{}
+	for _, item := range parser.Parse(root, 2, 2) {
+		found = append(found, item.URI.String())
+		if item.DocumentMeta != nil && !item.Modified.Equal(modified) {
+			t.Errorf("Unexpected modification time of %s: %v", item.URI, item.Modified)
+		}
+	}
+	sort.Strings(found)
+	expected := []string{
+		server.URL + "/download",
+		server.URL + "/image.png",
+		server.URL + "/index.html",
+		server.URL + "/nohead.pdf",
+		server.URL + "/page.pdf",
+		server.URL + "/paper.pdf",
+	}
+	if !reflect.DeepEqual(found, expected) {
+		t.Errorf("Expected %v, got %v", expected, found)
+	}
+	if errors := parser.Stats().Errors[ErrorContentType]; errors != 1 {
+		t.Errorf("Expected 1 content type error, got %d", errors)
+	}
+
+	for path, methods := range map[string][]string{
+		"/paper.pdf":  {"HEAD "},
+		"/nohead.pdf": {"HEAD ", "GET bytes=0-0"},
+		"/download":   {"GET "},
+		"/page.pdf":   {"HEAD ", "GET "},
+	} {
+		if !reflect.DeepEqual(requests[path], methods) {
+			t.Errorf("Expected requests %v of %s, got %v", methods, path, requests[path])
+		}
+	}
+
+	invalid := []ParserOption{
+		WithResourceTypes("application"),
+		WithResourceTypes("*/*"),
+		WithResourceTypes("text/html"),
+	}
+	for i, option := range invalid {
+		if _, err := NewParser(option); err == nil {
+			t.Errorf("Expected error for invalid option #%d", i)
+		}
+	}
+}
+
+func TestParser_isResource(t *testing.T) {
+	parser, err := NewParser(WithResourceTypes("application/pdf", "image/*"))
+	if err != nil {
+		t.Fatal("Unexpected NewParser() error:", err)
+	}
+	cases := []struct {
+		ctype    string
+		expected bool
+	}{
+		{"application/pdf", true},
+		{"Application/PDF; qs=0.5", true},
+		{"image/png", true},
+		{"text/html; charset=utf-8", false},
+		{"application/pdfx", false},
+		{"", false},
+	}
+	for _, c := range cases {
+		if actual := parser.isResource(c.ctype); actual != c.expected {
+			t.Errorf("Expected %v for %q, got %v", c.expected, c.ctype, actual)
+		}
+	}
+	if accept := parser.accept(); accept != "text/html, application/xhtml+xml, application/pdf;q=0.9, image/*;q=0.9" {
+		t.Errorf("Unexpected Accept header %q", accept)
+	}
+}
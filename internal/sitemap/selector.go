@@ -0,0 +1,157 @@
+package sitemap
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// selector - minimal subset of CSS selectors to match document elements.
+// Supported are comma-separated groups of compound selectors (`tag`, `#id`, `.class`, `[attr]`, `[attr=value]`)
+// joined with descendant combinator (whitespace).
+type selector [][]compound
+
+// compound - single compound selector, like `div#main.content[data-volatile]`.
+type compound struct {
+	tag     string
+	id      string
+	classes []string
+	attrs   []attrMatch
+}
+
+// attrMatch - attribute selector, value is checked only if `exact` is true.
+type attrMatch struct {
+	key, value string
+	exact      bool
+}
+
+// parseSelector - parses CSS selector from given string.
+func parseSelector(source string) (selector, error) {
+	sel := selector{}
+	for _, group := range strings.Split(source, ",") {
+		chain := []compound{}
+		for _, part := range strings.Fields(group) {
+			c, err := parseCompound(part)
+			if err != nil {
+				return nil, fmt.Errorf("invalid selector %q: %s", source, err)
+			}
+			chain = append(chain, c)
+		}
+		if len(chain) == 0 {
+			return nil, fmt.Errorf("invalid selector %q: empty group", source)
+		}
+		sel = append(sel, chain)
+	}
+	return sel, nil
+}
+
+// parseCompound - parses single compound selector without combinators.
+func parseCompound(source string) (compound, error) {
+	c := compound{}
+	i := strings.IndexAny(source, "#.[")
+	if i < 0 {
+		i = len(source)
+	}
+	c.tag = strings.ToLower(source[:i])
+	if c.tag == "*" {
+		c.tag = ""
+	}
+	for _, r := range c.tag {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-') {
+			return c, fmt.Errorf("unsupported %q", r)
+		}
+	}
+	for rest := source[i:]; rest != ""; {
+		switch rest[0] {
+		case '#', '.':
+			end := strings.IndexAny(rest[1:], "#.[") + 1
+			if end == 0 {
+				end = len(rest)
+			}
+			name := rest[1:end]
+			if name == "" {
+				return c, fmt.Errorf("empty name after %q", rest[0])
+			}
+			if rest[0] == '#' {
+				c.id = name
+			} else {
+				c.classes = append(c.classes, name)
+			}
+			rest = rest[end:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return c, fmt.Errorf("unclosed attribute selector")
+			}
+			a := attrMatch{key: strings.ToLower(rest[1:end])}
+			if eq := strings.IndexByte(a.key, '='); eq >= 0 {
+				a.key, a.value, a.exact = a.key[:eq], strings.Trim(rest[1+eq+1:end], `"'`), true
+			}
+			if a.key == "" {
+				return c, fmt.Errorf("empty attribute name")
+			}
+			c.attrs = append(c.attrs, a)
+			rest = rest[end+1:]
+		default:
+			return c, fmt.Errorf("unexpected %q", rest[0])
+		}
+	}
+	return c, nil
+}
+
+// match - checks element matches any group of selector.
+func (s selector) match(n *html.Node) bool {
+	for _, chain := range s {
+		if matchChain(chain, n) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchChain - checks element matches the last compound of chain
+// and its ancestors match the rest compounds in order.
+func matchChain(chain []compound, n *html.Node) bool {
+	last := len(chain) - 1
+	if !chain[last].match(n) {
+		return false
+	}
+	for a := n.Parent; a != nil && last > 0; a = a.Parent {
+		if chain[last-1].match(a) {
+			last--
+		}
+	}
+	return last == 0
+}
+
+// match - checks element matches compound selector.
+func (c compound) match(n *html.Node) bool {
+	if n == nil || n.Type != html.ElementNode {
+		return false
+	}
+	if c.tag != "" && c.tag != n.Data {
+		return false
+	}
+	if c.id != "" && attribute("id", n) != c.id {
+		return false
+	}
+	for _, class := range c.classes {
+		if !hasClass(class, n) {
+			return false
+		}
+	}
+	for _, a := range c.attrs {
+		val, ok := "", false
+		for _, attr := range n.Attr {
+			if attr.Key == a.key {
+				val, ok = attr.Val, true
+				break
+			}
+		}
+		if !ok || (a.exact && val != a.value) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,89 @@
+package sitemap
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func Test_parseSelector(t *testing.T) {
+	cases := []struct {
+		source string
+		valid  bool
+	}{
+		{"div", true},
+		{"*", true},
+		{"#main", true},
+		{"div.banner.top", true},
+		{"span[data-volatile]", true},
+		{`meta[name="csrf-token"]`, true},
+		{"footer .counter, #clock", true},
+		{"", false},
+		{"div,", false},
+		{"#", false},
+		{"div.", false},
+		{"a[href", false},
+		{"a[]", false},
+		{"div>p", false},
+	}
+	for _, c := range cases {
+		_, err := parseSelector(c.source)
+		if err != nil && c.valid {
+			t.Errorf("Unexpected error %q for %q", err, c.source)
+		}
+		if err == nil && !c.valid {
+			t.Errorf("Error was expected for %q, but it did not happened", c.source)
+		}
+	}
+}
+
+func Test_selectorMatch(t *testing.T) {
+	content := `
+	<html>
+	<head><meta name="csrf-token" content="x"></head>
+	<body>
+		<div id="main" class="content wide">
+			<p class="counter">1</p>
+		</div>
+		<footer><span class="counter" data-volatile>2</span></footer>
+	</body>
+	</html>
+	`
+	doc, _ := html.Parse(strings.NewReader(content))
+	cases := []struct {
+		selector string
+		expected []string
+	}{
+		{"p", []string{"p"}},
+		{".counter", []string{"p", "span"}},
+		{"footer .counter", []string{"span"}},
+		{"html footer span", []string{"span"}},
+		{"div#main.content.wide", []string{"div"}},
+		{"div.narrow", nil},
+		{"[data-volatile]", []string{"span"}},
+		{`meta[name="csrf-token"]`, []string{"meta"}},
+		{`meta[name=description]`, nil},
+		{"#main p, footer span", []string{"p", "span"}},
+	}
+	for _, c := range cases {
+		sel, err := parseSelector(c.selector)
+		if err != nil {
+			t.Fatal("Unexpected parseSelector() error:", err)
+		}
+		var actual []string
+		var walk func(n *html.Node)
+		walk = func(n *html.Node) {
+			if sel.match(n) {
+				actual = append(actual, n.Data)
+			}
+			for child := n.FirstChild; child != nil; child = child.NextSibling {
+				walk(child)
+			}
+		}
+		walk(doc)
+		if strings.Join(actual, ",") != strings.Join(c.expected, ",") {
+			t.Errorf("Expected %v for %q, got %v", c.expected, c.selector, actual)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package sitemap
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// PageState - cached data of previously fetched document, used to make conditional requests.
+type PageState struct {
+	// ETag - value of ETag header from last successful response
+	ETag string `json:"etag,omitempty"`
+	// LastModified - raw value of Last-Modified header from last successful response
+	LastModified string `json:"last_modified,omitempty"`
+	// Modified - resolved document modification time
+	Modified time.Time `json:"modified,omitempty"`
+	// Hash - hex-encoded SHA-256 of document content
+	Hash string `json:"hash,omitempty"`
+	// Links - absolute outgoing links found inside document
+	Links []string `json:"links,omitempty"`
+	// NoIndex - document must not be indexed
+	NoIndex bool `json:"noindex,omitempty"`
+}
+
+// CrawlState - thread-safe storage of page states between parser runs.
+// State remembers pages requested during the current run, see Prune.
+type CrawlState struct {
+	mx      sync.RWMutex
+	pages   map[string]PageState
+	visited map[string]bool
+}
+
+// crawlStateVersion - version of serialized crawl state format.
+const crawlStateVersion = 1
+
+// crawlStateFile - serialized representation of CrawlState.
+type crawlStateFile struct {
+	Version int                  `json:"version"`
+	Pages   map[string]PageState `json:"pages"`
+}
+
+// NewCrawlState - creates empty crawl state.
+func NewCrawlState() *CrawlState {
+	return &CrawlState{pages: map[string]PageState{}, visited: map[string]bool{}}
+}
+
+// LoadCrawlState - reads crawl state previously written with CrawlState.Save.
+func LoadCrawlState(r io.Reader) (*CrawlState, error) {
+	f := crawlStateFile{}
+	if err := json.NewDecoder(r).Decode(&f); err != nil {
+		return nil, fmt.Errorf("sitemap.LoadCrawlState: unable to decode state: %s", err)
+	}
+	if f.Version != crawlStateVersion {
+		return nil, fmt.Errorf("sitemap.LoadCrawlState: unsupported state version %d", f.Version)
+	}
+	s := NewCrawlState()
+	for uri, page := range f.Pages {
+		s.pages[uri] = page
+	}
+	return s, nil
+}
+
+// Save - writes crawl state in JSON format with given writer.
+func (s *CrawlState) Save(w io.Writer) error {
+	if s == nil {
+		return fmt.Errorf("sitemap.CrawlState.Save: state is nil")
+	}
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+	return json.NewEncoder(w).Encode(crawlStateFile{crawlStateVersion, s.pages})
+}
+
+// Get - returns state of page for given URI, page is marked as visited in the current run.
+func (s *CrawlState) Get(uri string) (PageState, bool) {
+	if s == nil {
+		return PageState{}, false
+	}
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	s.visited[uri] = true
+	page, ok := s.pages[uri]
+	return page, ok
+}
+
+// Set - stores state of page for given URI.
+func (s *CrawlState) Set(uri string, page PageState) {
+	if s == nil {
+		return
+	}
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	s.visited[uri] = true
+	s.pages[uri] = page
+}
+
+// Prune - removes pages which were not visited with Get or Set since state was created or loaded,
+// so state does not keep pages which are gone from the site. Returns num of removed pages.
+// Call it only after complete crawl, otherwise pages which were not reached yet are removed too.
+func (s *CrawlState) Prune() int {
+	if s == nil {
+		return 0
+	}
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	removed := 0
+	for uri := range s.pages {
+		if !s.visited[uri] {
+			delete(s.pages, uri)
+			removed++
+		}
+	}
+	return removed
+}
+
+// Len - returns number of pages in state.
+func (s *CrawlState) Len() int {
+	if s == nil {
+		return 0
+	}
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+	return len(s.pages)
+}
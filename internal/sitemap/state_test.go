@@ -0,0 +1,93 @@
+package sitemap
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCrawlState_SaveLoad(t *testing.T) {
+	state := NewCrawlState()
+	expected := PageState{
+		ETag:         `"abc"`,
+		LastModified: "Wed, 21 Oct 2015 07:28:00 GMT",
+		Modified:     time.Date(2015, 10, 21, 7, 28, 0, 0, time.UTC),
+		Hash:         "0a1b",
+		Links:        []string{"http://localhost/faq.html", "http://localhost/terms.html"},
+	}
+	state.Set("http://localhost/", expected)
+
+	buf := bytes.Buffer{}
+	if err := state.Save(&buf); err != nil {
+		t.Fatal("Unexpected Save() error:", err)
+	}
+	loaded, err := LoadCrawlState(&buf)
+	if err != nil {
+		t.Fatal("Unexpected LoadCrawlState() error:", err)
+	}
+	if loaded.Len() != 1 {
+		t.Error("Unexpected num of pages:", loaded.Len())
+	}
+	actual, ok := loaded.Get("http://localhost/")
+	if !ok {
+		t.Fatal("Page not found in loaded state")
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Error("Expected:", expected, "actual:", actual)
+	}
+	if _, ok := loaded.Get("http://localhost/faq.html"); ok {
+		t.Error("Found page which was not stored")
+	}
+}
+
+func TestLoadCrawlState_invalid(t *testing.T) {
+	for _, source := range []string{"", "[]", `{"version":100,"pages":{}}`} {
+		if _, err := LoadCrawlState(strings.NewReader(source)); err == nil {
+			t.Errorf("Expected error for %q", source)
+		}
+	}
+}
+
+func TestCrawlState_nil(t *testing.T) {
+	var state *CrawlState
+	state.Set("http://localhost/", PageState{})
+	if _, ok := state.Get("http://localhost/"); ok {
+		t.Error("Found page in nil state")
+	}
+	if err := state.Save(&bytes.Buffer{}); err == nil {
+		t.Error("Expected error when saving nil state")
+	}
+	if removed := state.Prune(); removed != 0 {
+		t.Error("Unexpected num of pruned pages:", removed)
+	}
+}
+
+func TestCrawlState_Prune(t *testing.T) {
+	previous := NewCrawlState()
+	for _, uri := range []string{"http://localhost/", "http://localhost/faq.html", "http://localhost/gone.html"} {
+		previous.Set(uri, PageState{Hash: "0a1b"})
+	}
+	buf := bytes.Buffer{}
+	if err := previous.Save(&buf); err != nil {
+		t.Fatal("Unexpected Save() error:", err)
+	}
+	state, err := LoadCrawlState(&buf)
+	if err != nil {
+		t.Fatal("Unexpected LoadCrawlState() error:", err)
+	}
+	state.Get("http://localhost/")
+	state.Set("http://localhost/faq.html", PageState{Hash: "2c3d"})
+	state.Set("http://localhost/new.html", PageState{Hash: "4e5f"})
+
+	if removed := state.Prune(); removed != 1 {
+		t.Error("Expected 1 pruned page, got", removed)
+	}
+	if state.Len() != 3 {
+		t.Error("Unexpected num of pages:", state.Len())
+	}
+	if _, ok := state.Get("http://localhost/gone.html"); ok {
+		t.Error("Found page which was not visited")
+	}
+}
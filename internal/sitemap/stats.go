@@ -0,0 +1,115 @@
+package sitemap
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Stats - snapshot of crawl statistics.
+type Stats struct {
+	// Started - time when crawl was started
+	Started time.Time
+	// Elapsed - duration of crawl
+	Elapsed time.Duration
+	// Fetched - num of documents fetched successfully, including not modified ones
+	Fetched int64
+	// Queued - num of targets which are waiting to be processed
+	Queued int
+	// InFlight - num of targets which are processed right now
+	InFlight int64
+	// Workers - max num of concurrent workers
+	Workers uint
+	// Errors - num of occurred errors by class
+	Errors map[ErrorClass]int64
+	// Bytes - num of downloaded bytes of documents
+	Bytes int64
+	// PagesPerSecond - average rate of fetched documents
+	PagesPerSecond float64
+	// MaxDepth - maximum level of processed targets
+	MaxDepth uint
+	// Done - crawl is completed or interrupted
+	Done bool
+}
+
+// crawlStats - counters of single crawl.
+type crawlStats struct {
+	started  time.Time
+	finished time.Time // protected by mx
+	frontier Frontier
+	busy     *int64 // num of running workers, shared with crawl
+	workers  uint
+
+	fetched, bytes, maxDepth int64 // atomic
+
+	mx     sync.Mutex
+	errors map[ErrorClass]int64
+}
+
+func newCrawlStats(frontier Frontier, busy *int64, workers uint) *crawlStats {
+	return &crawlStats{
+		started:  time.Now(),
+		frontier: frontier,
+		busy:     busy,
+		workers:  workers,
+		errors:   map[ErrorClass]int64{},
+	}
+}
+
+// completed - counts processed target.
+func (s *crawlStats) completed(c completedTarget) {
+	if c.meta != nil {
+		atomic.AddInt64(&s.fetched, 1)
+		atomic.AddInt64(&s.bytes, c.meta.Size)
+	}
+	level := int64(c.Level)
+	for {
+		max := atomic.LoadInt64(&s.maxDepth)
+		if level <= max || atomic.CompareAndSwapInt64(&s.maxDepth, max, level) {
+			break
+		}
+	}
+}
+
+// failed - counts occurred error.
+func (s *crawlStats) failed(err error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	s.errors[classify(err)]++
+}
+
+// finish - marks crawl as done.
+func (s *crawlStats) finish() {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	s.finished = time.Now()
+}
+
+// snapshot - returns current statistics.
+func (s *crawlStats) snapshot() Stats {
+	stats := Stats{
+		Started:  s.started,
+		Fetched:  atomic.LoadInt64(&s.fetched),
+		Queued:   s.frontier.Len(),
+		InFlight: atomic.LoadInt64(s.busy),
+		Workers:  s.workers,
+		Bytes:    atomic.LoadInt64(&s.bytes),
+		MaxDepth: uint(atomic.LoadInt64(&s.maxDepth)),
+		Errors:   map[ErrorClass]int64{},
+	}
+	s.mx.Lock()
+	for class, num := range s.errors {
+		stats.Errors[class] = num
+	}
+	if s.finished.IsZero() {
+		stats.Elapsed = time.Since(s.started)
+	} else {
+		stats.Elapsed = s.finished.Sub(s.started)
+		stats.Done = true
+	}
+	s.mx.Unlock()
+	if seconds := stats.Elapsed.Seconds(); seconds > 0 {
+		stats.PagesPerSecond = float64(stats.Fetched) / seconds
+	}
+	return stats
+}
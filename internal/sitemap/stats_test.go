@@ -0,0 +1,59 @@
+package sitemap
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParser_Stats(t *testing.T) {
+	server := httptest.NewServer(http.FileServer(http.Dir("testdata/simplesite")))
+	defer server.Close()
+
+	parser, err := NewParser()
+	if err != nil {
+		t.Fatal("Unexpected NewParser() error:", err)
+	}
+	if stats := parser.Stats(); stats.Fetched != 0 || stats.Done || len(stats.Errors) != 0 {
+		t.Error("Unexpected stats before crawl:", stats)
+	}
+
+	root, _ := NewURI(server.URL + "/homepage.witherror.html")
+	parser.Parse(root, 1, 2)
+	stats := parser.Stats()
+	if !stats.Done {
+		t.Error("Expected crawl is done")
+	}
+	if stats.Fetched != 4 {
+		t.Error("Unexpected num of fetched documents:", stats.Fetched)
+	}
+	if stats.Queued != 0 || stats.InFlight != 0 {
+		t.Error("Unexpected queued or in-flight targets:", stats.Queued, stats.InFlight)
+	}
+	if stats.MaxDepth != 1 {
+		t.Error("Unexpected max depth:", stats.MaxDepth)
+	}
+	if stats.Bytes == 0 || stats.PagesPerSecond == 0 {
+		t.Error("Unexpected zero bytes or rate:", stats.Bytes, stats.PagesPerSecond)
+	}
+	if len(stats.Errors) != 1 || stats.Errors[ErrorStatus] != 1 {
+		t.Error("Unexpected errors:", stats.Errors)
+	}
+}
+
+func Test_classify(t *testing.T) {
+	cases := []struct {
+		err      error
+		expected ErrorClass
+	}{
+		{&CrawlError{"http://localhost/", ErrorStatus, errors.New("status")}, ErrorStatus},
+		{&CrawlError{"", ErrorStore, nil}, ErrorStore},
+		{errors.New("unknown"), ErrorOther},
+	}
+	for _, c := range cases {
+		if actual := classify(c.err); actual != c.expected {
+			t.Errorf("Expected %q, got %q for %v", c.expected, actual, c.err)
+		}
+	}
+}
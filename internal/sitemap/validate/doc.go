@@ -0,0 +1,3 @@
+// Package validate checks site map and site map index files against the protocol
+// suggested by https://www.sitemaps.org/protocol.html
+package validate
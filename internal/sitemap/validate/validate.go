@@ -0,0 +1,347 @@
+package validate
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// Namespace - required namespace of site map and site map index
+	Namespace = "http://www.sitemaps.org/schemas/sitemap/0.9"
+	// MaxEntries - max num of URLs in site map or site maps in index
+	MaxEntries = 50000
+	// MaxFileSize - max size of uncompressed file in bytes
+	MaxFileSize = 50 * 1024 * 1024
+	// MaxLocLength - max length of URL
+	MaxLocLength = 2048
+)
+
+// Kind - type of validated file.
+type Kind string
+
+const (
+	// KindMap - site map, `urlset` root element
+	KindMap Kind = "urlset"
+	// KindIndex - site map index, `sitemapindex` root element
+	KindIndex Kind = "sitemapindex"
+)
+
+// changeFrequencies - allowed values of `changefreq`.
+var changeFrequencies = map[string]bool{
+	"always":  true,
+	"hourly":  true,
+	"daily":   true,
+	"weekly":  true,
+	"monthly": true,
+	"yearly":  true,
+	"never":   true,
+}
+
+// dateLayouts - W3C Datetime formats allowed for `lastmod`.
+var dateLayouts = []string{
+	"2006",
+	"2006-01",
+	"2006-01-02",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04:05Z07:00",
+}
+
+// Violation - single protocol violation.
+type Violation struct {
+	// Line - num of line where violation is found, 0 if violation is related to whole file
+	Line int `json:"line"`
+	// Message - description of violation
+	Message string `json:"message"`
+}
+
+// String - formats violation for output.
+func (v Violation) String() string {
+	if v.Line == 0 {
+		return v.Message
+	}
+	return fmt.Sprintf("line %d: %s", v.Line, v.Message)
+}
+
+// Result - outcome of validation.
+type Result struct {
+	// Kind - type of file, empty if root element is unknown
+	Kind Kind `json:"kind"`
+	// Entries - num of `url` or `sitemap` elements
+	Entries int `json:"entries"`
+	// Violations - found violations in order of appearance
+	Violations []Violation `json:"violations"`
+}
+
+// Valid - reports there are no violations.
+func (r *Result) Valid() bool {
+	return len(r.Violations) == 0
+}
+
+// File - reads and validates site map or site map index file, plain or gzip-compressed.
+// Reading is stopped as soon as uncompressed content exceeds MaxFileSize, such file is not parsed.
+// Returns error only if file can not be read.
+func File(filename string) (*Result, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("validate.File: cannot open %s: %s", filename, err)
+	}
+	defer f.Close()
+	br := bufio.NewReader(f)
+	var r io.Reader = br
+	if magic, _ := br.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("validate.File: %s, cannot prepare gzip reader: %s", filename, err)
+		}
+		defer gr.Close()
+		r = gr
+	}
+	// small gzipped file can hide huge content, so it is never read beyond the limit
+	data, err := ioutil.ReadAll(io.LimitReader(r, MaxFileSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("validate.File: cannot read %s: %s", filename, err)
+	}
+	if len(data) > MaxFileSize {
+		return &Result{
+			Violations: []Violation{{0, fmt.Sprintf("uncompressed file size exceeds limit of %d bytes", MaxFileSize)}},
+		}, nil
+	}
+	return Validate(data), nil
+}
+
+// entry - `url` or `sitemap` element under validation.
+type entry struct {
+	line   int
+	fields map[string]bool
+}
+
+// validator - keeps state of single validation.
+type validator struct {
+	result *Result
+	lines  []int  // offsets of line breaks
+	space  string // namespace of root element, entries in other namespaces are extensions
+	host   string
+	locs   map[string]int // loc -> line of first occurrence
+}
+
+// Validate - checks uncompressed content of site map or site map index.
+func Validate(data []byte) *Result {
+	v := &validator{result: &Result{Violations: []Violation{}}, locs: map[string]int{}}
+	for i, b := range data {
+		if b == '\n' {
+			v.lines = append(v.lines, i)
+		}
+	}
+	if len(data) > MaxFileSize {
+		v.report(0, "file size %d exceeds limit of %d bytes", len(data), MaxFileSize)
+	}
+	if !utf8.Valid(data) {
+		v.report(0, "file is not UTF-8 encoded")
+	}
+	v.parse(data)
+	return v.result
+}
+
+// report - adds violation.
+func (v *validator) report(line int, format string, args ...interface{}) {
+	v.result.Violations = append(v.result.Violations, Violation{line, fmt.Sprintf(format, args...)})
+}
+
+// line - converts byte offset into line number.
+func (v *validator) line(offset int64) int {
+	return sort.SearchInts(v.lines, int(offset)) + 1
+}
+
+// parse - walks elements tree and checks every entry.
+func (v *validator) parse(data []byte) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	var (
+		path    []xml.Name // stack of open elements
+		current *entry
+		text    strings.Builder
+		start   int // line of current field
+	)
+	for {
+		offset := dec.InputOffset()
+		token, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if e, ok := err.(*xml.SyntaxError); ok {
+				v.report(e.Line, "malformed XML: %s", e.Msg)
+			} else {
+				v.report(v.line(offset), "malformed XML: %s", err)
+			}
+			return
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			line := v.line(offset)
+			path = append(path, t.Name)
+			switch len(path) {
+			case 1:
+				v.root(line, t.Name)
+			case 2:
+				current = v.entryStart(line, t.Name)
+			case 3:
+				text.Reset()
+				start = line
+			}
+		case xml.CharData:
+			if len(path) == 3 {
+				text.Write(t)
+			}
+		case xml.EndElement:
+			switch len(path) {
+			case 2:
+				if current != nil {
+					v.entryEnd(current)
+				}
+				current = nil
+			case 3:
+				if current != nil {
+					v.field(current, start, path[2], strings.TrimSpace(text.String()))
+				}
+			}
+			path = path[:len(path)-1]
+		}
+	}
+	if v.result.Kind == "" && len(v.result.Violations) == 0 {
+		v.report(0, "root element is not found")
+	}
+	if v.result.Entries > MaxEntries {
+		v.report(0, "num of entries %d exceeds limit of %d", v.result.Entries, MaxEntries)
+	}
+}
+
+// root - checks root element.
+func (v *validator) root(line int, name xml.Name) {
+	switch Kind(name.Local) {
+	case KindMap, KindIndex:
+		v.result.Kind = Kind(name.Local)
+	default:
+		v.report(line, "unexpected root element <%s>, expected <urlset> or <sitemapindex>", name.Local)
+		return
+	}
+	v.space = name.Space
+	if name.Space != Namespace {
+		v.report(line, "invalid namespace %q of <%s>, expected %q", name.Space, name.Local, Namespace)
+	}
+}
+
+// entryStart - checks `url` or `sitemap` element, returns nil if element is unexpected.
+func (v *validator) entryStart(line int, name xml.Name) *entry {
+	expected := "url"
+	if v.result.Kind == KindIndex {
+		expected = "sitemap"
+	}
+	if v.result.Kind == "" {
+		return nil
+	}
+	if name.Space != v.space {
+		// extensions are allowed
+		return nil
+	}
+	if name.Local != expected {
+		v.report(line, "unexpected element <%s>, expected <%s>", name.Local, expected)
+		return nil
+	}
+	v.result.Entries++
+	return &entry{line: line, fields: map[string]bool{}}
+}
+
+// entryEnd - checks required fields of entry.
+func (v *validator) entryEnd(e *entry) {
+	if !e.fields["loc"] {
+		v.report(e.line, "required <loc> is missing")
+	}
+}
+
+// field - checks single child of entry.
+func (v *validator) field(e *entry, line int, name xml.Name, value string) {
+	if name.Space != v.space {
+		return
+	}
+	if e.fields[name.Local] {
+		v.report(line, "duplicate <%s>", name.Local)
+	}
+	e.fields[name.Local] = true
+	switch name.Local {
+	case "loc":
+		v.loc(line, value)
+	case "lastmod":
+		if !validDate(value) {
+			v.report(line, "invalid <lastmod> %q, expected W3C Datetime format", value)
+		}
+	case "changefreq":
+		if v.result.Kind != KindMap {
+			v.report(line, "unexpected element <%s>", name.Local)
+		} else if !changeFrequencies[value] {
+			v.report(line, "invalid <changefreq> %q", value)
+		}
+	case "priority":
+		if v.result.Kind != KindMap {
+			v.report(line, "unexpected element <%s>", name.Local)
+		} else if p, err := strconv.ParseFloat(value, 64); err != nil || p < 0 || p > 1 {
+			v.report(line, "invalid <priority> %q, expected value from 0.0 to 1.0", value)
+		}
+	default:
+		v.report(line, "unexpected element <%s>", name.Local)
+	}
+}
+
+// loc - checks URL is absolute, properly escaped and belongs to the same host as previous ones.
+func (v *validator) loc(line int, value string) {
+	if len(value) > MaxLocLength {
+		v.report(line, "<loc> length %d exceeds limit of %d", len(value), MaxLocLength)
+	}
+	for _, r := range value {
+		if r > 0x7e || r <= 0x20 || strings.ContainsRune(`"<>\^{|}`+"`", r) {
+			v.report(line, "<loc> %q contains unescaped character %q", value, r)
+			break
+		}
+	}
+	u, err := url.Parse(value)
+	if err != nil {
+		v.report(line, "invalid <loc> %q: %s", value, err)
+		return
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		v.report(line, "<loc> %q is not absolute http(s) URL", value)
+		return
+	}
+	host := u.Scheme + "://" + u.Host
+	if v.host == "" {
+		v.host = host
+	} else if host != v.host {
+		v.report(line, "<loc> %q does not belong to %s", value, v.host)
+	}
+	if first, ok := v.locs[value]; ok {
+		v.report(line, "duplicate <loc> %q, first found at line %d", value, first)
+	} else {
+		v.locs[value] = line
+	}
+}
+
+// validDate - checks value is in one of W3C Datetime formats.
+func validDate(value string) bool {
+	for _, layout := range dateLayouts {
+		if _, err := time.Parse(layout, value); err == nil {
+			return true
+		}
+	}
+	return false
+}
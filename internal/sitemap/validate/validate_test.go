@@ -0,0 +1,268 @@
+package validate
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/wtask/sitemap/internal/compression"
+)
+
+const validMap = `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+	<url>
+		<loc>http://localhost/</loc>
+		<lastmod>2019-05-21T23:26:00+03:00</lastmod>
+		<changefreq>daily</changefreq>
+		<priority>0.8</priority>
+	</url>
+	<url>
+		<loc>http://localhost/faq.html?a=1&amp;b=2</loc>
+		<lastmod>2019-05-21</lastmod>
+	</url>
+</urlset>
+`
+
+func TestValidate(t *testing.T) {
+	cases := []struct {
+		name       string
+		data       string
+		kind       Kind
+		entries    int
+		violations []Violation
+	}{
+		{"valid map", validMap, KindMap, 2, []Violation{}},
+		{
+			"valid index",
+			`<?xml version="1.0" encoding="UTF-8"?>
+<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+	<sitemap>
+		<loc>http://localhost/sitemap1.xml</loc>
+		<lastmod>2019-05-21T23:26:00.123Z</lastmod>
+	</sitemap>
+</sitemapindex>`,
+			KindIndex,
+			1,
+			[]Violation{},
+		},
+		{
+			"malformed",
+			"<urlset xmlns=\"http://www.sitemaps.org/schemas/sitemap/0.9\">\n<url>\n<loc>http://localhost/?a=1&b=2</loc>\n</url>\n</urlset>",
+			KindMap,
+			1,
+			[]Violation{{3, "malformed XML: invalid character entity &b (no semicolon)"}},
+		},
+		{
+			"unclosed",
+			"<urlset xmlns=\"http://www.sitemaps.org/schemas/sitemap/0.9\">\n<url>\n",
+			KindMap,
+			1,
+			[]Violation{{3, "malformed XML: unexpected EOF"}},
+		},
+		{"empty", "", "", 0, []Violation{{0, "root element is not found"}}},
+		{
+			"namespace",
+			`<urlset xmlns="http://www.google.com/schemas/sitemap/0.84"></urlset>`,
+			KindMap,
+			0,
+			[]Violation{{
+				1,
+				`invalid namespace "http://www.google.com/schemas/sitemap/0.84" of <urlset>, ` +
+					`expected "http://www.sitemaps.org/schemas/sitemap/0.9"`,
+			}},
+		},
+		{
+			"root",
+			`<urls></urls>`,
+			"",
+			0,
+			[]Violation{{1, "unexpected root element <urls>, expected <urlset> or <sitemapindex>"}},
+		},
+		{
+			"entries",
+			`<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+<sitemap><loc>http://localhost/</loc></sitemap>
+<url><lastmod>2019-05-21</lastmod></url>
+<url xmlns:image="http://www.google.com/schemas/sitemap-image/1.1">
+	<loc>http://localhost/a.html</loc>
+	<image:image><image:loc>http://localhost/a.png</image:loc></image:image>
+	<title>A</title>
+</url>
+</urlset>`,
+			KindMap,
+			2,
+			[]Violation{
+				{2, "unexpected element <sitemap>, expected <url>"},
+				{3, "required <loc> is missing"},
+				{7, "unexpected element <title>"},
+			},
+		},
+		{
+			"loc",
+			`<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+<url><loc>/relative.html</loc></url>
+<url><loc>http://localhost/with space.html</loc></url>
+<url><loc>http://localhost/кириллица.html</loc></url>
+<url><loc>https://localhost/</loc></url>
+<url><loc>http://localhost/a.html</loc></url>
+<url><loc>http://localhost/a.html</loc><loc>http://localhost/b.html</loc></url>
+</urlset>`,
+			KindMap,
+			6,
+			[]Violation{
+				{2, `<loc> "/relative.html" is not absolute http(s) URL`},
+				{3, `<loc> "http://localhost/with space.html" contains unescaped character ' '`},
+				{4, `<loc> "http://localhost/кириллица.html" contains unescaped character 'к'`},
+				{5, `<loc> "https://localhost/" does not belong to http://localhost`},
+				{7, `duplicate <loc> "http://localhost/a.html", first found at line 6`},
+				{7, `duplicate <loc>`},
+			},
+		},
+		{
+			"values",
+			`<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+<url>
+	<loc>http://localhost/</loc>
+	<lastmod>21.05.2019</lastmod>
+	<changefreq>sometimes</changefreq>
+	<priority>1.5</priority>
+</url>
+<url>
+	<loc>http://localhost/a.html</loc>
+	<lastmod>2019-05-21T23:26</lastmod>
+	<priority>high</priority>
+</url>
+</urlset>`,
+			KindMap,
+			2,
+			[]Violation{
+				{4, `invalid <lastmod> "21.05.2019", expected W3C Datetime format`},
+				{5, `invalid <changefreq> "sometimes"`},
+				{6, `invalid <priority> "1.5", expected value from 0.0 to 1.0`},
+				{10, `invalid <lastmod> "2019-05-21T23:26", expected W3C Datetime format`},
+				{11, `invalid <priority> "high", expected value from 0.0 to 1.0`},
+			},
+		},
+		{
+			"index values",
+			`<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+<sitemap>
+	<loc>http://localhost/sitemap.xml</loc>
+	<changefreq>daily</changefreq>
+</sitemap>
+<url><loc>http://localhost/</loc></url>
+</sitemapindex>`,
+			KindIndex,
+			1,
+			[]Violation{
+				{4, "unexpected element <changefreq>"},
+				{6, "unexpected element <url>, expected <sitemap>"},
+			},
+		},
+	}
+	for _, c := range cases {
+		actual := Validate([]byte(c.data))
+		if actual.Kind != c.kind || actual.Entries != c.entries {
+			t.Errorf("%s: unexpected kind %q or num of entries %d", c.name, actual.Kind, actual.Entries)
+		}
+		if !reflect.DeepEqual(actual.Violations, c.violations) {
+			t.Errorf("%s: expected violations %v, got %v", c.name, c.violations, actual.Violations)
+		}
+		if actual.Valid() != (len(c.violations) == 0) {
+			t.Errorf("%s: unexpected Valid() result", c.name)
+		}
+	}
+}
+
+func TestValidate_limits(t *testing.T) {
+	entries := strings.Builder{}
+	entries.WriteString(`<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`)
+	for i := 0; i <= MaxEntries; i++ {
+		fmt.Fprintf(&entries, "<url><loc>http://localhost/%d.html</loc></url>", i)
+	}
+	entries.WriteString("</urlset>")
+	actual := Validate([]byte(entries.String()))
+	expected := []Violation{{0, "num of entries 50001 exceeds limit of 50000"}}
+	if actual.Entries != MaxEntries+1 || !reflect.DeepEqual(actual.Violations, expected) {
+		t.Errorf("Expected violations %v, got %v", expected, actual.Violations)
+	}
+
+	size := `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"><!--` +
+		strings.Repeat(" ", MaxFileSize) +
+		`--></urlset>`
+	actual = Validate([]byte(size))
+	expected = []Violation{{0, fmt.Sprintf("file size %d exceeds limit of %d bytes", len(size), MaxFileSize)}}
+	if !reflect.DeepEqual(actual.Violations, expected) {
+		t.Errorf("Expected violations %v, got %v", expected, actual.Violations)
+	}
+}
+
+func TestFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "validate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	plain := filepath.Join(dir, "sitemap.xml")
+	if err := ioutil.WriteFile(plain, []byte(validMap), 0644); err != nil {
+		t.Fatal(err)
+	}
+	gz := filepath.Join(dir, "sitemap.xml.gzip")
+	if err := compression.GzipFile(plain, gz); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, filename := range []string{plain, gz} {
+		result, err := File(filename)
+		if err != nil {
+			t.Fatal("Unexpected File() error:", err)
+		}
+		if !result.Valid() || result.Entries != 2 {
+			t.Errorf("Unexpected result for %s: %+v", filename, result)
+		}
+	}
+
+	if _, err := File(filepath.Join(dir, "nonexistent.xml")); err == nil {
+		t.Error("Expected error for nonexistent file")
+	}
+
+	// few kilobytes of gzip are unpacked into content larger than the limit
+	bomb := filepath.Join(dir, "bomb.xml.gz")
+	content := io.MultiReader(
+		strings.NewReader(`<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"><!--`),
+		io.LimitReader(zeros{}, MaxFileSize),
+		strings.NewReader(`--></urlset>`),
+	)
+	f, err := os.Create(bomb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := compression.Gzip(content, f, nil); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	result, err := File(bomb)
+	if err != nil {
+		t.Fatal("Unexpected File() error:", err)
+	}
+	expected := []Violation{{0, fmt.Sprintf("uncompressed file size exceeds limit of %d bytes", MaxFileSize)}}
+	if !reflect.DeepEqual(result.Violations, expected) || result.Entries != 0 {
+		t.Errorf("Expected violations %v, got %+v", expected, result)
+	}
+}
+
+// zeros - endless reader of zero bytes.
+type zeros struct{}
+
+func (zeros) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+	return len(p), nil
+}